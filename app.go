@@ -3,8 +3,16 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"os"
 	"path/filepath"
+	goruntime "runtime"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 	"twitterxmediabatchdownloader/backend"
 
 	"github.com/wailsapp/wails/v2/pkg/runtime"
@@ -12,11 +20,127 @@ import (
 
 // App struct
 type App struct {
-	ctx            context.Context
-	downloadCtx    context.Context
-	downloadCancel context.CancelFunc
+	ctx          context.Context
+	jobsMu       sync.Mutex
+	jobs         map[string]*downloadJob
+	nextJobID    int64
+	prefetch     cancelSlot // thumbnail prefetch
+	gifConvert   cancelSlot // GIF conversion
+	audioExtract cancelSlot // audio extraction
+	transcode    cancelSlot // video transcoding
+	ffmpeg       cancelSlot // ffmpeg binary download
+	extract      cancelSlot // full-timeline and batch extractions
+	sizeMu       sync.Mutex
+	sizeCtx      context.Context
+	sizeCancel   context.CancelFunc
+	activeOps    sync.WaitGroup
 }
 
+// downloadJob is a running download with its own cancellation and pause control
+type downloadJob struct {
+	id       string
+	username string
+	total    int
+	cancel   context.CancelFunc
+	pause    *backend.PauseController
+}
+
+// DownloadJobInfo describes a running download job
+type DownloadJobInfo struct {
+	ID       string `json:"id"`
+	Username string `json:"username"`
+	Total    int    `json:"total"`
+	Paused   bool   `json:"paused"`
+}
+
+// startJob registers a new download job and returns it with its context
+func (a *App) startJob(username string, total int) (*downloadJob, context.Context) {
+	ctx, cancel := context.WithCancel(context.Background())
+	job := &downloadJob{
+		id:       fmt.Sprintf("job-%d", atomic.AddInt64(&a.nextJobID, 1)),
+		username: username,
+		total:    total,
+		cancel:   cancel,
+		pause:    backend.NewPauseController(),
+	}
+
+	a.jobsMu.Lock()
+	if a.jobs == nil {
+		a.jobs = make(map[string]*downloadJob)
+	}
+	a.jobs[job.id] = job
+	a.jobsMu.Unlock()
+
+	return job, ctx
+}
+
+// finishJob removes a job from the registry and releases its context
+func (a *App) finishJob(job *downloadJob) {
+	a.jobsMu.Lock()
+	delete(a.jobs, job.id)
+	a.jobsMu.Unlock()
+	job.cancel()
+}
+
+// cancelSlot holds the cancel function of the one operation of its kind that
+// runs at a time; Wails calls it from concurrent goroutines
+type cancelSlot struct {
+	mu     sync.Mutex
+	cancel context.CancelFunc
+	seq    uint64
+}
+
+// start cancels the running operation, if any, and returns the context of a
+// new one; release clears the slot unless a later operation took it over
+func (s *cancelSlot) start() (ctx context.Context, release func()) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	s.mu.Lock()
+	if s.cancel != nil {
+		s.cancel()
+	}
+	s.seq++
+	seq := s.seq
+	s.cancel = cancel
+	s.mu.Unlock()
+
+	return ctx, func() {
+		cancel()
+		s.mu.Lock()
+		if s.seq == seq {
+			s.cancel = nil
+		}
+		s.mu.Unlock()
+	}
+}
+
+// stop cancels the running operation; false when none is running
+func (s *cancelSlot) stop() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.cancel == nil {
+		return false
+	}
+	s.cancel()
+	s.cancel = nil
+	return true
+}
+
+// activeJobs returns a snapshot of the running jobs
+func (a *App) activeJobs() []*downloadJob {
+	a.jobsMu.Lock()
+	defer a.jobsMu.Unlock()
+
+	jobs := make([]*downloadJob, 0, len(a.jobs))
+	for _, job := range a.jobs {
+		jobs = append(jobs, job)
+	}
+	return jobs
+}
+
+// shutdownTimeout bounds how long shutdown waits for in-flight work
+const shutdownTimeout = 5 * time.Second
+
 // NewApp creates a new App application struct
 func NewApp() *App {
 	return &App{}
@@ -26,24 +150,100 @@ func NewApp() *App {
 // so we can call the runtime methods
 func (a *App) startup(ctx context.Context) {
 	a.ctx = ctx
-	// Initialize database
-	backend.InitDB()
+	// Initialize database. Extraction and downloads keep working without it;
+	// only the DB-backed features (saved accounts, history, settings) are off.
+	if err := backend.InitDB(); err != nil {
+		runtime.LogErrorf(ctx, "%v; saved accounts, history and settings are disabled", err)
+		return
+	}
+	backend.LoadTransportConfig()
+	backend.PruneExtractCache()
+
+	// Daily safety copy that RepairDatabase can fall back to
+	a.activeOps.Add(1)
+	go func() {
+		defer a.activeOps.Done()
+		if err := backend.BackupDatabaseIfDue(); err != nil {
+			runtime.LogErrorf(ctx, "%v", err)
+		}
+	}()
+
+	// One-time backfill of the media table for libraries saved before it existed
+	if !backend.IsMediaBackfillDone() {
+		a.activeOps.Add(1)
+		go func() {
+			defer a.activeOps.Done()
+			a.BackfillMediaTable()
+		}()
+	}
+
+	// One-time build of the account search index
+	if !backend.IsSearchIndexDone() {
+		a.activeOps.Add(1)
+		go func() {
+			defer a.activeOps.Done()
+			backend.BackfillSearchIndex(nil)
+		}()
+	}
 }
 
-// shutdown is called when the app is closing
+// shutdown is called when the app is closing. In-flight work is canceled
+// and given a short time to stop before the database is closed.
 func (a *App) shutdown(ctx context.Context) {
+	a.StopDownload()
+	a.StopThumbnailPrefetch()
+	a.StopGIFConversion()
+	a.StopAudioExtraction()
+	a.StopTranscode()
+	a.StopExtraction()
+
+	done := make(chan struct{})
+	go func() {
+		a.activeOps.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(shutdownTimeout):
+	}
+
 	backend.CloseDB()
 }
 
 // TimelineRequest represents the request structure for timeline extraction
 type TimelineRequest struct {
-	Username     string `json:"username"`
+	Username     string `json:"username"` // handle, URL or id:<user ID>
+	UserID       string `json:"user_id"`  // numeric user ID, takes precedence over Username
 	AuthToken    string `json:"auth_token"`
 	TimelineType string `json:"timeline_type"`
 	BatchSize    int    `json:"batch_size"`
 	Page         int    `json:"page"`
 	MediaType    string `json:"media_type"`
-	Retweets     bool   `json:"retweets"`
+	Retweets     *bool  `json:"retweets"` // nil = saved default
+	MaxEntries   int    `json:"max_entries"`
+	Cursor       string `json:"cursor"`
+	Pretty       bool   `json:"pretty"` // indent the returned JSON for export/debugging
+	// AdaptiveBatch tunes the page size to rate limiting in ExtractFullTimeline
+	AdaptiveBatch bool `json:"adaptive_batch"`
+	// Random pause between pages in ExtractFullTimeline, in milliseconds;
+	// both 0 = a small default jitter, PageDelayMin < 0 = none
+	PageDelayMin int `json:"page_delay_min_ms"`
+	PageDelayMax int `json:"page_delay_max_ms"`
+	// Incremental makes RefreshAccount fetch only tweets newer than the
+	// newest stored one and merge them into the saved timeline
+	Incremental bool `json:"incremental"`
+	// NoCache skips the extraction cache and always fetches fresh results
+	NoCache bool `json:"no_cache"`
+	// AutoSave makes ExtractTimeline save a successful result to the library
+	AutoSave bool `json:"auto_save"`
+}
+
+// savedTimelineResponse is an extraction result with the ID it was saved
+// under by AutoSave
+type savedTimelineResponse struct {
+	*backend.TwitterResponse
+	AccountID int64 `json:"account_id"`
 }
 
 // DateRangeRequest represents the request structure for date range extraction
@@ -53,38 +253,318 @@ type DateRangeRequest struct {
 	StartDate   string `json:"start_date"`
 	EndDate     string `json:"end_date"`
 	MediaFilter string `json:"media_filter"`
+	Pretty      bool   `json:"pretty"` // indent the returned JSON for export/debugging
 }
 
-// ExtractTimeline extracts media from user timeline
-func (a *App) ExtractTimeline(req TimelineRequest) (string, error) {
-	if req.Username == "" {
-		return "", fmt.Errorf("username is required")
+// encodeResponse marshals an extraction response, compact unless pretty is set
+func encodeResponse(v interface{}, pretty bool) (string, error) {
+	var jsonData []byte
+	var err error
+	if pretty {
+		jsonData, err = json.MarshalIndent(v, "", "  ")
+	} else {
+		jsonData, err = json.Marshal(v)
 	}
-	if req.AuthToken == "" {
-		return "", fmt.Errorf("auth token is required")
+	if err != nil {
+		return "", fmt.Errorf("failed to encode response: %v", err)
 	}
 
+	return string(jsonData), nil
+}
+
+// toBackend converts the request to the backend timeline request, filling
+// empty/zero fields from the saved extraction defaults
+func (req TimelineRequest) toBackend() backend.TimelineRequest {
+	defaults, _ := backend.GetExtractionDefaults()
+
 	backendReq := backend.TimelineRequest{
 		Username:     req.Username,
+		UserID:       req.UserID,
 		AuthToken:    req.AuthToken,
 		TimelineType: req.TimelineType,
 		BatchSize:    req.BatchSize,
 		Page:         req.Page,
 		MediaType:    req.MediaType,
-		Retweets:     req.Retweets,
+		Retweets:     defaults.Retweets,
+		MaxEntries:   req.MaxEntries,
+		Cursor:       req.Cursor,
+
+		AdaptiveBatch: req.AdaptiveBatch,
+		PageDelayMin:  req.PageDelayMin,
+		PageDelayMax:  req.PageDelayMax,
+		NoCache:       req.NoCache,
+	}
+
+	if backendReq.TimelineType == "" {
+		backendReq.TimelineType = defaults.TimelineType
+	}
+	if backendReq.MediaType == "" {
+		backendReq.MediaType = defaults.MediaType
+	}
+	if backendReq.BatchSize == 0 {
+		backendReq.BatchSize = defaults.BatchSize
+	}
+	if req.Retweets != nil {
+		backendReq.Retweets = *req.Retweets
+	}
+
+	return backendReq
+}
+
+// ExtractTimelineStruct extracts media from user timeline and returns the
+// response directly, so Wails serializes it once. Tweet IDs stay strings.
+func (a *App) ExtractTimelineStruct(req TimelineRequest) (*backend.TwitterResponse, error) {
+	if req.Username == "" && req.UserID == "" {
+		return nil, fmt.Errorf("username or user ID is required")
+	}
+	req.AuthToken = backend.ResolveAuthToken(req.AuthToken)
+	if req.AuthToken == "" {
+		return nil, fmt.Errorf("auth token is required")
+	}
+
+	response, err := backend.ExtractTimeline(req.toBackend())
+	if err != nil {
+		return nil, fmt.Errorf("failed to extract timeline: %v", err)
+	}
+
+	return response, nil
+}
+
+// ExtractProgress represents a page progress event of a full-timeline extraction
+type ExtractProgress struct {
+	Username     string `json:"username"`
+	Page         int    `json:"page"`
+	PageEntries  int    `json:"page_entries"`
+	TotalEntries int    `json:"total_entries"`
+	BatchSize    int    `json:"batch_size"` // effective page size, changes in adaptive mode
+}
+
+// ExtractWait is emitted as "extract-wait" while pausing before the next page
+type ExtractWait struct {
+	Username string `json:"username"`
+	NextPage int    `json:"next_page"`
+	DelayMs  int64  `json:"delay_ms"`
+}
+
+// ExtractFullTimeline extracts every page of a timeline, emitting
+// extract-progress per page and extract-wait during the pause between pages.
+// An interrupted run is resumed on the next call.
+func (a *App) ExtractFullTimeline(req TimelineRequest) (*backend.TwitterResponse, error) {
+	if req.Username == "" && req.UserID == "" {
+		return nil, fmt.Errorf("username or user ID is required")
+	}
+	req.AuthToken = backend.ResolveAuthToken(req.AuthToken)
+	if req.AuthToken == "" {
+		return nil, fmt.Errorf("auth token is required")
+	}
+
+	a.activeOps.Add(1)
+	defer a.activeOps.Done()
+
+	// Only one full extraction runs at a time
+	ctx, release := a.extract.start()
+	defer release()
+
+	opID := backend.StartOperation(backend.OperationExtract, req.Username)
+	defer backend.FinishOperation(opID)
+
+	response, err := backend.ExtractFullTimeline(ctx, req.toBackend(), func(page, pageEntries, totalEntries, batchSize int) {
+		backend.UpdateOperation(opID, int64(page), 0)
+		runtime.EventsEmit(a.ctx, "extract-progress", ExtractProgress{
+			Username:     req.Username,
+			Page:         page,
+			PageEntries:  pageEntries,
+			TotalEntries: totalEntries,
+			BatchSize:    batchSize,
+		})
+	}, func(nextPage int, delay time.Duration) {
+		runtime.EventsEmit(a.ctx, "extract-wait", ExtractWait{
+			Username: req.Username,
+			NextPage: nextPage,
+			DelayMs:  delay.Milliseconds(),
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to extract timeline: %v", err)
+	}
+
+	return response, nil
+}
+
+// TimelineBatchEvent represents a per-account start/finish event of a batch extraction
+type TimelineBatchEvent struct {
+	Username string `json:"username"`
+	Success  bool   `json:"success"`
+	Error    string `json:"error,omitempty"`
+	Entries  int    `json:"entries"`
+}
+
+// ExtractTimelineBatch extracts several accounts with bounded concurrency
+// (0 = default), rotating through authTokens when more than one is given.
+// Emits timeline-batch-start and timeline-batch-finish per account.
+func (a *App) ExtractTimelineBatch(usernames []string, req TimelineRequest, authTokens []string, concurrency int) ([]backend.TimelineBatchResult, error) {
+	if len(usernames) == 0 {
+		return nil, fmt.Errorf("no usernames provided")
+	}
+	req.AuthToken = backend.ResolveAuthToken(req.AuthToken)
+	if req.AuthToken == "" && len(authTokens) == 0 {
+		return nil, fmt.Errorf("auth token is required")
+	}
+
+	a.activeOps.Add(1)
+	defer a.activeOps.Done()
+
+	// Shares the full-extraction slot so StopExtraction cancels a batch too
+	ctx, release := a.extract.start()
+	defer release()
+
+	opID := backend.StartOperation(backend.OperationExtract, strings.Join(usernames, ", "))
+	defer backend.FinishOperation(opID)
+	var finished int64
+
+	results := backend.ExtractTimelineBatch(ctx, usernames, req.toBackend(), backend.TimelineBatchOptions{
+		Concurrency: concurrency,
+		AuthTokens:  authTokens,
+		OnStart: func(username string) {
+			runtime.EventsEmit(a.ctx, "timeline-batch-start", TimelineBatchEvent{Username: username})
+		},
+		OnFinish: func(result backend.TimelineBatchResult) {
+			backend.UpdateOperation(opID, atomic.AddInt64(&finished, 1), int64(len(usernames)))
+			event := TimelineBatchEvent{
+				Username: result.Username,
+				Success:  result.Error == "",
+				Error:    result.Error,
+			}
+			if result.Response != nil {
+				event.Entries = len(result.Response.Timeline)
+			}
+			runtime.EventsEmit(a.ctx, "timeline-batch-finish", event)
+		},
+	})
+
+	return results, nil
+}
+
+// ExtractAndSaveResult is the per-account event of ExtractAndSaveMany
+type ExtractAndSaveResult struct {
+	Username string `json:"username"`
+	Index    int    `json:"index"`
+	Total    int    `json:"total"`
+	Success  bool   `json:"success"`
+	Error    string `json:"error,omitempty"`
+	Entries  int    `json:"entries"`
+}
+
+// ExtractAndSaveMany extracts each account in turn and saves it to the
+// library, emitting extract-save-progress per account. Failed accounts are
+// listed in the summary without stopping the batch; StopExtraction cancels it.
+func (a *App) ExtractAndSaveMany(usernames []string, authToken string, req TimelineRequest) (backend.ExtractSaveSummary, error) {
+	if len(usernames) == 0 {
+		return backend.ExtractSaveSummary{}, fmt.Errorf("no usernames provided")
+	}
+	if authToken != "" {
+		req.AuthToken = authToken
+	}
+	req.AuthToken = backend.ResolveAuthToken(req.AuthToken)
+	if req.AuthToken == "" {
+		return backend.ExtractSaveSummary{}, fmt.Errorf("auth token is required")
+	}
+
+	a.activeOps.Add(1)
+	defer a.activeOps.Done()
+
+	// Shares the full-extraction slot so StopExtraction cancels it too
+	ctx, release := a.extract.start()
+	defer release()
+
+	opID := backend.StartOperation(backend.OperationExtract, strings.Join(usernames, ", "))
+	defer backend.FinishOperation(opID)
+
+	summary := backend.ExtractAndSaveMany(ctx, usernames, req.toBackend(), func(index int, username string, entries int, err error) {
+		backend.UpdateOperation(opID, int64(index+1), int64(len(usernames)))
+		event := ExtractAndSaveResult{
+			Username: username,
+			Index:    index + 1,
+			Total:    len(usernames),
+			Success:  err == nil,
+			Entries:  entries,
+		}
+		if err != nil {
+			event.Error = err.Error()
+		}
+		runtime.EventsEmit(a.ctx, "extract-save-progress", event)
+	})
+
+	return summary, nil
+}
+
+// StopExtraction cancels the running full-timeline extraction after the current page
+func (a *App) StopExtraction() bool {
+	return a.extract.stop()
+}
+
+// ClearExtractionProgress discards the saved position of an interrupted extraction
+func (a *App) ClearExtractionProgress(req TimelineRequest) error {
+	return backend.ClearExtractionProgress(req.toBackend())
+}
+
+// ExtractTimeline extracts media from user timeline. With req.AutoSave the
+// result is also saved to the library and the returned JSON carries its
+// account_id.
+func (a *App) ExtractTimeline(req TimelineRequest) (string, error) {
+	if req.Username == "" && req.UserID == "" {
+		return "", fmt.Errorf("username or user ID is required")
+	}
+	req.AuthToken = backend.ResolveAuthToken(req.AuthToken)
+	if req.AuthToken == "" {
+		return "", fmt.Errorf("auth token is required")
 	}
 
+	backendReq := req.toBackend()
+
 	response, err := backend.ExtractTimeline(backendReq)
 	if err != nil {
 		return "", fmt.Errorf("failed to extract timeline: %v", err)
 	}
 
-	jsonData, err := json.MarshalIndent(response, "", "  ")
+	if req.AutoSave {
+		// Save under the handle X reports, not the URL, @handle or ID typed in,
+		// so the account isn't stored twice
+		username := response.AccountInfo.Name
+		if username == "" {
+			username = req.Username
+		}
+		id, err := backend.SaveExtractedAccount(username, response)
+		if err != nil {
+			return "", err
+		}
+		return encodeResponse(savedTimelineResponse{TwitterResponse: response, AccountID: id}, req.Pretty)
+	}
+
+	return encodeResponse(response, req.Pretty)
+}
+
+// ExtractTimelineAll extracts every page of a timeline into one response.
+// When continueOnError is set, pages that keep failing are skipped and listed.
+func (a *App) ExtractTimelineAll(req TimelineRequest, continueOnError bool) (*backend.TimelineAllResponse, error) {
+	if req.Username == "" && req.UserID == "" {
+		return nil, fmt.Errorf("username or user ID is required")
+	}
+	req.AuthToken = backend.ResolveAuthToken(req.AuthToken)
+	if req.AuthToken == "" {
+		return nil, fmt.Errorf("auth token is required")
+	}
+
+	backendReq := req.toBackend()
+
+	response, err := backend.ExtractTimelineAll(backendReq, backend.TimelineAllOptions{
+		ContinueOnError: continueOnError,
+	})
 	if err != nil {
-		return "", fmt.Errorf("failed to encode response: %v", err)
+		return nil, fmt.Errorf("failed to extract timeline: %v", err)
 	}
 
-	return string(jsonData), nil
+	return response, nil
 }
 
 // ExtractDateRange extracts media based on date range
@@ -92,6 +572,7 @@ func (a *App) ExtractDateRange(req DateRangeRequest) (string, error) {
 	if req.Username == "" {
 		return "", fmt.Errorf("username is required")
 	}
+	req.AuthToken = backend.ResolveAuthToken(req.AuthToken)
 	if req.AuthToken == "" {
 		return "", fmt.Errorf("auth token is required")
 	}
@@ -115,12 +596,43 @@ func (a *App) ExtractDateRange(req DateRangeRequest) (string, error) {
 		return "", fmt.Errorf("failed to extract date range: %v", err)
 	}
 
-	jsonData, err := json.MarshalIndent(response, "", "  ")
+	return encodeResponse(response, req.Pretty)
+}
+
+// ExtractTweetsBatch extracts media from a list of tweet URLs
+func (a *App) ExtractTweetsBatch(urls []string, authToken string) (*backend.TweetBatchResponse, error) {
+	if len(urls) == 0 {
+		return nil, fmt.Errorf("no URLs provided")
+	}
+	authToken = backend.ResolveAuthToken(authToken)
+	if authToken == "" {
+		return nil, fmt.Errorf("auth token is required")
+	}
+
+	response, err := backend.ExtractTweetsBatch(urls, authToken)
 	if err != nil {
-		return "", fmt.Errorf("failed to encode response: %v", err)
+		return nil, fmt.Errorf("failed to extract tweets: %v", err)
 	}
 
-	return string(jsonData), nil
+	return response, nil
+}
+
+// ExtractTweet extracts media from a single tweet URL or ID
+func (a *App) ExtractTweet(urlOrID string, authToken string) (*backend.TwitterResponse, error) {
+	if urlOrID == "" {
+		return nil, fmt.Errorf("tweet URL or ID is required")
+	}
+	authToken = backend.ResolveAuthToken(authToken)
+	if authToken == "" {
+		return nil, fmt.Errorf("auth token is required")
+	}
+
+	response, err := backend.ExtractTweet(urlOrID, authToken)
+	if err != nil {
+		return nil, fmt.Errorf("failed to extract tweet: %v", err)
+	}
+
+	return response, nil
 }
 
 // OpenFolder opens a folder in the file explorer
@@ -137,11 +649,63 @@ func (a *App) OpenFolder(path string) error {
 	return nil
 }
 
+// RevealFile opens the folder containing a file with the file selected,
+// falling back to just opening the folder
+func (a *App) RevealFile(path string) error {
+	if path == "" {
+		return fmt.Errorf("path is required")
+	}
+
+	if err := backend.RevealFile(path); err != nil {
+		return fmt.Errorf("failed to reveal file: %v", err)
+	}
+	return nil
+}
+
+// GetDataDir returns the app data directory path
+func (a *App) GetDataDir() string {
+	return backend.GetDataDir()
+}
+
+// OpenDataDir opens the app data directory in the file explorer
+func (a *App) OpenDataDir() error {
+	if err := backend.OpenDataDir(); err != nil {
+		return fmt.Errorf("failed to open data directory: %v", err)
+	}
+	return nil
+}
+
 // SelectFolder opens a folder selection dialog and returns the selected path
 func (a *App) SelectFolder(defaultPath string) (string, error) {
 	return backend.SelectFolderDialog(a.ctx, defaultPath)
 }
 
+// OutputPathCheck is the result of ValidateOutputPath
+type OutputPathCheck struct {
+	Valid  bool   `json:"valid"`
+	Reason string `json:"reason,omitempty"`
+}
+
+// ValidateOutputPath checks a typed or pasted download folder before it is
+// used: absolute, valid on this OS, on an attached volume and writable
+func (a *App) ValidateOutputPath(path string) OutputPathCheck {
+	valid, reason := backend.ValidateOutputPath(path)
+	return OutputPathCheck{Valid: valid, Reason: reason}
+}
+
+// CopyToClipboard copies text such as a media URL or file path to the clipboard
+func (a *App) CopyToClipboard(text string) error {
+	if text == "" {
+		return fmt.Errorf("text is required")
+	}
+
+	if err := runtime.ClipboardSetText(a.ctx, text); err != nil {
+		return fmt.Errorf("failed to copy to clipboard: %v", err)
+	}
+
+	return nil
+}
+
 // GetDefaults returns the default configuration
 func (a *App) GetDefaults() map[string]string {
 	return map[string]string{
@@ -149,9 +713,46 @@ func (a *App) GetDefaults() map[string]string {
 	}
 }
 
-// Quit closes the application
+// VersionInfo represents the app and bundled tool versions
+type VersionInfo struct {
+	AppVersion       string `json:"app_version"`
+	ExtractorVersion string `json:"extractor_version"`
+	FFmpegVersion    string `json:"ffmpeg_version"`
+	GoVersion        string `json:"go_version"`
+}
+
+// DatabaseStatus represents whether the database-backed features are available
+type DatabaseStatus struct {
+	Available bool   `json:"available"`
+	Error     string `json:"error,omitempty"`
+}
+
+// IsDatabaseAvailable reports whether saved accounts, history and settings can be used
+func (a *App) IsDatabaseAvailable() bool {
+	return backend.IsDatabaseAvailable()
+}
+
+// GetDatabaseStatus returns the database availability and, if unavailable, why
+func (a *App) GetDatabaseStatus() DatabaseStatus {
+	return DatabaseStatus{
+		Available: backend.IsDatabaseAvailable(),
+		Error:     backend.DatabaseError(),
+	}
+}
+
+// GetVersionInfo returns version and build metadata for bug reports
+func (a *App) GetVersionInfo() VersionInfo {
+	return VersionInfo{
+		AppVersion:       AppVersion,
+		ExtractorVersion: backend.GetExtractorVersion(),
+		FFmpegVersion:    backend.GetFFmpegVersion(),
+		GoVersion:        goruntime.Version(),
+	}
+}
+
+// Quit closes the application through the Wails runtime so shutdown runs
 func (a *App) Quit() {
-	panic("quit")
+	runtime.Quit(a.ctx)
 }
 
 // DownloadMediaRequest represents the request for downloading media (legacy)
@@ -167,13 +768,66 @@ type MediaItemRequest struct {
 	Date    string                `json:"date"`
 	TweetID backend.TweetIDString `json:"tweet_id"`
 	Type    string                `json:"type"`
+	Text    string                `json:"text,omitempty"`
+	AltText string                `json:"alt_text,omitempty"`
+	// Username is the item's account, used when items from different accounts are exported together
+	Username string `json:"username,omitempty"`
 }
 
 // DownloadMediaWithMetadataRequest represents the request for downloading media with metadata
 type DownloadMediaWithMetadataRequest struct {
-	Items     []MediaItemRequest `json:"items"`
-	OutputDir string             `json:"output_dir"`
-	Username  string             `json:"username"`
+	Items             []MediaItemRequest    `json:"items"`
+	OutputDir         string                `json:"output_dir"`
+	MinTweetID        backend.TweetIDString `json:"min_tweet_id"` // 0 = no lower bound
+	MaxTweetID        backend.TweetIDString `json:"max_tweet_id"` // 0 = no upper bound
+	Username          string                `json:"username"`
+	FilenameTemplate  string                `json:"filename_template"`
+	MaxFilenameLength int                   `json:"max_filename_length"`
+	DateInputFormat   string                `json:"date_input_format"`
+	DateOutputFormat  string                `json:"date_output_format"`
+	GenerateGallery   bool                  `json:"generate_gallery"`
+	ArchiveIndex      bool                  `json:"archive_index"`
+	EmbedMetadata     bool                  `json:"embed_metadata"`
+	WriteAltText      bool                  `json:"write_alt_text"`
+	FullResolution    bool                  `json:"full_resolution"`
+	ImageFormat       string                `json:"image_format"`    // jpg, png or webp, "" = as served
+	Retries           *int                  `json:"retries"`         // extra attempts per file, nil = default, 0 = no retries
+	MaxRetryAfter     int                   `json:"max_retry_after"` // seconds, longest Retry-After wait honoured, 0 = default
+	HostConcurrency   map[string]int        `json:"host_concurrency"`
+	CircuitBreaker    int                   `json:"circuit_breaker"` // consecutive failures that stop the batch, 0 = default, < 0 = off
+	IncludeProfile    bool                  `json:"include_profile"`
+	ProfileImage      string                `json:"profile_image"`
+	ProfileBanner     string                `json:"profile_banner"`
+	MinWidth          int                   `json:"min_width"`
+	MinHeight         int                   `json:"min_height"`
+	FilterHosts       bool                  `json:"filter_hosts"`
+	AllowedHosts      []string              `json:"allowed_hosts"`
+	BlockedHosts      []string              `json:"blocked_hosts"`
+	OverwritePolicy   string                `json:"overwrite_policy"`  // skip (default), overwrite or rename
+	AutoConvertGIFs   bool                  `json:"auto_convert_gifs"` // convert the gifs folder after downloading
+	GIFFPS            int                   `json:"gif_fps"`
+	GIFWidth          int                   `json:"gif_width"`
+	GIFDeleteOriginal bool                  `json:"gif_delete_original"`
+	Limit             int                   `json:"limit"`       // download only the newest N items, 0 = all
+	UseSession        bool                  `json:"use_session"` // send the extraction session's cookies to the media CDN
+	AuthToken         string                `json:"auth_token"`  // session to reuse, "" = the configured token
+
+	// Stop cleanly when free space on the output volume drops below
+	// MinFreeBytes, checked every FreeSpaceCheckEvery items (0 = default)
+	MinFreeBytes        int64 `json:"min_free_bytes"`
+	FreeSpaceCheckEvery int   `json:"free_space_check_every"`
+}
+
+// downloadRetries maps the request's retry count to DownloadOptions.Retries,
+// where 0 means the default and a negative value means none
+func downloadRetries(retries *int) int {
+	switch {
+	case retries == nil:
+		return 0
+	case *retries <= 0:
+		return -1
+	}
+	return *retries
 }
 
 // DownloadMediaResponse represents the response for download operation
@@ -181,7 +835,28 @@ type DownloadMediaResponse struct {
 	Success    bool   `json:"success"`
 	Downloaded int    `json:"downloaded"`
 	Failed     int    `json:"failed"`
+	Skipped    int    `json:"skipped,omitempty"`
+	Filtered   int    `json:"filtered,omitempty"` // outside the tweet ID range
+	Trimmed    int    `json:"trimmed,omitempty"`  // older than the newest Limit items
 	Message    string `json:"message"`
+	JobID      string `json:"job_id,omitempty"`
+
+	// Files that already existed, by the overwrite policy applied to them
+	SkippedExisting int `json:"skipped_existing,omitempty"`
+	Overwritten     int `json:"overwritten,omitempty"`
+	Renamed         int `json:"renamed,omitempty"`
+
+	GIFsConverted int `json:"gifs_converted,omitempty"` // with AutoConvertGIFs
+
+	// HTTP requests made across all items, and items that only succeeded on
+	// a retry; many of those point to a flaky connection
+	TotalAttempts       int `json:"total_attempts"`
+	SucceededAfterRetry int `json:"succeeded_after_retry"`
+
+	LowDiskSpace bool `json:"low_disk_space,omitempty"` // stopped early by MinFreeBytes
+	Cancelled    bool `json:"cancelled,omitempty"`      // stopped by StopDownload or CancelJob
+
+	FailedItems []backend.FailedItem `json:"failed_items,omitempty"`
 }
 
 // DownloadMedia downloads media files from URLs (legacy)
@@ -200,7 +875,7 @@ func (a *App) DownloadMedia(req DownloadMediaRequest) (DownloadMediaResponse, er
 
 	// Create subfolder for username if provided
 	if req.Username != "" {
-		outputDir = filepath.Join(outputDir, req.Username)
+		outputDir = filepath.Join(outputDir, backend.SanitizeFilename(req.Username))
 	}
 
 	downloaded, failed, err := backend.DownloadMediaFiles(req.URLs, outputDir)
@@ -223,9 +898,12 @@ func (a *App) DownloadMedia(req DownloadMediaRequest) (DownloadMediaResponse, er
 
 // DownloadProgress represents download progress event data
 type DownloadProgress struct {
-	Current int `json:"current"`
-	Total   int `json:"total"`
-	Percent int `json:"percent"`
+	JobID            string  `json:"job_id,omitempty"`
+	Current          int     `json:"current"`
+	Total            int     `json:"total"`
+	Percent          int     `json:"percent"`
+	BytesPerSec      float64 `json:"bytes_per_sec"`
+	SecondsRemaining int     `json:"seconds_remaining"` // -1 while unknown
 }
 
 // DownloadMediaWithMetadata downloads media files with proper naming and categorization
@@ -239,7 +917,7 @@ func (a *App) DownloadMediaWithMetadata(req DownloadMediaWithMetadataRequest) (D
 
 	outputDir := req.OutputDir
 	if outputDir == "" {
-		outputDir = backend.GetDefaultDownloadPath()
+		outputDir = accountOutputDir(req.Username)
 	}
 
 	// Convert request items to backend items
@@ -251,55 +929,775 @@ func (a *App) DownloadMediaWithMetadata(req DownloadMediaWithMetadataRequest) (D
 			TweetID:  int64(item.TweetID),
 			Type:     item.Type,
 			Username: req.Username,
+			Text:     item.Text,
+			AltText:  item.AltText,
 		}
 	}
 
-	// Create cancellable context
-	a.downloadCtx, a.downloadCancel = context.WithCancel(context.Background())
+	items, filtered := backend.FilterByTweetIDRange(items, int64(req.MinTweetID), int64(req.MaxTweetID))
+	if len(items) == 0 {
+		return DownloadMediaResponse{
+			Success:  true,
+			Filtered: filtered,
+			Message:  fmt.Sprintf("No items in the tweet ID range, %d filtered out", filtered),
+		}, nil
+	}
+
+	items, trimmed := backend.LimitNewest(items, req.Limit, req.DateInputFormat)
+
+	opts := backend.DownloadOptions{
+		FilenameTemplate:  req.FilenameTemplate,
+		MaxFilenameLength: req.MaxFilenameLength,
+		DateInputFormat:   req.DateInputFormat,
+		DateOutputFormat:  req.DateOutputFormat,
+		GenerateGallery:   req.GenerateGallery,
+		ArchiveIndex:      req.ArchiveIndex,
+		EmbedMetadata:     req.EmbedMetadata,
+		WriteAltText:      req.WriteAltText,
+		FullResolution:    req.FullResolution,
+		ImageFormat:       req.ImageFormat,
+		Retries:           downloadRetries(req.Retries),
+		MaxRetryAfter:     time.Duration(req.MaxRetryAfter) * time.Second,
+		HostConcurrency:   req.HostConcurrency,
+		CircuitBreaker:    req.CircuitBreaker,
+		MinWidth:          req.MinWidth,
+		MinHeight:         req.MinHeight,
+		FilterHosts:       req.FilterHosts,
+		AllowedHosts:      req.AllowedHosts,
+		BlockedHosts:      req.BlockedHosts,
+		OverwritePolicy:   req.OverwritePolicy,
+
+		MinFreeBytes:        req.MinFreeBytes,
+		FreeSpaceCheckEvery: req.FreeSpaceCheckEvery,
+	}
+	if req.UseSession {
+		opts.Cookies = backend.SessionCookies(backend.ResolveAuthToken(req.AuthToken))
+	}
+
+	// Avatar and banner are best effort and don't affect the media counts
+	if req.IncludeProfile {
+		backend.DownloadProfileAssets(req.ProfileImage, req.ProfileBanner, outputDir, req.Username)
+	}
+
+	// Convert the gifs folder as part of the download job
+	var afterDownload func(ctx context.Context)
+	var gifsConverted int
+	var gifMessage string
+	if req.AutoConvertGIFs && hasGIFs(items) {
+		baseDir := filepath.Join(outputDir, backend.SanitizeFilename(req.Username))
+		afterDownload = func(ctx context.Context) {
+			gifsConverted, gifMessage = a.autoConvertGIFs(ctx, baseDir, req.GIFFPS, req.GIFWidth, req.GIFDeleteOriginal)
+		}
+	}
+
+	response, err := a.runDownload(items, outputDir, req.Username, opts, afterDownload)
+	response.Filtered = filtered
+	response.Trimmed = trimmed
+	if err == nil && filtered > 0 {
+		response.Message += fmt.Sprintf(", %d outside the tweet ID range", filtered)
+	}
+	if err == nil && trimmed > 0 {
+		response.Message += fmt.Sprintf(", %d older items left out by the limit", trimmed)
+	}
+
+	if gifMessage != "" {
+		response.GIFsConverted = gifsConverted
+		response.Message += ", " + gifMessage
+	}
+	return response, err
+}
+
+// hasGIFs reports whether any item is saved to the gifs folder
+func hasGIFs(items []backend.MediaItem) bool {
+	for _, item := range items {
+		if item.Type == "gif" || item.Type == "animated_gif" {
+			return true
+		}
+	}
+	return false
+}
+
+// autoConvertGIFs converts a finished download's gifs folder under the
+// download job's ctx, emitting gif-convert-progress, and returns the count and
+// a summary for the download message. It doesn't take the shared conversion
+// slot, so a manual conversion can't cancel it. Without ffmpeg it only warns.
+func (a *App) autoConvertGIFs(ctx context.Context, baseDir string, fps, width int, deleteOriginal bool) (int, string) {
+	if !backend.IsFFmpegInstalled() {
+		runtime.LogWarning(a.ctx, "GIF conversion skipped: ffmpeg is not installed")
+		return 0, "GIF conversion skipped (FFmpeg not installed)"
+	}
 
-	// Progress callback
 	progressCallback := func(current, total int) {
 		percent := 0
 		if total > 0 {
 			percent = (current * 100) / total
 		}
-		runtime.EventsEmit(a.ctx, "download-progress", DownloadProgress{
+		runtime.EventsEmit(a.ctx, "gif-convert-progress", DownloadProgress{
 			Current: current,
 			Total:   total,
 			Percent: percent,
 		})
 	}
 
-	downloaded, failed, err := backend.DownloadMediaWithMetadataProgress(items, outputDir, req.Username, progressCallback, a.downloadCtx)
+	converted, _, failed, err := backend.ConvertGIFsInFolderProgress(ctx, baseDir, "", fps, width, 0, deleteOriginal, false, 0, progressCallback)
+	if err == context.Canceled {
+		return converted, fmt.Sprintf("GIF conversion stopped after %d GIFs", converted)
+	}
 	if err != nil {
-		return DownloadMediaResponse{
-			Success:    false,
-			Downloaded: downloaded,
-			Failed:     failed,
-			Message:    err.Error(),
-		}, err
+		runtime.LogErrorf(a.ctx, "GIF conversion failed: %v", err)
+		return converted, fmt.Sprintf("GIF conversion failed: %v", err)
 	}
-
-	// Clear cancel function
-	a.downloadCancel = nil
-
-	return DownloadMediaResponse{
-		Success:    true,
-		Downloaded: downloaded,
-		Failed:     failed,
-		Message:    fmt.Sprintf("Downloaded %d files, %d failed", downloaded, failed),
-	}, nil
+	return converted, fmt.Sprintf("converted %d GIFs, %d failed", converted, failed)
 }
 
-// StopDownload cancels the current download operation
-func (a *App) StopDownload() bool {
-	if a.downloadCancel != nil {
-		a.downloadCancel()
-		a.downloadCancel = nil
-		return true
-	}
-	return false
-}
+// ExportSelectedMedia downloads just the given items into a new
+// selection_<timestamp> folder under outputDir (empty = default download
+// path), separate from the accounts' main archives. Progress is emitted as
+// download-progress like any other download.
+func (a *App) ExportSelectedMedia(items []MediaItemRequest, outputDir string) (DownloadMediaResponse, error) {
+	if len(items) == 0 {
+		return DownloadMediaResponse{
+			Success: false,
+			Message: "No items provided",
+		}, fmt.Errorf("no items provided")
+	}
+
+	if outputDir == "" {
+		outputDir = backend.GetDefaultDownloadPath()
+	}
+	exportDir := filepath.Join(outputDir, "selection_"+time.Now().Format("20060102_150405"))
+
+	// Files are named after the account when the selection comes from one
+	username := items[0].Username
+	mediaItems := make([]backend.MediaItem, len(items))
+	for i, item := range items {
+		if item.Username != username {
+			username = ""
+		}
+		mediaItems[i] = backend.MediaItem{
+			URL:      item.URL,
+			Date:     item.Date,
+			TweetID:  int64(item.TweetID),
+			Type:     item.Type,
+			Username: item.Username,
+			Text:     item.Text,
+			AltText:  item.AltText,
+		}
+	}
+	if username == "" {
+		username = "selection"
+	}
+
+	response, err := a.runDownload(mediaItems, exportDir, username, backend.DownloadOptions{}, nil)
+	if err == nil {
+		response.Message += fmt.Sprintf(", saved to %s", exportDir)
+	}
+	return response, err
+}
+
+// runDownload runs a cancellable, pausable download with progress events and
+// records the run in the download history. afterDownload, if set, runs once a
+// completed download's files are in place, still as part of the job, so
+// cancelling the job stops it too.
+func (a *App) runDownload(items []backend.MediaItem, outputDir string, username string, opts backend.DownloadOptions, afterDownload func(ctx context.Context)) (DownloadMediaResponse, error) {
+	a.activeOps.Add(1)
+	defer a.activeOps.Done()
+
+	// Each run is a job with its own cancellable context and pause control
+	job, ctx := a.startJob(username, len(items))
+	defer a.finishJob(job)
+	opID := backend.StartOperation(backend.OperationDownload, username)
+	defer backend.FinishOperation(opID)
+	opts.Pause = job.pause
+	runtime.EventsEmit(a.ctx, "download-job-started", DownloadJobInfo{
+		ID:       job.id,
+		Username: username,
+		Total:    len(items),
+	})
+	meter := backend.NewThroughputMeter()
+	opts.Throughput = meter
+
+	// Progress callback
+	progressCallback := func(current, total int) {
+		percent := 0
+		if total > 0 {
+			percent = (current * 100) / total
+		}
+		backend.UpdateOperation(opID, int64(current), int64(total))
+		runtime.EventsEmit(a.ctx, "download-progress", DownloadProgress{
+			JobID:            job.id,
+			Current:          current,
+			Total:            total,
+			Percent:          percent,
+			BytesPerSec:      meter.BytesPerSec(),
+			SecondsRemaining: meter.SecondsRemaining(current, total),
+		})
+	}
+
+	// Collect failed items for the history record
+	var failedMu sync.Mutex
+	failedItems := []backend.MediaItem{}
+	failureDetails := []backend.FailedItem{}
+	opts.OnFailure = func(item backend.MediaItem, err error) {
+		failedMu.Lock()
+		failedItems = append(failedItems, item)
+		failureDetails = append(failureDetails, backend.NewFailedItem(item, err))
+		failedMu.Unlock()
+	}
+
+	// Count items skipped by filters
+	var skipped int64
+	opts.OnSkipped = func(item backend.MediaItem, reason string) {
+		atomic.AddInt64(&skipped, 1)
+	}
+
+	// Count existing files per overwrite policy
+	var skippedExisting, overwritten, renamed int64
+	opts.OnExisting = func(item backend.MediaItem, policy string) {
+		switch policy {
+		case backend.OverwriteReplace:
+			atomic.AddInt64(&overwritten, 1)
+		case backend.OverwriteRename:
+			atomic.AddInt64(&renamed, 1)
+		default:
+			atomic.AddInt64(&skippedExisting, 1)
+		}
+	}
+
+	// Count HTTP attempts to tell a flaky connection from missing content
+	var totalAttempts, succeededAfterRetry int64
+	opts.OnAttempts = func(item backend.MediaItem, attempts int, err error) {
+		atomic.AddInt64(&totalAttempts, int64(attempts))
+		if err == nil && attempts > 1 {
+			atomic.AddInt64(&succeededAfterRetry, 1)
+		}
+	}
+
+	// Track which items were actually handled, for the retry queue
+	var attemptedMu sync.Mutex
+	attempted := []backend.MediaItem{}
+	opts.OnDone = func(item backend.MediaItem) {
+		attemptedMu.Lock()
+		attempted = append(attempted, item)
+		attemptedMu.Unlock()
+	}
+
+	downloaded, failed, err := backend.DownloadMediaWithMetadataProgress(items, outputDir, username, opts, progressCallback, ctx)
+
+	// A run cancelled by the user or by shutdown is neither history nor a
+	// reason to rewrite the retry queue; its failures may just be the abort
+	if ctx.Err() == nil {
+		backend.SaveDownloadHistory(backend.DownloadHistoryRecord{
+			Username:    username,
+			OutputDir:   outputDir,
+			Total:       len(items),
+			Downloaded:  downloaded,
+			Failed:      failed,
+			FailedItems: failedItems,
+		})
+
+		// Update the persistent retry queue with the items that were attempted
+		backend.RecordDownloadRun(username, outputDir, attempted, failureDetails, err)
+	}
+
+	if err != nil {
+		message := err.Error()
+		cancelled := errors.Is(err, context.Canceled)
+		if cancelled {
+			message = fmt.Sprintf("Download cancelled after %d files, %d not downloaded", downloaded, failed)
+		}
+		return DownloadMediaResponse{
+			Success:    false,
+			Downloaded: downloaded,
+			Failed:     failed,
+			Skipped:    int(skipped),
+			Message:    message,
+			JobID:      job.id,
+
+			SkippedExisting: int(skippedExisting),
+			Overwritten:     int(overwritten),
+			Renamed:         int(renamed),
+
+			TotalAttempts:       int(totalAttempts),
+			SucceededAfterRetry: int(succeededAfterRetry),
+
+			LowDiskSpace: errors.Is(err, backend.ErrLowDiskSpace),
+			Cancelled:    cancelled,
+
+			FailedItems: failureDetails,
+		}, err
+	}
+
+	// Run the user's post-download command, if configured, only for a run that
+	// completed; a cancellation landing after the last file (e.g. shutdown)
+	// skips it too
+	if ctx.Err() == nil {
+		output, hookErr := backend.RunPostDownloadCommand(outputDir, username, downloaded, failed)
+		if hookErr != nil {
+			runtime.LogErrorf(a.ctx, "%v, output: %s", hookErr, output)
+		} else if output != "" {
+			runtime.LogInfof(a.ctx, "post-download command output: %s", output)
+		}
+	}
+	if afterDownload != nil && ctx.Err() == nil {
+		afterDownload(ctx)
+	}
+
+	message := fmt.Sprintf("Downloaded %d files, %d failed", downloaded, failed)
+	if skipped > 0 {
+		message += fmt.Sprintf(", %d skipped", skipped)
+	}
+	if skippedExisting > 0 {
+		message += fmt.Sprintf(", %d already existed", skippedExisting)
+	}
+	if overwritten > 0 {
+		message += fmt.Sprintf(", %d overwritten", overwritten)
+	}
+	if renamed > 0 {
+		message += fmt.Sprintf(", %d saved under a new name", renamed)
+	}
+
+	return DownloadMediaResponse{
+		Success:    true,
+		Downloaded: downloaded,
+		Failed:     failed,
+		Skipped:    int(skipped),
+		Message:    message,
+		JobID:      job.id,
+
+		SkippedExisting: int(skippedExisting),
+		Overwritten:     int(overwritten),
+		Renamed:         int(renamed),
+
+		TotalAttempts:       int(totalAttempts),
+		SucceededAfterRetry: int(succeededAfterRetry),
+
+		FailedItems: failureDetails,
+	}, nil
+}
+
+// DownloadProfileAssets downloads an account's avatar and banner into its folder
+func (a *App) DownloadProfileAssets(username, profileImage, profileBanner, outputDir string) (int, error) {
+	if username == "" {
+		return 0, fmt.Errorf("username is required")
+	}
+	if outputDir == "" {
+		outputDir = backend.GetDefaultDownloadPath()
+	}
+	return backend.DownloadProfileAssets(profileImage, profileBanner, outputDir, username)
+}
+
+// GetPostDownloadCommand returns the command run after each successful download
+func (a *App) GetPostDownloadCommand() ([]string, error) {
+	return backend.GetPostDownloadCommand()
+}
+
+// SetPostDownloadCommand sets the program and arguments run after each successful
+// download (output dir, username, downloaded and failed counts are appended).
+// This runs arbitrary local commands; pass an empty list to disable it.
+func (a *App) SetPostDownloadCommand(command []string) error {
+	return backend.SetPostDownloadCommand(command)
+}
+
+// GetTransportConfig returns the shared HTTP transport settings
+func (a *App) GetTransportConfig() backend.TransportConfig {
+	return backend.GetTransportConfig()
+}
+
+// SetTransportConfig updates the proxy and connection pool settings used by all downloads
+func (a *App) SetTransportConfig(cfg backend.TransportConfig) error {
+	return backend.ConfigureTransport(cfg)
+}
+
+// ResetHTTPClient drops pooled connections and rebuilds the download transport;
+// use it when downloads start failing after sleep or a network switch
+func (a *App) ResetHTTPClient() error {
+	return backend.ResetHTTPClient()
+}
+
+// MoveDownloadedMedia moves an account's downloaded media to a new base folder
+func (a *App) MoveDownloadedMedia(username, oldBase, newBase string) (int, error) {
+	if username == "" {
+		return 0, fmt.Errorf("username is required")
+	}
+	if oldBase == "" || newBase == "" {
+		return 0, fmt.Errorf("source and destination folders are required")
+	}
+	return backend.MoveDownloadedMedia(username, oldBase, newBase)
+}
+
+// MoveDownloadsRequest represents the request structure for moving or copying downloads
+type MoveDownloadsRequest struct {
+	SrcDir         string `json:"src_dir"`
+	DestDir        string `json:"dest_dir"`
+	Copy           bool   `json:"copy"`            // copy instead of move
+	OnExisting     string `json:"on_existing"`     // skip (default), overwrite or rename
+	UpdateManifest bool   `json:"update_manifest"` // rewrite archive index paths
+}
+
+// MoveDownloads moves or copies downloaded media between folders
+func (a *App) MoveDownloads(req MoveDownloadsRequest) (*backend.MoveDownloadsResult, error) {
+	if req.SrcDir == "" || req.DestDir == "" {
+		return nil, fmt.Errorf("source and destination folders are required")
+	}
+
+	a.activeOps.Add(1)
+	defer a.activeOps.Done()
+
+	opID := backend.StartOperation(backend.OperationMove, req.SrcDir)
+	defer backend.FinishOperation(opID)
+	return backend.MoveDownloads(req.SrcDir, req.DestDir, req.Copy, req.OnExisting, req.UpdateManifest)
+}
+
+// GetDownloadHistory returns the most recent download runs
+func (a *App) GetDownloadHistory(limit int) ([]backend.DownloadHistoryRecord, error) {
+	return backend.GetDownloadHistory(limit)
+}
+
+// RetryHistoryFailures re-downloads the items that failed in a past download run
+func (a *App) RetryHistoryFailures(historyID int64, outputDir string) (DownloadMediaResponse, error) {
+	record, err := backend.GetDownloadHistoryByID(historyID)
+	if err != nil {
+		return DownloadMediaResponse{
+			Success: false,
+			Message: "Download history not found",
+		}, fmt.Errorf("failed to load download history: %v", err)
+	}
+
+	if len(record.FailedItems) == 0 {
+		return DownloadMediaResponse{
+			Success: true,
+			Message: "No failed items to retry",
+		}, nil
+	}
+
+	if outputDir == "" {
+		outputDir = record.OutputDir
+	}
+	if outputDir == "" {
+		outputDir = backend.GetDefaultDownloadPath()
+	}
+
+	return a.runDownload(record.FailedItems, outputDir, record.Username, backend.DownloadOptions{}, nil)
+}
+
+// GetFailedDownloads returns the downloads queued for a later retry
+func (a *App) GetFailedDownloads() ([]backend.FailedDownload, error) {
+	return backend.GetFailedDownloads()
+}
+
+// RetryFailedDownloads re-attempts every queued failed download, grouped by
+// account and output folder. Successes leave the queue; failures stay with
+// their retry counter incremented.
+func (a *App) RetryFailedDownloads() (DownloadMediaResponse, error) {
+	failures, err := backend.GetFailedDownloads()
+	if err != nil {
+		return DownloadMediaResponse{Success: false, Message: err.Error()}, err
+	}
+	if len(failures) == 0 {
+		return DownloadMediaResponse{
+			Success: true,
+			Message: "No failed downloads to retry",
+		}, nil
+	}
+
+	type retryGroup struct {
+		username  string
+		outputDir string
+	}
+	groups := make(map[retryGroup][]backend.MediaItem)
+	var order []retryGroup
+	for _, failure := range failures {
+		key := retryGroup{username: failure.Username, outputDir: failure.OutputDir}
+		if _, ok := groups[key]; !ok {
+			order = append(order, key)
+		}
+		groups[key] = append(groups[key], failure.MediaItem())
+	}
+
+	total := DownloadMediaResponse{Success: true}
+	for _, key := range order {
+		response, err := a.runDownload(groups[key], key.outputDir, key.username, backend.DownloadOptions{}, nil)
+		total.Downloaded += response.Downloaded
+		total.Failed += response.Failed
+		total.Skipped += response.Skipped
+		total.SkippedExisting += response.SkippedExisting
+		total.Overwritten += response.Overwritten
+		total.Renamed += response.Renamed
+		total.TotalAttempts += response.TotalAttempts
+		total.SucceededAfterRetry += response.SucceededAfterRetry
+		total.FailedItems = append(total.FailedItems, response.FailedItems...)
+		if err != nil {
+			total.Success = false
+			total.Cancelled = response.Cancelled
+			total.Message = response.Message
+			return total, err
+		}
+	}
+
+	total.Message = fmt.Sprintf("Retried %d files: %d downloaded, %d failed", len(failures), total.Downloaded, total.Failed)
+	return total, nil
+}
+
+// PreviewFilenameTemplate renders a filename template against a sample item so
+// the settings UI can show a live preview or the validation error
+func (a *App) PreviewFilenameTemplate(template string, sample backend.MediaItem) (string, error) {
+	return backend.PreviewFilenameTemplate(template, sample, backend.DownloadOptions{})
+}
+
+// ListDownloadedMedia lists the media files in a download folder for the archive browser
+func (a *App) ListDownloadedMedia(folderPath string) ([]backend.DownloadedMedia, error) {
+	if folderPath == "" {
+		return nil, fmt.Errorf("folder path is required")
+	}
+	return backend.ListDownloadedMedia(folderPath)
+}
+
+// ListDownloadedFiles lists the files on disk in an account's folder under
+// baseDir (empty = the account's download folder), optionally only one type:
+// photo, video, gif, audio or other
+func (a *App) ListDownloadedFiles(username, baseDir, fileType string) ([]backend.FileInfo, error) {
+	if username == "" {
+		return nil, fmt.Errorf("username is required")
+	}
+	if baseDir == "" {
+		baseDir = accountOutputDir(username)
+	}
+	return backend.ListDownloadedFiles(username, baseDir, fileType)
+}
+
+// DeleteDownloadedFile deletes a file from a download folder along with its sidecar
+func (a *App) DeleteDownloadedFile(path string) error {
+	if path == "" {
+		return fmt.Errorf("path is required")
+	}
+	return backend.DeleteDownloadedFile(path)
+}
+
+// GetArchiveIndex returns every item recorded in an account's archive index
+// under baseDir (empty = the account's download folder)
+func (a *App) GetArchiveIndex(username, baseDir string) ([]backend.ArchiveIndexEntry, error) {
+	if username == "" {
+		return nil, fmt.Errorf("username is required")
+	}
+	if baseDir == "" {
+		baseDir = accountOutputDir(username)
+	}
+	return backend.GetArchiveIndex(username, baseDir)
+}
+
+// MediaCheckRequest holds the items of a preflight check and how hard to hit
+// the CDN while checking them
+type MediaCheckRequest struct {
+	Items       []MediaItemRequest `json:"items"`
+	Username    string             `json:"username"`
+	Concurrency int                `json:"concurrency"`  // 0 = 8 requests at once
+	TimeoutSecs int                `json:"timeout_secs"` // per request, 0 = 10 seconds
+}
+
+// startMediaCheck converts a check request into backend items and options,
+// registering a job so StopDownload and CancelJob cancel the check
+func (a *App) startMediaCheck(req MediaCheckRequest) ([]backend.MediaItem, backend.HeadCheckOptions, *downloadJob, context.Context) {
+	items := make([]backend.MediaItem, len(req.Items))
+	for i, item := range req.Items {
+		items[i] = backend.MediaItem{
+			URL:      item.URL,
+			Date:     item.Date,
+			TweetID:  int64(item.TweetID),
+			Type:     item.Type,
+			Username: req.Username,
+		}
+	}
+	opts := backend.HeadCheckOptions{
+		Concurrency: req.Concurrency,
+		Timeout:     time.Duration(req.TimeoutSecs) * time.Second,
+	}
+	job, ctx := a.startJob(req.Username, len(items))
+	return items, opts, job, ctx
+}
+
+// CheckMediaAvailability reports which items can still be downloaded, using
+// HEAD requests
+func (a *App) CheckMediaAvailability(req MediaCheckRequest) ([]backend.MediaAvailability, error) {
+	items, opts, job, ctx := a.startMediaCheck(req)
+	defer a.finishJob(job)
+	return backend.CheckMediaAvailability(ctx, items, opts)
+}
+
+// EstimateDownloadSize adds up the sizes the CDN reports for the items
+func (a *App) EstimateDownloadSize(req MediaCheckRequest) (backend.DownloadSizeEstimate, error) {
+	items, opts, job, ctx := a.startMediaCheck(req)
+	defer a.finishJob(job)
+	return backend.EstimateDownloadSize(ctx, items, opts)
+}
+
+// VerifyArchive checks the files of an account folder against the sizes and
+// hashes recorded in its archive index
+func (a *App) VerifyArchive(folderPath string) (backend.VerifyResult, error) {
+	if folderPath == "" {
+		return backend.VerifyResult{}, fmt.Errorf("folder path is required")
+	}
+	return backend.VerifyArchive(folderPath)
+}
+
+// RepairArchive re-downloads the missing and corrupt files of an account
+// folder from their indexed URLs, emitting archive-repair-progress events.
+// StopDownload cancels it.
+func (a *App) RepairArchive(folderPath string) (*backend.RepairResult, error) {
+	if folderPath == "" {
+		return nil, fmt.Errorf("folder path is required")
+	}
+
+	a.activeOps.Add(1)
+	defer a.activeOps.Done()
+
+	job, ctx := a.startJob(filepath.Base(folderPath), 0)
+	defer a.finishJob(job)
+
+	opID := backend.StartOperation(backend.OperationRepair, folderPath)
+	defer backend.FinishOperation(opID)
+
+	progress := func(current, total int) {
+		backend.UpdateOperation(opID, int64(current), int64(total))
+		percent := 0
+		if total > 0 {
+			percent = (current * 100) / total
+		}
+		runtime.EventsEmit(a.ctx, "archive-repair-progress", DownloadProgress{
+			Current: current,
+			Total:   total,
+			Percent: percent,
+		})
+	}
+
+	return backend.RepairArchive(ctx, folderPath, progress)
+}
+
+// GenerateGallery writes an offline index.html gallery into a download folder
+func (a *App) GenerateGallery(folderPath string) (string, error) {
+	if folderPath == "" {
+		return "", fmt.Errorf("folder path is required")
+	}
+	return backend.GenerateGallery(folderPath)
+}
+
+// StopDownload cancels the current download operation
+func (a *App) StopDownload() bool {
+	jobs := a.activeJobs()
+	for _, job := range jobs {
+		job.cancel()
+	}
+	return len(jobs) > 0
+}
+
+// CancelJob cancels one running download job by ID, leaving others running.
+// Returns false if no such job is running.
+func (a *App) CancelJob(id string) bool {
+	a.jobsMu.Lock()
+	job, ok := a.jobs[id]
+	a.jobsMu.Unlock()
+	if !ok {
+		return false
+	}
+
+	// A paused job stops waiting as soon as its context is cancelled
+	job.cancel()
+	return true
+}
+
+// GetActiveJobs lists the running download jobs
+func (a *App) GetActiveJobs() []DownloadJobInfo {
+	jobs := a.activeJobs()
+	infos := make([]DownloadJobInfo, 0, len(jobs))
+	for _, job := range jobs {
+		infos = append(infos, DownloadJobInfo{
+			ID:       job.id,
+			Username: job.username,
+			Total:    job.total,
+			Paused:   job.pause.IsPaused(),
+		})
+	}
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].ID < infos[j].ID
+	})
+	return infos
+}
+
+// GetActiveOperations returns the extractions, downloads, conversions and
+// other long-running operations in progress, oldest first
+func (a *App) GetActiveOperations() []backend.Operation {
+	return backend.ActiveOperations()
+}
+
+// PrefetchThumbnails warms the thumbnail cache in the background
+func (a *App) PrefetchThumbnails(urls []string) {
+	// Only one prefetch runs at a time
+	ctx, release := a.prefetch.start()
+
+	a.activeOps.Add(1)
+	go func() {
+		defer a.activeOps.Done()
+		defer release()
+		opID := backend.StartOperation(backend.OperationThumbnails, "")
+		defer backend.FinishOperation(opID)
+		backend.PrefetchThumbnails(ctx, urls, func(current, total int) {
+			backend.UpdateOperation(opID, int64(current), int64(total))
+			percent := 0
+			if total > 0 {
+				percent = (current * 100) / total
+			}
+			runtime.EventsEmit(a.ctx, "thumbnail-prefetch-progress", DownloadProgress{
+				Current: current,
+				Total:   total,
+				Percent: percent,
+			})
+		})
+	}()
+}
+
+// StopThumbnailPrefetch cancels the running thumbnail prefetch
+func (a *App) StopThumbnailPrefetch() bool {
+	return a.prefetch.stop()
+}
+
+// GetCachedThumbnailPath returns the local cache path of a thumbnail, or an empty string if not cached
+func (a *App) GetCachedThumbnailPath(url string) string {
+	path := backend.GetCachedThumbnailPath(url)
+	if _, err := os.Stat(path); err != nil {
+		return ""
+	}
+	return path
+}
+
+// PauseDownload stops starting new files while letting in-flight files finish
+func (a *App) PauseDownload() bool {
+	paused := false
+	for _, job := range a.activeJobs() {
+		if job.pause.Pause() {
+			paused = true
+		}
+	}
+	if !paused {
+		return false
+	}
+	runtime.EventsEmit(a.ctx, "download-paused")
+	return true
+}
+
+// ResumeDownload continues a paused download
+func (a *App) ResumeDownload() bool {
+	resumed := false
+	for _, job := range a.activeJobs() {
+		if job.pause.Resume() {
+			resumed = true
+		}
+	}
+	if !resumed {
+		return false
+	}
+	runtime.EventsEmit(a.ctx, "download-resumed")
+	return true
+}
 
 // Database functions
 
@@ -322,6 +1720,145 @@ func (a *App) GetAccountFromDB(id int64) (string, error) {
 	return acc.ResponseJSON, nil
 }
 
+// DiffAccount compares a stored account with a newly extracted response JSON
+func (a *App) DiffAccount(id int64, newResponseJSON string) (*backend.AccountDiff, error) {
+	return backend.DiffAccount(id, newResponseJSON)
+}
+
+// SetAutoDownload enables or disables automatic download of new media on refresh
+func (a *App) SetAutoDownload(id int64, enabled bool) error {
+	return backend.SetAutoDownload(id, enabled)
+}
+
+// SetAccountDownloadDir remembers the folder an account downloads to; "" reverts to the default
+func (a *App) SetAccountDownloadDir(id int64, path string) error {
+	return backend.SetAccountDownloadDir(id, path)
+}
+
+// accountOutputDir returns the stored download folder of an account, falling
+// back to the default download path
+func accountOutputDir(username string) string {
+	if dir := backend.GetAccountDownloadDir(username); dir != "" {
+		return dir
+	}
+	return backend.GetDefaultDownloadPath()
+}
+
+// TouchAccount marks an account as just opened; call it when the user opens an account
+func (a *App) TouchAccount(id int64) error {
+	return backend.TouchAccount(id)
+}
+
+// SearchAccounts returns the saved accounts matching every word of query in
+// their username, name or tweet texts; an empty query returns all accounts
+func (a *App) SearchAccounts(query string) ([]backend.AccountListItem, error) {
+	return backend.SearchAccounts(query)
+}
+
+// GetRecentlyViewed returns the most recently opened accounts, newest first
+func (a *App) GetRecentlyViewed(limit int) ([]backend.AccountListItem, error) {
+	return backend.GetRecentlyViewed(limit)
+}
+
+// SetAccountPinned pins or unpins an account so stale-account purges skip it
+func (a *App) SetAccountPinned(id int64, pinned bool) error {
+	return backend.SetAccountPinned(id, pinned)
+}
+
+// PurgeStaleAccounts lists unpinned accounts not fetched in olderThanDays days,
+// deleting them unless dryRun is set
+func (a *App) PurgeStaleAccounts(olderThanDays int, dryRun bool) ([]backend.AccountListItem, error) {
+	return backend.PurgeStaleAccounts(olderThanDays, dryRun)
+}
+
+// RefreshAccountResponse represents the result of refreshing a saved account
+type RefreshAccountResponse struct {
+	NewEntries int                    `json:"new_entries"`
+	Download   *DownloadMediaResponse `json:"download,omitempty"`
+}
+
+// RefreshAccount re-extracts a saved account and saves the result. With
+// req.Incremental only tweets newer than the stored ones are fetched. When the
+// account has auto-download enabled, newly found media is downloaded to outputDir.
+func (a *App) RefreshAccount(id int64, req TimelineRequest, outputDir string) (RefreshAccountResponse, error) {
+	acc, err := backend.GetAccountByID(id)
+	if err != nil {
+		return RefreshAccountResponse{}, fmt.Errorf("account not found: %v", err)
+	}
+	req.AuthToken = backend.ResolveAuthToken(req.AuthToken)
+	if req.AuthToken == "" {
+		return RefreshAccountResponse{}, fmt.Errorf("auth token is required")
+	}
+
+	req.Username = acc.Username
+	backendReq := req.toBackend()
+	// A refresh is for finding new tweets, so it never reuses a cached result
+	backendReq.NoCache = true
+
+	// Without stored entries there is nothing to stop at, so a full extraction runs
+	if req.Incremental {
+		backendReq.SinceID = backend.MaxStoredTweetID(acc.ResponseJSON)
+	}
+
+	response, err := backend.ExtractTimeline(backendReq)
+	if err != nil {
+		return RefreshAccountResponse{}, fmt.Errorf("failed to extract timeline: %v", err)
+	}
+
+	newEntries := backend.NewTimelineEntries(acc.ResponseJSON, response)
+	if backendReq.SinceID > 0 {
+		response = backend.MergeIncremental(acc.ResponseJSON, response)
+	}
+
+	jsonData, err := json.Marshal(response)
+	if err != nil {
+		return RefreshAccountResponse{}, fmt.Errorf("failed to encode response: %v", err)
+	}
+	if err := backend.SaveAccount(acc.Username, response.AccountInfo.Nick, response.AccountInfo.ProfileImage, response.TotalURLs, string(jsonData)); err != nil {
+		return RefreshAccountResponse{}, fmt.Errorf("failed to save account: %v", err)
+	}
+
+	result := RefreshAccountResponse{NewEntries: len(newEntries)}
+	runtime.EventsEmit(a.ctx, "account-refreshed", map[string]interface{}{
+		"id":          id,
+		"username":    acc.Username,
+		"new_entries": len(newEntries),
+	})
+
+	autoDownload, _ := backend.GetAutoDownload(id)
+	if !autoDownload || len(newEntries) == 0 {
+		return result, nil
+	}
+
+	if outputDir == "" {
+		outputDir = accountOutputDir(acc.Username)
+	}
+
+	items := make([]backend.MediaItem, len(newEntries))
+	for i, entry := range newEntries {
+		items[i] = backend.MediaItem{
+			URL:      entry.URL,
+			Date:     entry.Date,
+			TweetID:  int64(entry.TweetID),
+			Type:     entry.Type,
+			Username: acc.Username,
+			Text:     entry.Text,
+			AltText:  entry.AltText,
+		}
+	}
+
+	download, err := a.runDownload(items, outputDir, acc.Username, backend.DownloadOptions{}, nil)
+	result.Download = &download
+	runtime.EventsEmit(a.ctx, "auto-download-complete", map[string]interface{}{
+		"id":         id,
+		"username":   acc.Username,
+		"downloaded": download.Downloaded,
+		"failed":     download.Failed,
+		"cancelled":  download.Cancelled,
+	})
+	return result, err
+}
+
 // DeleteAccountFromDB deletes an account from database
 func (a *App) DeleteAccountFromDB(id int64) error {
 	return backend.DeleteAccount(id)
@@ -337,11 +1874,66 @@ func (a *App) ExportAccountJSON(id int64, outputDir string) (string, error) {
 	return backend.ExportAccountToFile(id, outputDir)
 }
 
+// ExportAccountCSV exports an account's timeline to a CSV file with the selected columns
+func (a *App) ExportAccountCSV(id int64, outputDir string, fields []string) (string, error) {
+	return backend.ExportAccountCSV(id, outputDir, fields)
+}
+
+// ExportTimelineMetadata extracts a timeline and saves only its metadata as
+// JSON, CSV or both (format), skipping the media download
+func (a *App) ExportTimelineMetadata(req TimelineRequest, outputDir string, format string) (string, error) {
+	if req.Username == "" && req.UserID == "" {
+		return "", fmt.Errorf("username or user ID is required")
+	}
+	req.AuthToken = backend.ResolveAuthToken(req.AuthToken)
+	if req.AuthToken == "" {
+		return "", fmt.Errorf("auth token is required")
+	}
+	if outputDir == "" {
+		outputDir = backend.GetDefaultDownloadPath()
+	}
+
+	return backend.ExportTimelineMetadata(req.toBackend(), outputDir, format)
+}
+
+// ExportLibrary writes all accounts, settings and a database snapshot into one zip
+func (a *App) ExportLibrary(destPath string) (string, error) {
+	if destPath == "" {
+		destPath = backend.GetDefaultDownloadPath()
+	}
+	return backend.ExportLibrary(destPath)
+}
+
+// ImportLibrary restores a library zip; existing accounts and settings are
+// only replaced when overwrite is set
+func (a *App) ImportLibrary(zipPath string, overwrite bool) (*backend.LibraryImportResult, error) {
+	if zipPath == "" {
+		return nil, fmt.Errorf("library archive path is required")
+	}
+	return backend.ImportLibrary(zipPath, overwrite)
+}
+
 // UpdateAccountGroup updates the group for an account
 func (a *App) UpdateAccountGroup(id int64, groupName, groupColor string) error {
 	return backend.UpdateAccountGroup(id, groupName, groupColor)
 }
 
+// RenameGroup renames a group and updates its color for all member accounts
+func (a *App) RenameGroup(oldName, newName, newColor string) error {
+	if oldName == "" || strings.TrimSpace(newName) == "" {
+		return fmt.Errorf("group name is required")
+	}
+	return backend.RenameGroup(oldName, strings.TrimSpace(newName), newColor)
+}
+
+// DeleteGroup ungroups all member accounts of a group
+func (a *App) DeleteGroup(name string) error {
+	if name == "" {
+		return fmt.Errorf("group name is required")
+	}
+	return backend.DeleteGroup(name)
+}
+
 // GetAllGroups returns all unique groups
 func (a *App) GetAllGroups() ([]map[string]string, error) {
 	return backend.GetAllGroups()
@@ -354,9 +1946,64 @@ func (a *App) IsFFmpegInstalled() bool {
 	return backend.IsFFmpegInstalled()
 }
 
-// DownloadFFmpeg downloads ffmpeg binary
+// GetFFmpegInfo returns the installed ffmpeg's version and encoders
+func (a *App) GetFFmpegInfo() (*backend.FFmpegInfo, error) {
+	return backend.GetFFmpegInfo()
+}
+
+// FFmpegDownloadProgress is emitted as "ffmpeg-download-progress" while
+// ffmpeg downloads
+type FFmpegDownloadProgress struct {
+	Downloaded int64 `json:"downloaded"`
+	Total      int64 `json:"total"`   // -1 when the server doesn't send a length
+	Percent    int   `json:"percent"` // 0 when the total is unknown
+}
+
+// DownloadFFmpeg downloads ffmpeg binary, emitting "ffmpeg-download-progress"
+// events. StopFFmpegDownload aborts it.
 func (a *App) DownloadFFmpeg() error {
-	return backend.DownloadFFmpeg(nil)
+	a.activeOps.Add(1)
+	defer a.activeOps.Done()
+
+	// Only one download runs at a time
+	ctx, release := a.ffmpeg.start()
+	defer release()
+
+	opID := backend.StartOperation(backend.OperationFFmpegDownload, backend.GetFFmpegPath())
+	defer backend.FinishOperation(opID)
+
+	// Emit on each percent step (or MiB when the size is unknown) rather than
+	// every chunk
+	lastStep := int64(-1)
+	progressCallback := func(downloaded, total int64) {
+		backend.UpdateOperation(opID, downloaded, max(total, 0))
+		percent := 0
+		step := downloaded >> 20
+		if total > 0 {
+			percent = int(downloaded * 100 / total)
+			step = int64(percent)
+		}
+		if step == lastStep {
+			return
+		}
+		lastStep = step
+		runtime.EventsEmit(a.ctx, "ffmpeg-download-progress", FFmpegDownloadProgress{
+			Downloaded: downloaded,
+			Total:      total,
+			Percent:    percent,
+		})
+	}
+
+	err := backend.DownloadFFmpeg(ctx, progressCallback)
+	if err == context.Canceled {
+		return fmt.Errorf("ffmpeg download cancelled")
+	}
+	return err
+}
+
+// StopFFmpegDownload cancels the running ffmpeg download
+func (a *App) StopFFmpegDownload() bool {
+	return a.ffmpeg.stop()
 }
 
 // ConvertGIFsRequest represents request for converting GIFs
@@ -365,17 +2012,23 @@ type ConvertGIFsRequest struct {
 	FPS            int    `json:"fps"`
 	Width          int    `json:"width"`
 	DeleteOriginal bool   `json:"delete_original"`
+	Workers        int    `json:"workers"` // parallel ffmpeg processes, 0 = number of CPUs
+	Force          bool   `json:"force"`   // re-convert MP4s that already have a GIF
+	Format         string `json:"format"`  // gif (default) or webp
+	Quality        int    `json:"quality"` // WebP quality 0-100, 0 = default
 }
 
 // ConvertGIFsResponse represents response for GIF conversion
 type ConvertGIFsResponse struct {
 	Success   bool   `json:"success"`
 	Converted int    `json:"converted"`
+	Skipped   int    `json:"skipped"` // already converted
 	Failed    int    `json:"failed"`
 	Message   string `json:"message"`
 }
 
-// ConvertGIFs converts MP4 files in gifs folder to actual GIF format
+// ConvertGIFs converts MP4 files in gifs folder to actual GIF format, or to
+// animated WebP when req.Format is "webp"
 func (a *App) ConvertGIFs(req ConvertGIFsRequest) (ConvertGIFsResponse, error) {
 	if !backend.IsFFmpegInstalled() {
 		return ConvertGIFsResponse{
@@ -384,7 +2037,51 @@ func (a *App) ConvertGIFs(req ConvertGIFsRequest) (ConvertGIFsResponse, error) {
 		}, nil
 	}
 
-	converted, failed, err := backend.ConvertGIFsInFolder(req.FolderPath, req.FPS, req.Width, req.DeleteOriginal)
+	if err := backend.ValidateGIFConvertOptions(req.Format, req.Quality); err != nil {
+		return ConvertGIFsResponse{
+			Success: false,
+			Message: err.Error(),
+		}, err
+	}
+
+	a.activeOps.Add(1)
+	defer a.activeOps.Done()
+
+	// Only one conversion runs at a time
+	ctx, release := a.gifConvert.start()
+	defer release()
+
+	opID := backend.StartOperation(backend.OperationGIFConvert, req.FolderPath)
+	defer backend.FinishOperation(opID)
+
+	progressCallback := func(current, total int) {
+		backend.UpdateOperation(opID, int64(current), int64(total))
+		percent := 0
+		if total > 0 {
+			percent = (current * 100) / total
+		}
+		runtime.EventsEmit(a.ctx, "gif-convert-progress", DownloadProgress{
+			Current: current,
+			Total:   total,
+			Percent: percent,
+		})
+	}
+
+	label := "GIFs"
+	if strings.EqualFold(req.Format, backend.GIFFormatWebP) {
+		label = "WebPs"
+	}
+
+	converted, skipped, failed, err := backend.ConvertGIFsInFolderProgress(ctx, req.FolderPath, req.Format, req.FPS, req.Width, req.Quality, req.DeleteOriginal, req.Force, req.Workers, progressCallback)
+	if err == context.Canceled {
+		return ConvertGIFsResponse{
+			Success:   false,
+			Converted: converted,
+			Skipped:   skipped,
+			Failed:    failed,
+			Message:   fmt.Sprintf("Conversion stopped. Converted %d %s, %d failed", converted, label, failed),
+		}, nil
+	}
 	if err != nil {
 		return ConvertGIFsResponse{
 			Success: false,
@@ -395,11 +2092,323 @@ func (a *App) ConvertGIFs(req ConvertGIFsRequest) (ConvertGIFsResponse, error) {
 	return ConvertGIFsResponse{
 		Success:   true,
 		Converted: converted,
+		Skipped:   skipped,
+		Failed:    failed,
+		Message:   fmt.Sprintf("Converted %d %s, %d already converted, %d failed", converted, label, skipped, failed),
+	}, nil
+}
+
+// StopGIFConversion cancels the running GIF conversion and kills its ffmpeg processes
+func (a *App) StopGIFConversion() bool {
+	return a.gifConvert.stop()
+}
+
+// GetFolderSize returns the total size in bytes of the files under path
+func (a *App) GetFolderSize(path string) (int64, error) {
+	if path == "" {
+		return 0, fmt.Errorf("path is required")
+	}
+	ctx := a.startSizeScan()
+	return backend.GetFolderSize(ctx, path)
+}
+
+// GetAccountArchiveSize returns the size in bytes of an account's download
+// folder under baseDir (empty = default download path)
+func (a *App) GetAccountArchiveSize(username, baseDir string) (int64, error) {
+	if username == "" {
+		return 0, fmt.Errorf("username is required")
+	}
+	ctx := a.startSizeScan()
+	return backend.GetAccountArchiveSize(ctx, username, baseDir)
+}
+
+// startSizeScan returns the context shared by running size scans, so several
+// accounts can be measured at once and StopFolderSize cancels them together
+func (a *App) startSizeScan() context.Context {
+	a.sizeMu.Lock()
+	defer a.sizeMu.Unlock()
+	if a.sizeCtx == nil {
+		a.sizeCtx, a.sizeCancel = context.WithCancel(context.Background())
+	}
+	return a.sizeCtx
+}
+
+// StopFolderSize cancels every running folder size scan
+func (a *App) StopFolderSize() bool {
+	a.sizeMu.Lock()
+	defer a.sizeMu.Unlock()
+	if a.sizeCancel == nil {
+		return false
+	}
+	a.sizeCancel()
+	a.sizeCtx, a.sizeCancel = nil, nil
+	return true
+}
+
+// ExtractAudioRequest represents a request to extract audio from downloaded videos
+type ExtractAudioRequest struct {
+	FolderPath string `json:"folder_path"`
+	Format     string `json:"format"` // mp3, aac or m4a
+}
+
+// ExtractAudioResponse represents the result of an audio extraction
+type ExtractAudioResponse struct {
+	Success   bool   `json:"success"`
+	Converted int    `json:"converted"`
+	Skipped   int    `json:"skipped"` // videos without an audio stream
+	Failed    int    `json:"failed"`
+	Message   string `json:"message"`
+}
+
+// ExtractAudio extracts the audio track of the videos in a download folder,
+// emitting audio-extract-progress. StopAudioExtraction stops it; a GIF
+// conversion running alongside is left alone.
+func (a *App) ExtractAudio(req ExtractAudioRequest) (ExtractAudioResponse, error) {
+	if !backend.IsFFmpegInstalled() {
+		return ExtractAudioResponse{
+			Success: false,
+			Message: "FFmpeg not installed. Please download it first.",
+		}, nil
+	}
+
+	a.activeOps.Add(1)
+	defer a.activeOps.Done()
+
+	// Only one audio extraction runs at a time
+	ctx, release := a.audioExtract.start()
+	defer release()
+
+	opID := backend.StartOperation(backend.OperationAudioExtract, req.FolderPath)
+	defer backend.FinishOperation(opID)
+
+	progressCallback := func(current, total int) {
+		backend.UpdateOperation(opID, int64(current), int64(total))
+		percent := 0
+		if total > 0 {
+			percent = (current * 100) / total
+		}
+		runtime.EventsEmit(a.ctx, "audio-extract-progress", DownloadProgress{
+			Current: current,
+			Total:   total,
+			Percent: percent,
+		})
+	}
+
+	converted, skipped, failed, err := backend.ExtractAudioProgress(ctx, req.FolderPath, req.Format, progressCallback)
+	if err == context.Canceled {
+		return ExtractAudioResponse{
+			Success:   false,
+			Converted: converted,
+			Skipped:   skipped,
+			Failed:    failed,
+			Message:   fmt.Sprintf("Extraction stopped. Extracted %d audio files, %d failed", converted, failed),
+		}, nil
+	}
+	if err != nil {
+		return ExtractAudioResponse{
+			Success: false,
+			Message: err.Error(),
+		}, err
+	}
+
+	return ExtractAudioResponse{
+		Success:   true,
+		Converted: converted,
+		Skipped:   skipped,
 		Failed:    failed,
-		Message:   fmt.Sprintf("Converted %d GIFs, %d failed", converted, failed),
+		Message:   fmt.Sprintf("Extracted %d audio files, %d without audio, %d failed", converted, skipped, failed),
 	}, nil
 }
 
+// StopAudioExtraction cancels the running audio extraction and kills its ffmpeg processes
+func (a *App) StopAudioExtraction() bool {
+	return a.audioExtract.stop()
+}
+
+// TranscodeVideosRequest represents a request to re-encode downloaded videos
+type TranscodeVideosRequest struct {
+	FolderPath     string `json:"folder_path"`
+	Codec          string `json:"codec"` // h264, vp9 or webm
+	CRF            int    `json:"crf"`   // quality, lower is better, 0 = codec default
+	DeleteOriginal bool   `json:"delete_original"`
+}
+
+// TranscodeVideosResponse represents the result of a video transcode
+type TranscodeVideosResponse struct {
+	Success   bool   `json:"success"`
+	Converted int    `json:"converted"`
+	Failed    int    `json:"failed"`
+	Message   string `json:"message"`
+}
+
+// TranscodeVideos re-encodes the videos in a download folder, emitting
+// transcode-progress. StopTranscode stops it; GIF conversions and audio
+// extractions running alongside are left alone.
+func (a *App) TranscodeVideos(req TranscodeVideosRequest) (TranscodeVideosResponse, error) {
+	if !backend.IsFFmpegInstalled() {
+		return TranscodeVideosResponse{
+			Success: false,
+			Message: "FFmpeg not installed. Please download it first.",
+		}, nil
+	}
+	if err := backend.ValidateTranscodeOptions(req.Codec, req.CRF); err != nil {
+		return TranscodeVideosResponse{
+			Success: false,
+			Message: err.Error(),
+		}, err
+	}
+
+	a.activeOps.Add(1)
+	defer a.activeOps.Done()
+
+	// Only one transcode runs at a time
+	ctx, release := a.transcode.start()
+	defer release()
+
+	opID := backend.StartOperation(backend.OperationTranscode, req.FolderPath)
+	defer backend.FinishOperation(opID)
+
+	progressCallback := func(current, total int) {
+		backend.UpdateOperation(opID, int64(current), int64(total))
+		percent := 0
+		if total > 0 {
+			percent = (current * 100) / total
+		}
+		runtime.EventsEmit(a.ctx, "transcode-progress", DownloadProgress{
+			Current: current,
+			Total:   total,
+			Percent: percent,
+		})
+	}
+
+	converted, failed, err := backend.TranscodeVideosProgress(ctx, req.FolderPath, req.Codec, req.CRF, req.DeleteOriginal, progressCallback)
+	if err == context.Canceled {
+		return TranscodeVideosResponse{
+			Success:   false,
+			Converted: converted,
+			Failed:    failed,
+			Message:   fmt.Sprintf("Transcode stopped. Converted %d videos, %d failed", converted, failed),
+		}, nil
+	}
+	if err != nil {
+		return TranscodeVideosResponse{
+			Success: false,
+			Message: err.Error(),
+		}, err
+	}
+
+	return TranscodeVideosResponse{
+		Success:   true,
+		Converted: converted,
+		Failed:    failed,
+		Message:   fmt.Sprintf("Converted %d videos, %d failed", converted, failed),
+	}, nil
+}
+
+// StopTranscode cancels the running transcode and kills its ffmpeg processes
+func (a *App) StopTranscode() bool {
+	return a.transcode.stop()
+}
+
+// CheckDatabaseIntegrity runs an integrity check on the database file
+func (a *App) CheckDatabaseIntegrity() (*backend.DatabaseIntegrity, error) {
+	return backend.CheckDatabaseIntegrity()
+}
+
+// FindCorruptAccounts lists saved accounts whose stored timeline is empty or
+// broken, without changing anything
+func (a *App) FindCorruptAccounts() ([]backend.CorruptAccount, error) {
+	return backend.FindCorruptAccounts()
+}
+
+// RepairDatabase recovers a corrupt database, falling back to the newest
+// automatic backup, and reports the action taken
+func (a *App) RepairDatabase() (*backend.DatabaseRepairResult, error) {
+	result, err := backend.RepairDatabase()
+	if err != nil {
+		return nil, err
+	}
+	if result.Action != backend.RepairNone {
+		backend.LoadTransportConfig()
+		if !backend.IsSearchIndexDone() {
+			a.activeOps.Add(1)
+			go func() {
+				defer a.activeOps.Done()
+				backend.BackfillSearchIndex(nil)
+			}()
+		}
+	}
+	return result, nil
+}
+
+// GetExtractionDefaults returns the saved defaults applied to timeline requests
+func (a *App) GetExtractionDefaults() (backend.ExtractionDefaults, error) {
+	return backend.GetExtractionDefaults()
+}
+
+// SetExtractionDefaults saves the defaults used when a timeline request leaves
+// timeline type, media type, batch size or retweets unset
+func (a *App) SetExtractionDefaults(defaults backend.ExtractionDefaults) error {
+	return backend.SetExtractionDefaults(defaults)
+}
+
+// GetExtractCacheTTL returns how long extraction results are reused, in seconds (0 = off)
+func (a *App) GetExtractCacheTTL() int {
+	return int(backend.GetExtractCacheTTL() / time.Second)
+}
+
+// SetExtractCacheTTL sets how long extraction results are reused, in seconds (0 = off)
+func (a *App) SetExtractCacheTTL(seconds int) error {
+	return backend.SetExtractCacheTTL(time.Duration(seconds) * time.Second)
+}
+
+// ClearExtractCache deletes every cached extraction result
+func (a *App) ClearExtractCache() (int64, error) {
+	return backend.ClearExtractCache()
+}
+
+// GetAuthTokenFile returns the configured auth token file path
+func (a *App) GetAuthTokenFile() (string, error) {
+	return backend.GetAuthTokenFile()
+}
+
+// SetAuthTokenFile sets a file to read the auth token from when a request has none
+func (a *App) SetAuthTokenFile(path string) error {
+	if path != "" {
+		if _, err := os.Stat(path); err != nil {
+			return fmt.Errorf("auth token file not found: %s", path)
+		}
+	}
+	return backend.SetAuthTokenFile(path)
+}
+
+// BackfillMediaTable populates the media table from stored accounts, emitting
+// media-backfill-progress per account. Safe to run more than once.
+func (a *App) BackfillMediaTable() (int, error) {
+	return backend.BackfillMediaTable(func(current, total int) {
+		percent := 0
+		if total > 0 {
+			percent = (current * 100) / total
+		}
+		runtime.EventsEmit(a.ctx, "media-backfill-progress", DownloadProgress{
+			Current: current,
+			Total:   total,
+			Percent: percent,
+		})
+	})
+}
+
+// GetTypeCountsForAccounts returns media counts per type for each account, for list badges
+func (a *App) GetTypeCountsForAccounts(ids []int64) (map[int64]map[string]int, error) {
+	return backend.GetTypeCountsForAccounts(ids)
+}
+
+// SortTimelineByEngagement returns an account's media sorted by likes, retweets,
+// replies or total engagement, most popular first, dropping entries below minCount
+func (a *App) SortTimelineByEngagement(id int64, metric string, minCount int) ([]backend.TimelineEntry, error) {
+	return backend.GetMediaByEngagement(id, metric, minCount)
+}
+
 // ImportAccountResponse represents the response for import operation
 type ImportAccountResponse struct {
 	Success  bool   `json:"success"`
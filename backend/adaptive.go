@@ -0,0 +1,89 @@
+package backend
+
+import (
+	"context"
+	"time"
+)
+
+const (
+	// MinAdaptiveBatchSize and MaxAdaptiveBatchSize bound the adaptive page size
+	MinAdaptiveBatchSize = 20
+	MaxAdaptiveBatchSize = 200
+	// initialAdaptiveBatchSize is the page size an adaptive extraction starts with
+	initialAdaptiveBatchSize = 50
+	// adaptiveGrowAfter is how many successful pages in a row grow the page size
+	adaptiveGrowAfter = 2
+	// maxRateLimitBackoffs is how many rate limits in a row abort the extraction
+	maxRateLimitBackoffs = 5
+)
+
+// adaptiveBatchSize grows the page size while requests succeed and halves it
+// when the extractor is rate limited
+type adaptiveBatchSize struct {
+	size      int
+	successes int
+	backoffs  int
+	locked    bool
+}
+
+// newAdaptiveBatchSize starts at the initial size, clamped to the request's
+// batch size when that is smaller
+func newAdaptiveBatchSize(requested int) *adaptiveBatchSize {
+	size := initialAdaptiveBatchSize
+	if requested > 0 && requested < size {
+		size = clampBatchSize(requested)
+	}
+	return &adaptiveBatchSize{size: size}
+}
+
+// clampBatchSize keeps a size within the adaptive bounds
+func clampBatchSize(size int) int {
+	if size < MinAdaptiveBatchSize {
+		return MinAdaptiveBatchSize
+	}
+	if size > MaxAdaptiveBatchSize {
+		return MaxAdaptiveBatchSize
+	}
+	return size
+}
+
+// lock stops further adaptation. Page-number paging offsets by page*size, so
+// the size must stay fixed once the extractor stops returning cursors.
+func (a *adaptiveBatchSize) lock() {
+	a.locked = true
+}
+
+// success records a successful page and grows the size every few successes
+func (a *adaptiveBatchSize) success() {
+	a.backoffs = 0
+	if a.locked {
+		return
+	}
+	a.successes++
+	if a.successes >= adaptiveGrowAfter {
+		a.successes = 0
+		a.size = clampBatchSize(a.size + a.size/2)
+	}
+}
+
+// rateLimited halves the size and reports whether another attempt should be made
+func (a *adaptiveBatchSize) rateLimited() bool {
+	a.successes = 0
+	a.backoffs++
+	if !a.locked {
+		a.size = clampBatchSize(a.size / 2)
+	}
+	return a.backoffs <= maxRateLimitBackoffs
+}
+
+// sleepContext waits for d or until the context is cancelled
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,142 @@
+package backend
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// archiveIndexFilename is the append-only index kept in each account folder
+const archiveIndexFilename = "archive-index.jsonl"
+
+// ArchiveIndexEntry represents one line of the archive index
+type ArchiveIndexEntry struct {
+	TweetID      int64  `json:"tweet_id"`
+	URL          string `json:"url"`
+	Type         string `json:"type"`
+	Date         string `json:"date"`
+	File         string `json:"file"`          // path relative to the account folder
+	DownloadedAt string `json:"downloaded_at"` // RFC3339 in UTC
+	Size         int64  `json:"size,omitempty"`
+	SHA256       string `json:"sha256,omitempty"` // hex, checked by VerifyArchive
+}
+
+// archiveIndexKey identifies an entry for deduplication
+func archiveIndexKey(tweetID int64, url string) string {
+	return fmt.Sprintf("%d|%s", tweetID, url)
+}
+
+// readArchiveIndex reads every valid line of an archive index, skipping
+// partial lines left by an interrupted write
+func readArchiveIndex(path string) ([]ArchiveIndexEntry, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []ArchiveIndexEntry{}, nil
+		}
+		return nil, err
+	}
+	defer file.Close()
+
+	entries := []ArchiveIndexEntry{}
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var entry ArchiveIndexEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			continue
+		}
+		entries = append(entries, entry)
+	}
+
+	return entries, scanner.Err()
+}
+
+// appendArchiveIndex appends the entries that are not already in the account's
+// index, never rewriting existing lines
+func appendArchiveIndex(accountDir string, entries []ArchiveIndexEntry) error {
+	path := filepath.Join(accountDir, archiveIndexFilename)
+
+	existing, err := readArchiveIndex(path)
+	if err != nil {
+		return fmt.Errorf("failed to read archive index: %v", err)
+	}
+	seen := make(map[string]bool, len(existing))
+	for _, entry := range existing {
+		seen[archiveIndexKey(entry.TweetID, entry.URL)] = true
+	}
+
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open archive index: %v", err)
+	}
+	defer file.Close()
+
+	writer := bufio.NewWriter(file)
+	now := time.Now().UTC().Format(time.RFC3339)
+	for _, entry := range entries {
+		key := archiveIndexKey(entry.TweetID, entry.URL)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+
+		if entry.DownloadedAt == "" {
+			entry.DownloadedAt = now
+		}
+		line, err := json.Marshal(entry)
+		if err != nil {
+			continue
+		}
+		writer.Write(line)
+		writer.WriteByte('\n')
+	}
+
+	if err := writer.Flush(); err != nil {
+		return fmt.Errorf("failed to write archive index: %v", err)
+	}
+	return file.Sync()
+}
+
+// writeArchiveIndex replaces an archive index with entries, writing a temporary
+// file first so an interrupted rewrite leaves the old index intact
+func writeArchiveIndex(path string, entries []ArchiveIndexEntry) error {
+	tempPath := path + partialDownloadSuffix
+	file, err := os.Create(tempPath)
+	if err != nil {
+		return fmt.Errorf("failed to write archive index: %v", err)
+	}
+
+	writer := bufio.NewWriter(file)
+	for _, entry := range entries {
+		line, err := json.Marshal(entry)
+		if err != nil {
+			continue
+		}
+		writer.Write(line)
+		writer.WriteByte('\n')
+	}
+	if err := writer.Flush(); err != nil {
+		file.Close()
+		os.Remove(tempPath)
+		return fmt.Errorf("failed to write archive index: %v", err)
+	}
+	if err := file.Close(); err != nil {
+		os.Remove(tempPath)
+		return fmt.Errorf("failed to write archive index: %v", err)
+	}
+	return os.Rename(tempPath, path)
+}
+
+// GetArchiveIndex reads the archive index of an account download folder
+func GetArchiveIndex(username, baseDir string) ([]ArchiveIndexEntry, error) {
+	path := filepath.Join(baseDir, SanitizeFilename(username), archiveIndexFilename)
+	entries, err := readArchiveIndex(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read archive index: %v", err)
+	}
+	return entries, nil
+}
@@ -0,0 +1,130 @@
+package backend
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// audioCodecArgs are the ffmpeg codec arguments per supported audio format.
+// Twitter videos carry AAC audio, so aac and m4a copy the stream as is.
+var audioCodecArgs = map[string][]string{
+	"mp3": {"-c:a", "libmp3lame", "-q:a", "2"},
+	"aac": {"-c:a", "copy"},
+	"m4a": {"-c:a", "copy"},
+}
+
+// ValidateAudioFormat checks that format is mp3, aac or m4a
+func ValidateAudioFormat(format string) error {
+	if _, ok := audioCodecArgs[format]; !ok {
+		return fmt.Errorf("unsupported audio format: %s (use mp3, aac or m4a)", format)
+	}
+	return nil
+}
+
+// hasAudioStream reports whether ffmpeg lists an audio stream in the file.
+// ffmpeg exits with an error when given no output, so only its log is used.
+func hasAudioStream(ctx context.Context, inputPath string) (bool, error) {
+	cmd := exec.CommandContext(ctx, GetFFmpegPath(), "-hide_banner", "-i", inputPath)
+	hideWindow(cmd) // Hide console window on Windows
+	output, _ := cmd.CombinedOutput()
+	if ctx.Err() != nil {
+		return false, ctx.Err()
+	}
+	return strings.Contains(string(output), "Audio:"), nil
+}
+
+// extractAudioFile writes the audio track of inputPath to outputPath in format
+func extractAudioFile(ctx context.Context, inputPath, outputPath, format string) error {
+	args := append([]string{"-i", inputPath, "-vn"}, audioCodecArgs[format]...)
+	args = append(args, "-y", outputPath)
+
+	cmd := exec.CommandContext(ctx, GetFFmpegPath(), args...)
+	hideWindow(cmd) // Hide console window on Windows
+	output, err := cmd.CombinedOutput()
+	if ctx.Err() != nil {
+		os.Remove(outputPath)
+		return ctx.Err()
+	}
+	if err != nil {
+		os.Remove(outputPath)
+		return fmt.Errorf("ffmpeg error: %v, output: %s", err, string(output))
+	}
+	return nil
+}
+
+// ExtractAudio extracts the audio of every MP4 in the videos folder into an
+// audio folder next to it
+func ExtractAudio(folderPath string, format string) (converted int, skipped int, failed int, err error) {
+	return ExtractAudioProgress(context.Background(), folderPath, format, nil)
+}
+
+// ExtractAudioProgress extracts the audio of every MP4 in the videos folder
+// as mp3, aac or m4a. Videos without an audio stream are skipped. Cancelling
+// ctx kills the running ffmpeg and stops before the next file.
+func ExtractAudioProgress(ctx context.Context, folderPath string, format string, progress ProgressCallback) (converted int, skipped int, failed int, err error) {
+	if !IsFFmpegInstalled() {
+		return 0, 0, 0, fmt.Errorf("ffmpeg not installed")
+	}
+	format = strings.ToLower(format)
+	if err := ValidateAudioFormat(format); err != nil {
+		return 0, 0, 0, err
+	}
+
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	cleanPath := filepath.Clean(folderPath)
+	videosFolder := filepath.Join(cleanPath, "videos")
+	files, err := os.ReadDir(videosFolder)
+	if err != nil {
+		return 0, 0, 0, fmt.Errorf("failed to read videos folder: %v", err)
+	}
+
+	inputs := []string{}
+	for _, file := range files {
+		if !file.IsDir() && strings.HasSuffix(strings.ToLower(file.Name()), ".mp4") {
+			inputs = append(inputs, filepath.Join(videosFolder, file.Name()))
+		}
+	}
+
+	audioFolder := filepath.Join(cleanPath, "audio")
+	if len(inputs) > 0 {
+		if err := os.MkdirAll(audioFolder, 0755); err != nil {
+			return 0, 0, 0, fmt.Errorf("failed to create audio folder: %v", err)
+		}
+	}
+
+	for i, inputPath := range inputs {
+		if ctx.Err() != nil {
+			break
+		}
+
+		hasAudio, err := hasAudioStream(ctx, inputPath)
+		switch {
+		case err != nil:
+			// Cancelled; the loop stops at the next check
+		case !hasAudio:
+			skipped++
+		default:
+			name := strings.TrimSuffix(filepath.Base(inputPath), filepath.Ext(inputPath)) + "." + format
+			if err := extractAudioFile(ctx, inputPath, filepath.Join(audioFolder, name), format); err != nil {
+				if ctx.Err() == nil {
+					failed++
+				}
+			} else {
+				converted++
+			}
+		}
+
+		if progress != nil {
+			progress(i+1, len(inputs))
+		}
+	}
+
+	return converted, skipped, failed, ctx.Err()
+}
@@ -0,0 +1,42 @@
+package backend
+
+import (
+	"os"
+	"strings"
+)
+
+// AuthTokenEnvVar is the environment variable read when a request has no auth token
+const AuthTokenEnvVar = "TWITTER_AUTH_TOKEN"
+
+// GetAuthTokenFile returns the path of the auth token file, or an empty string if unset
+func GetAuthTokenFile() (string, error) {
+	return GetSetting(SettingAuthTokenFile)
+}
+
+// SetAuthTokenFile sets the path of a file holding the auth token; an empty path clears it
+func SetAuthTokenFile(path string) error {
+	return SetSetting(SettingAuthTokenFile, strings.TrimSpace(path))
+}
+
+// ResolveAuthToken returns the token to use for a request. Precedence is the
+// explicit token, then the TWITTER_AUTH_TOKEN environment variable, then the
+// contents of the auth token file from settings.
+func ResolveAuthToken(token string) string {
+	if token = strings.TrimSpace(token); token != "" {
+		return token
+	}
+
+	if token = strings.TrimSpace(os.Getenv(AuthTokenEnvVar)); token != "" {
+		return token
+	}
+
+	path, err := GetAuthTokenFile()
+	if err != nil || path == "" {
+		return ""
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
@@ -0,0 +1,270 @@
+package backend
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// libraryFormatVersion is bumped when the library archive layout changes
+const libraryFormatVersion = 1
+
+// Paths inside a library archive
+const (
+	libraryManifestName = "manifest.json"
+	librarySettingsName = "settings.json"
+	libraryDBName       = "database/accounts.db"
+	libraryAccountsDir  = "accounts/"
+)
+
+// LibraryManifest describes the contents of a library archive
+type LibraryManifest struct {
+	FormatVersion int               `json:"format_version"`
+	CreatedAt     string            `json:"created_at"` // RFC3339 in UTC
+	Accounts      []AccountListItem `json:"accounts"`
+}
+
+// LibraryImportResult reports what an import restored
+type LibraryImportResult struct {
+	Imported []string `json:"imported"`
+	Skipped  []string `json:"skipped"` // already present and not overwritten
+	Errors   []string `json:"errors"`
+	Settings int      `json:"settings"`
+}
+
+// getAllSettings returns every stored setting
+func getAllSettings() (map[string]string, error) {
+	rows, err := db.Query("SELECT key, value FROM settings")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	settings := make(map[string]string)
+	for rows.Next() {
+		var key, value string
+		if err := rows.Scan(&key, &value); err == nil {
+			settings[key] = value
+		}
+	}
+	return settings, nil
+}
+
+// writeZipFile adds a file with the given content to a zip archive
+func writeZipFile(archive *zip.Writer, name string, data []byte) error {
+	writer, err := archive.Create(name)
+	if err != nil {
+		return err
+	}
+	_, err = writer.Write(data)
+	return err
+}
+
+// ExportLibrary writes every account JSON, the settings, a consistent
+// snapshot of the database and a manifest into one zip. If destPath is a
+// directory a timestamped file name is used. Returns the archive path.
+func ExportLibrary(destPath string) (string, error) {
+	if db == nil {
+		if err := InitDB(); err != nil {
+			return "", err
+		}
+	}
+
+	if info, err := os.Stat(destPath); err == nil && info.IsDir() {
+		destPath = filepath.Join(destPath, fmt.Sprintf("twitterxmediabatchdownloader_library_%s.zip", time.Now().UTC().Format(DefaultDateOutputFormat)))
+	}
+
+	accounts, err := GetAllAccounts()
+	if err != nil {
+		return "", fmt.Errorf("failed to list accounts: %v", err)
+	}
+	if accounts == nil {
+		accounts = []AccountListItem{}
+	}
+
+	settings, err := getAllSettings()
+	if err != nil {
+		return "", fmt.Errorf("failed to read settings: %v", err)
+	}
+
+	// VACUUM INTO gives a consistent copy even while WAL has uncommitted pages
+	snapshotPath := filepath.Join(os.TempDir(), fmt.Sprintf("library-snapshot-%d.db", time.Now().UnixNano()))
+	if _, err := db.Exec("VACUUM INTO ?", snapshotPath); err != nil {
+		return "", fmt.Errorf("failed to snapshot database: %v", err)
+	}
+	defer os.Remove(snapshotPath)
+
+	file, err := os.Create(destPath)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	archive := zip.NewWriter(file)
+
+	manifest, _ := json.MarshalIndent(LibraryManifest{
+		FormatVersion: libraryFormatVersion,
+		CreatedAt:     time.Now().UTC().Format(time.RFC3339),
+		Accounts:      accounts,
+	}, "", "  ")
+	if err := writeZipFile(archive, libraryManifestName, manifest); err != nil {
+		return "", err
+	}
+
+	settingsJSON, _ := json.MarshalIndent(settings, "", "  ")
+	if err := writeZipFile(archive, librarySettingsName, settingsJSON); err != nil {
+		return "", err
+	}
+
+	snapshot, err := os.ReadFile(snapshotPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to read database snapshot: %v", err)
+	}
+	if err := writeZipFile(archive, libraryDBName, snapshot); err != nil {
+		return "", err
+	}
+
+	for _, item := range accounts {
+		acc, err := GetAccountByID(item.ID)
+		if err != nil {
+			continue
+		}
+		name := libraryAccountsDir + SanitizeFilename(acc.Username+".json")
+		if err := writeZipFile(archive, name, []byte(acc.ResponseJSON)); err != nil {
+			return "", err
+		}
+	}
+
+	if err := archive.Close(); err != nil {
+		return "", fmt.Errorf("failed to write archive: %v", err)
+	}
+
+	return destPath, nil
+}
+
+// readZipFile reads a whole file from a zip archive
+func readZipFile(f *zip.File) ([]byte, error) {
+	reader, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	return io.ReadAll(reader)
+}
+
+// ImportLibrary restores accounts, groups and settings from a library archive.
+// Accounts and settings that already exist are kept unless overwrite is set.
+// The database snapshot is not swapped in while the app is running; it is in
+// the archive for manual recovery.
+func ImportLibrary(zipPath string, overwrite bool) (*LibraryImportResult, error) {
+	if db == nil {
+		if err := InitDB(); err != nil {
+			return nil, err
+		}
+	}
+
+	archive, err := zip.OpenReader(zipPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open library archive: %v", err)
+	}
+	defer archive.Close()
+
+	var manifest LibraryManifest
+	accountFiles := make(map[string]*zip.File)
+	var settingsFile *zip.File
+	for _, f := range archive.File {
+		switch {
+		case f.Name == libraryManifestName:
+			data, err := readZipFile(f)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read manifest: %v", err)
+			}
+			if err := json.Unmarshal(data, &manifest); err != nil {
+				return nil, fmt.Errorf("invalid manifest: %v", err)
+			}
+		case f.Name == librarySettingsName:
+			settingsFile = f
+		case strings.HasPrefix(f.Name, libraryAccountsDir) && strings.HasSuffix(f.Name, ".json"):
+			accountFiles[f.Name] = f
+		}
+	}
+
+	if manifest.FormatVersion == 0 {
+		return nil, fmt.Errorf("not a library archive: missing manifest")
+	}
+	if manifest.FormatVersion > libraryFormatVersion {
+		return nil, fmt.Errorf("library archive version %d is newer than supported version %d", manifest.FormatVersion, libraryFormatVersion)
+	}
+
+	result := &LibraryImportResult{
+		Imported: []string{},
+		Skipped:  []string{},
+		Errors:   []string{},
+	}
+
+	for _, item := range manifest.Accounts {
+		if !overwrite {
+			if _, err := GetAccountByUsername(item.Username); err == nil {
+				result.Skipped = append(result.Skipped, item.Username)
+				continue
+			}
+		}
+
+		f, ok := accountFiles[libraryAccountsDir+SanitizeFilename(item.Username+".json")]
+		if !ok {
+			result.Errors = append(result.Errors, fmt.Sprintf("%s: missing account data", item.Username))
+			continue
+		}
+		data, err := readZipFile(f)
+		if err != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("%s: %v", item.Username, err))
+			continue
+		}
+
+		if err := SaveAccount(item.Username, item.Name, item.ProfileImage, item.TotalMedia, string(data)); err != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("%s: %v", item.Username, err))
+			continue
+		}
+
+		// Restore group membership, auto-download, pinning and download folder
+		if acc, err := GetAccountByUsername(item.Username); err == nil {
+			UpdateAccountGroup(acc.ID, item.GroupName, item.GroupColor)
+			SetAutoDownload(acc.ID, item.AutoDownload)
+			SetAccountPinned(acc.ID, item.Pinned)
+			SetAccountDownloadDir(acc.ID, item.DownloadDir)
+		}
+		result.Imported = append(result.Imported, item.Username)
+	}
+
+	if settingsFile != nil {
+		data, err := readZipFile(settingsFile)
+		var settings map[string]string
+		if err == nil {
+			err = json.Unmarshal(data, &settings)
+		}
+		if err != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("settings: %v", err))
+		}
+		for key, value := range settings {
+			// Machine-local state is not carried over
+			if key == SettingMediaBackfilled || key == SettingSearchIndexed {
+				continue
+			}
+			if !overwrite {
+				if existing, err := GetSetting(key); err == nil && existing != "" {
+					continue
+				}
+			}
+			if err := SetSetting(key, value); err == nil {
+				result.Settings++
+			}
+		}
+	}
+
+	return result, nil
+}
@@ -0,0 +1,261 @@
+package backend
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultBatchConcurrency is how many extractor processes a batch runs at once
+	DefaultBatchConcurrency = 3
+	// rateLimitCooldown is how long every worker waits after a rate limit
+	// that came without a Retry-After
+	rateLimitCooldown = 30 * time.Second
+)
+
+// TimelineBatchOptions holds options for extracting several accounts
+type TimelineBatchOptions struct {
+	Concurrency int      // 0 = DefaultBatchConcurrency
+	AuthTokens  []string // rotated between accounts; empty = the request's token
+	OnStart     func(username string)
+	OnFinish    func(result TimelineBatchResult)
+}
+
+// TimelineBatchResult represents the extraction result of one account in a batch
+type TimelineBatchResult struct {
+	Username string           `json:"username"`
+	Response *TwitterResponse `json:"response,omitempty"`
+	Error    string           `json:"error,omitempty"`
+}
+
+// rateLimitGate makes every batch worker back off after any of them is rate limited
+type rateLimitGate struct {
+	mu    sync.Mutex
+	until time.Time
+}
+
+// wait blocks until the cooldown has passed or ctx is done
+func (g *rateLimitGate) wait(ctx context.Context) error {
+	g.mu.Lock()
+	delay := time.Until(g.until)
+	g.mu.Unlock()
+	if delay <= 0 {
+		return nil
+	}
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(delay):
+		return nil
+	}
+}
+
+// trip starts a cooldown for all workers, as long as err asks for
+func (g *rateLimitGate) trip(err error) {
+	g.mu.Lock()
+	if until := time.Now().Add(rateLimitWait(err)); until.After(g.until) {
+		g.until = until
+	}
+	g.mu.Unlock()
+}
+
+// rateLimitWait is how long to back off after a rate limit: the extractor's
+// Retry-After, capped at DefaultMaxRetryAfter, or rateLimitCooldown without one
+func rateLimitWait(err error) time.Duration {
+	var extractorErr *ExtractorError
+	if errors.As(err, &extractorErr) && extractorErr.HasRetryAfter {
+		return capRetryAfter(extractorErr.RetryAfter, DefaultMaxRetryAfter)
+	}
+	return rateLimitCooldown
+}
+
+// isRateLimitError reports whether the extractor failed because of rate limiting
+func isRateLimitError(err error) bool {
+	if errors.Is(err, ErrExtractorRateLimited) {
+		return true
+	}
+	msg := strings.ToLower(err.Error())
+	return strings.Contains(msg, "429") || strings.Contains(msg, "rate limit") || strings.Contains(msg, "too many requests")
+}
+
+// ExtractTimelineBatch extracts the timelines of several accounts with at most
+// Concurrency extractor processes at once. Tokens are rotated per account, and
+// a rate limit on any account pauses all workers before retrying once with the
+// next token. Results are returned in input order.
+func ExtractTimelineBatch(ctx context.Context, usernames []string, req TimelineRequest, opts TimelineBatchOptions) []TimelineBatchResult {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	concurrency := opts.Concurrency
+	if concurrency <= 0 {
+		concurrency = DefaultBatchConcurrency
+	}
+
+	tokens := opts.AuthTokens
+	if len(tokens) == 0 {
+		tokens = []string{req.AuthToken}
+	}
+
+	results := make([]TimelineBatchResult, len(usernames))
+	gate := &rateLimitGate{}
+	semaphore := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+
+	for i, username := range usernames {
+		results[i].Username = username
+
+		select {
+		case <-ctx.Done():
+			results[i].Error = ctx.Err().Error()
+			continue
+		case semaphore <- struct{}{}:
+		}
+
+		wg.Add(1)
+		go func(i int, username string) {
+			defer wg.Done()
+			defer func() { <-semaphore }()
+
+			if opts.OnStart != nil {
+				opts.OnStart(username)
+			}
+
+			accountReq := req
+			accountReq.Username = username
+
+			var response *TwitterResponse
+			var err error
+			for attempt := 0; attempt < 2; attempt++ {
+				if err = gate.wait(ctx); err != nil {
+					break
+				}
+				accountReq.AuthToken = tokens[(i+attempt)%len(tokens)]
+				response, err = ExtractTimeline(accountReq)
+				if err == nil || !isRateLimitError(err) {
+					break
+				}
+				gate.trip(err)
+			}
+
+			if err != nil {
+				results[i].Error = err.Error()
+			} else {
+				results[i].Response = response
+			}
+
+			if opts.OnFinish != nil {
+				opts.OnFinish(results[i])
+			}
+		}(i, username)
+	}
+
+	wg.Wait()
+	return results
+}
+
+// ExtractSaveFailure is an account that could not be extracted or saved
+type ExtractSaveFailure struct {
+	Username string `json:"username"`
+	Error    string `json:"error"`
+}
+
+// ExtractSaveSummary is the outcome of ExtractAndSaveMany
+type ExtractSaveSummary struct {
+	Succeeded []string             `json:"succeeded"`
+	Failed    []ExtractSaveFailure `json:"failed"`
+	Cancelled bool                 `json:"cancelled,omitempty"`
+}
+
+// ExtractAndSaveMany extracts every page of each account one at a time and
+// saves it to the database, continuing past failures. A rate limit pauses for
+// the cooldown and retries the account once. onFinish is called per account
+// with the entries saved or the error; on cancellation the remaining accounts
+// are not attempted.
+func ExtractAndSaveMany(ctx context.Context, usernames []string, req TimelineRequest, onFinish func(index int, username string, entries int, err error)) ExtractSaveSummary {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	summary := ExtractSaveSummary{
+		Succeeded: []string{},
+		Failed:    []ExtractSaveFailure{},
+	}
+
+	for i, username := range usernames {
+		if ctx.Err() != nil {
+			summary.Cancelled = true
+			break
+		}
+
+		accountReq := req
+		accountReq.Username = username
+
+		var response *TwitterResponse
+		var err error
+		for attempt := 0; attempt < 2; attempt++ {
+			response, err = ExtractFullTimeline(ctx, accountReq, nil, nil)
+			if err == nil || !isRateLimitError(err) || attempt > 0 {
+				break
+			}
+			if err = sleepContext(ctx, rateLimitWait(err)); err != nil {
+				break
+			}
+		}
+		if err == nil {
+			_, err = SaveExtractedAccount(username, response)
+		}
+
+		if err != nil && ctx.Err() != nil {
+			summary.Cancelled = true
+			break
+		}
+
+		entries := 0
+		if err != nil {
+			summary.Failed = append(summary.Failed, ExtractSaveFailure{Username: username, Error: err.Error()})
+		} else {
+			entries = response.TotalURLs
+			summary.Succeeded = append(summary.Succeeded, username)
+		}
+
+		if onFinish != nil {
+			onFinish(i, username, entries, err)
+		}
+	}
+
+	return summary
+}
+
+// SaveExtractedAccount stores an extracted response under the account's
+// handle, taking the name, avatar and media count from its account info, and
+// returns the saved account's ID
+func SaveExtractedAccount(username string, response *TwitterResponse) (int64, error) {
+	jsonData, err := json.Marshal(response)
+	if err != nil {
+		return 0, fmt.Errorf("failed to encode response: %v", err)
+	}
+
+	handle := response.AccountInfo.Name
+	if handle == "" {
+		handle = normalizeUsername(username)
+	}
+	if handle == "" || strings.HasPrefix(handle, "id:") {
+		return 0, fmt.Errorf("cannot save account: the response has no handle")
+	}
+	if err := SaveAccount(handle, response.AccountInfo.Nick, response.AccountInfo.ProfileImage, response.TotalURLs, string(jsonData)); err != nil {
+		return 0, fmt.Errorf("failed to save account: %v", err)
+	}
+
+	id, err := accountIDByUsername(handle)
+	if err != nil {
+		return 0, fmt.Errorf("failed to save account: %v", err)
+	}
+	return id, nil
+}
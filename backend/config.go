@@ -16,3 +16,21 @@ func GetDefaultDownloadPath() string {
 	// Return path to user's Pictures folder
 	return filepath.Join(homeDir, "Pictures")
 }
+
+// GetDataDir returns the app data directory where the database and ffmpeg live
+func GetDataDir() string {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		homeDir = "."
+	}
+	return filepath.Join(homeDir, ".twitterxmediabatchdownloader")
+}
+
+// OpenDataDir opens the app data directory in the file explorer, creating it if needed
+func OpenDataDir() error {
+	dataDir := GetDataDir()
+	if err := os.MkdirAll(dataDir, 0755); err != nil {
+		return err
+	}
+	return OpenFolderInExplorer(dataDir)
+}
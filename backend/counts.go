@@ -0,0 +1,100 @@
+package backend
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// countMediaTypes stream-parses a stored response and counts entries by type,
+// decoding only the type field of each timeline (or legacy media_list) entry
+func countMediaTypes(responseJSON string) (map[string]int, error) {
+	counts := make(map[string]int)
+	decoder := json.NewDecoder(strings.NewReader(responseJSON))
+
+	if _, err := decoder.Token(); err != nil {
+		return nil, err
+	}
+
+	for decoder.More() {
+		keyToken, err := decoder.Token()
+		if err != nil {
+			return nil, err
+		}
+		key, _ := keyToken.(string)
+
+		if key != "timeline" && key != "media_list" {
+			var skipped json.RawMessage
+			if err := decoder.Decode(&skipped); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		if _, err := decoder.Token(); err != nil {
+			return nil, err
+		}
+		for decoder.More() {
+			var entry struct {
+				Type string `json:"type"`
+			}
+			if err := decoder.Decode(&entry); err != nil {
+				return nil, err
+			}
+			counts[entry.Type]++
+		}
+		if _, err := decoder.Token(); err != nil {
+			return nil, err
+		}
+	}
+
+	return counts, nil
+}
+
+// GetTypeCountsForAccounts returns media counts per type for each account ID,
+// loading all requested accounts in one query. Accounts that cannot be read
+// are omitted.
+func GetTypeCountsForAccounts(ids []int64) (map[int64]map[string]int, error) {
+	if db == nil {
+		if err := InitDB(); err != nil {
+			return nil, err
+		}
+	}
+
+	result := make(map[int64]map[string]int, len(ids))
+	if len(ids) == 0 {
+		return result, nil
+	}
+
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(ids)), ",")
+	args := make([]interface{}, len(ids))
+	for i, id := range ids {
+		args[i] = id
+	}
+
+	rows, err := db.Query("SELECT id, response_json FROM accounts WHERE id IN ("+placeholders+")", args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id int64
+		var responseData []byte
+		if err := rows.Scan(&id, &responseData); err != nil {
+			continue
+		}
+
+		responseJSON, err := decompressResponseJSON(responseData)
+		if err != nil {
+			continue
+		}
+
+		counts, err := countMediaTypes(responseJSON)
+		if err != nil {
+			continue
+		}
+		result[id] = counts
+	}
+
+	return result, rows.Err()
+}
@@ -0,0 +1,171 @@
+package backend
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// csvFields are the columns available in a CSV export, in default order
+var csvFields = []string{"tweet_id", "date", "type", "is_retweet", "url", "text"}
+
+// csvFieldValue returns the value of a column for a timeline entry
+func csvFieldValue(entry TimelineEntry, field string) string {
+	switch field {
+	case "tweet_id":
+		return strconv.FormatInt(int64(entry.TweetID), 10)
+	case "date":
+		return entry.Date
+	case "type":
+		return entry.Type
+	case "is_retweet":
+		return strconv.FormatBool(entry.IsRetweet)
+	case "url":
+		return entry.URL
+	case "text":
+		return entry.Text
+	}
+	return ""
+}
+
+// validateCSVFields checks the requested columns, defaulting to all known fields
+func validateCSVFields(fields []string) ([]string, error) {
+	if len(fields) == 0 {
+		return csvFields, nil
+	}
+
+	known := make(map[string]bool, len(csvFields))
+	for _, field := range csvFields {
+		known[field] = true
+	}
+
+	var unknown []string
+	for _, field := range fields {
+		if !known[field] {
+			unknown = append(unknown, field)
+		}
+	}
+	if len(unknown) > 0 {
+		return nil, fmt.Errorf("unknown CSV fields: %s (available: %s)", strings.Join(unknown, ", "), strings.Join(csvFields, ", "))
+	}
+
+	return fields, nil
+}
+
+// ExportAccountCSV exports an account's timeline to a CSV file with the given
+// columns in order (all known fields when empty)
+func ExportAccountCSV(id int64, outputDir string, fields []string) (string, error) {
+	columns, err := validateCSVFields(fields)
+	if err != nil {
+		return "", err
+	}
+
+	acc, err := GetAccountByID(id)
+	if err != nil {
+		return "", err
+	}
+
+	var response TwitterResponse
+	if err := json.Unmarshal([]byte(acc.ResponseJSON), &response); err != nil {
+		return "", fmt.Errorf("failed to parse account data: %v", err)
+	}
+
+	// Create export directory if not exists
+	exportDir := filepath.Join(outputDir, "twitterxmediabatchdownloader_backups")
+	if err := os.MkdirAll(exportDir, 0755); err != nil {
+		return "", err
+	}
+
+	filename := acc.Username
+	if filename == "" {
+		filename = acc.Name
+	}
+	filePath := filepath.Join(exportDir, SanitizeFilename(filename+".csv"))
+
+	if err := writeTimelineCSV(filePath, response.Timeline, columns); err != nil {
+		return "", err
+	}
+
+	return filePath, nil
+}
+
+// writeTimelineCSV writes timeline entries to a CSV file with the given columns
+func writeTimelineCSV(filePath string, entries []TimelineEntry, columns []string) error {
+	file, err := os.Create(filePath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	writer := csv.NewWriter(file)
+	writer.Write(columns)
+	for _, entry := range entries {
+		row := make([]string, len(columns))
+		for i, field := range columns {
+			row[i] = csvFieldValue(entry, field)
+		}
+		writer.Write(row)
+	}
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return fmt.Errorf("failed to write CSV: %v", err)
+	}
+
+	return nil
+}
+
+// ExportTimelineMetadata extracts a timeline and writes it to outputDir as
+// JSON, CSV or both without downloading any media. Returns the JSON path, or
+// the CSV path when only CSV is written.
+func ExportTimelineMetadata(req TimelineRequest, outputDir string, format string) (string, error) {
+	if format == "" {
+		format = "json"
+	}
+	if format != "json" && format != "csv" && format != "both" {
+		return "", fmt.Errorf("unknown export format: %s (use json, csv or both)", format)
+	}
+
+	response, err := ExtractTimeline(req)
+	if err != nil {
+		return "", err
+	}
+
+	if err := os.MkdirAll(outputDir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create output directory: %v", err)
+	}
+
+	// Name the files after the handle when the account was given by ID
+	name := normalizeUsername(req.Username)
+	if target, _ := timelineTarget(req); strings.HasPrefix(target, "id:") && response.AccountInfo.Name != "" {
+		name = response.AccountInfo.Name
+	}
+	baseName := SanitizeFilename(fmt.Sprintf("%s_metadata_%s", name, time.Now().UTC().Format(DefaultDateOutputFormat)))
+	jsonPath := filepath.Join(outputDir, baseName+".json")
+	csvPath := filepath.Join(outputDir, baseName+".csv")
+
+	if format == "json" || format == "both" {
+		jsonData, err := json.MarshalIndent(response, "", "  ")
+		if err != nil {
+			return "", fmt.Errorf("failed to encode response: %v", err)
+		}
+		if err := os.WriteFile(jsonPath, jsonData, 0644); err != nil {
+			return "", err
+		}
+	}
+
+	if format == "csv" || format == "both" {
+		if err := writeTimelineCSV(csvPath, response.Timeline, csvFields); err != nil {
+			return "", err
+		}
+		if format == "csv" {
+			return csvPath, nil
+		}
+	}
+
+	return jsonPath, nil
+}
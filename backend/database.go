@@ -1,11 +1,17 @@
 package backend
 
 import (
+	"bytes"
+	"compress/gzip"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"strconv"
+	"sync"
 	"time"
 
 	_ "github.com/mattn/go-sqlite3"
@@ -18,7 +24,7 @@ type AccountDB struct {
 	Name         string    `json:"name"`
 	ProfileImage string    `json:"profile_image"`
 	TotalMedia   int       `json:"total_media"`
-	LastFetched  time.Time `json:"last_fetched"`
+	LastFetched  time.Time `json:"last_fetched"` // always UTC
 	ResponseJSON string    `json:"response_json"`
 }
 
@@ -29,24 +35,92 @@ type AccountListItem struct {
 	Name         string `json:"name"`
 	ProfileImage string `json:"profile_image"`
 	TotalMedia   int    `json:"total_media"`
-	LastFetched  string `json:"last_fetched"`
+	LastFetched  string `json:"last_fetched"` // RFC3339 in UTC, formatted by the frontend
 	GroupName    string `json:"group_name"`
 	GroupColor   string `json:"group_color"`
+	AutoDownload bool   `json:"auto_download"`
+	Pinned       bool   `json:"pinned"`
+	DownloadDir  string `json:"download_dir"` // "" = default download path
+	LastViewed   string `json:"last_viewed"`  // RFC3339 in UTC, "" = never opened
 }
 
 var db *sql.DB
 
+const (
+	// dbBusyTimeoutMs is how long a connection waits for a lock
+	dbBusyTimeoutMs = 5000
+	// dbMaxOpenConns bounds the pool; SQLite allows one writer at a time anyway
+	dbMaxOpenConns = 4
+)
+
 // GetDBPath returns the database file path
 func GetDBPath() string {
-	homeDir, err := os.UserHomeDir()
-	if err != nil {
-		homeDir = "."
-	}
-	return filepath.Join(homeDir, ".twitterxmediabatchdownloader", "accounts.db")
+	return filepath.Join(GetDataDir(), "accounts.db")
 }
 
-// InitDB initializes the database connection
+// ErrDatabaseUnavailable is returned by every database function when the
+// database could not be opened (for example in a build without CGo)
+var ErrDatabaseUnavailable = errors.New("database unavailable")
+
+// dbRetryInterval is how long a failed InitDB is remembered before the
+// database is opened again, so callers fail fast without failing forever
+const dbRetryInterval = 5 * time.Second
+
+// dbInitErr remembers a failed InitDB, and dbInitFailedAt when it failed.
+// dbInitMu guards them and the opening and closing of db, so concurrent
+// callers don't each open a pool.
+var (
+	dbInitMu       sync.Mutex
+	dbInitErr      error
+	dbInitFailedAt time.Time
+)
+
+// InitDB initializes the database connection. A failure is returned as
+// ErrDatabaseUnavailable; calls within dbRetryInterval return it again, and
+// later ones try to open the database anew, so fixing the cause (e.g. a
+// locked or missing folder) doesn't need a restart.
 func InitDB() error {
+	dbInitMu.Lock()
+	defer dbInitMu.Unlock()
+
+	if db != nil {
+		return nil
+	}
+	if dbInitErr != nil && time.Since(dbInitFailedAt) < dbRetryInterval {
+		return dbInitErr
+	}
+
+	if err := openDB(); err != nil {
+		if db != nil {
+			db.Close()
+			db = nil
+		}
+		dbInitErr = fmt.Errorf("%w: %v", ErrDatabaseUnavailable, err)
+		dbInitFailedAt = time.Now()
+		return dbInitErr
+	}
+	dbInitErr = nil
+	return nil
+}
+
+// IsDatabaseAvailable reports whether the database is open and usable
+func IsDatabaseAvailable() bool {
+	return InitDB() == nil
+}
+
+// DatabaseError returns why the database is unavailable, or an empty string
+func DatabaseError() string {
+	dbInitMu.Lock()
+	defer dbInitMu.Unlock()
+
+	if dbInitErr == nil {
+		return ""
+	}
+	return dbInitErr.Error()
+}
+
+// openDB opens the database and creates or migrates its tables
+func openDB() error {
 	dbPath := GetDBPath()
 
 	// Create directory if not exists
@@ -55,11 +129,15 @@ func InitDB() error {
 		return err
 	}
 
+	// WAL lets reads overlap a write, and the busy timeout makes a connection
+	// wait for a lock instead of failing with "database is locked". Both are
+	// set in the DSN so every pooled connection gets them.
 	var err error
-	db, err = sql.Open("sqlite3", dbPath)
+	db, err = sql.Open("sqlite3", dbPath+"?_journal_mode=WAL&_busy_timeout="+strconv.Itoa(dbBusyTimeoutMs))
 	if err != nil {
 		return err
 	}
+	db.SetMaxOpenConns(dbMaxOpenConns)
 
 	// Create tables
 	_, err = db.Exec(`
@@ -72,7 +150,11 @@ func InitDB() error {
 			last_fetched DATETIME,
 			response_json TEXT,
 			group_name TEXT DEFAULT '',
-			group_color TEXT DEFAULT ''
+			group_color TEXT DEFAULT '',
+			auto_download INTEGER DEFAULT 0,
+			pinned INTEGER DEFAULT 0,
+			download_dir TEXT DEFAULT '',
+			last_viewed DATETIME
 		)
 	`)
 	if err != nil {
@@ -82,15 +164,146 @@ func InitDB() error {
 	// Add group columns if they don't exist (migration for existing databases)
 	db.Exec("ALTER TABLE accounts ADD COLUMN group_name TEXT DEFAULT ''")
 	db.Exec("ALTER TABLE accounts ADD COLUMN group_color TEXT DEFAULT ''")
+	db.Exec("ALTER TABLE accounts ADD COLUMN auto_download INTEGER DEFAULT 0")
+	db.Exec("ALTER TABLE accounts ADD COLUMN pinned INTEGER DEFAULT 0")
+	db.Exec("ALTER TABLE accounts ADD COLUMN download_dir TEXT DEFAULT ''")
+	db.Exec("ALTER TABLE accounts ADD COLUMN last_viewed DATETIME")
+
+	// GetAllAccounts sorts by the COALESCE it selects, so the index is on the
+	// same expression; username is already indexed by its UNIQUE constraint
+	if _, err := db.Exec("CREATE INDEX IF NOT EXISTS idx_accounts_group_fetched ON accounts (COALESCE(group_name, ''), last_fetched DESC)"); err != nil {
+		return err
+	}
+	if _, err := db.Exec("CREATE INDEX IF NOT EXISTS idx_accounts_last_viewed ON accounts (last_viewed)"); err != nil {
+		return err
+	}
+
+	if err := initHistoryTable(); err != nil {
+		return err
+	}
+
+	if err := initSettingsTable(); err != nil {
+		return err
+	}
+
+	if err := initExtractionProgressTable(); err != nil {
+		return err
+	}
+
+	if err := initMediaTable(); err != nil {
+		return err
+	}
+
+	if err := initFailedDownloadsTable(); err != nil {
+		return err
+	}
+
+	if err := initExtractCacheTable(); err != nil {
+		return err
+	}
+
+	initAccountSearchTable()
+
+	// Compress response_json rows saved before compression was introduced
+	if err := compressExistingResponses(); err != nil {
+		return err
+	}
 
 	return nil
 }
 
-// CloseDB closes the database connection
+// compressResponseJSON gzip-compresses the response JSON for storage
+func compressResponseJSON(jsonStr string) ([]byte, error) {
+	var buf bytes.Buffer
+	writer := gzip.NewWriter(&buf)
+	if _, err := writer.Write([]byte(jsonStr)); err != nil {
+		return nil, err
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// decompressResponseJSON returns the stored response JSON, decompressing it
+// when it starts with the gzip magic bytes (older rows are stored as plain text)
+func decompressResponseJSON(data []byte) (string, error) {
+	if len(data) < 2 || data[0] != 0x1f || data[1] != 0x8b {
+		return string(data), nil
+	}
+
+	reader, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return "", err
+	}
+	defer reader.Close()
+
+	decompressed, err := io.ReadAll(reader)
+	if err != nil {
+		return "", err
+	}
+	return string(decompressed), nil
+}
+
+// compressExistingResponses compresses response_json rows still stored as text
+func compressExistingResponses() error {
+	rows, err := db.Query("SELECT id, response_json FROM accounts WHERE typeof(response_json) = 'text'")
+	if err != nil {
+		return err
+	}
+
+	pending := make(map[int64]string)
+	for rows.Next() {
+		var id int64
+		var responseJSON string
+		if err := rows.Scan(&id, &responseJSON); err != nil {
+			continue
+		}
+		pending[id] = responseJSON
+	}
+	rows.Close()
+
+	if len(pending) == 0 {
+		return nil
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+
+	for id, responseJSON := range pending {
+		compressed, err := compressResponseJSON(responseJSON)
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+		if _, err := tx.Exec("UPDATE accounts SET response_json = ? WHERE id = ?", compressed, id); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// tableSchema returns the CREATE statement of a table, or "" if it doesn't exist
+func tableSchema(table string) string {
+	var schema string
+	db.QueryRow("SELECT sql FROM sqlite_master WHERE type = 'table' AND name = ?", table).Scan(&schema)
+	return schema
+}
+
+// CloseDB closes the database connection; the next InitDB opens it again
 func CloseDB() {
+	dbInitMu.Lock()
+	defer dbInitMu.Unlock()
+
 	if db != nil {
 		db.Close()
+		db = nil
 	}
+	dbInitErr = nil
 }
 
 // SaveAccount saves or updates an account in the database
@@ -101,7 +314,12 @@ func SaveAccount(username, name, profileImage string, totalMedia int, responseJS
 		}
 	}
 
-	_, err := db.Exec(`
+	compressed, err := compressResponseJSON(responseJSON)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.Exec(`
 		INSERT INTO accounts (username, name, profile_image, total_media, last_fetched, response_json)
 		VALUES (?, ?, ?, ?, ?, ?)
 		ON CONFLICT(username) DO UPDATE SET
@@ -110,9 +328,20 @@ func SaveAccount(username, name, profileImage string, totalMedia int, responseJS
 			total_media = excluded.total_media,
 			last_fetched = excluded.last_fetched,
 			response_json = excluded.response_json
-	`, username, name, profileImage, totalMedia, time.Now(), responseJSON)
+	`, username, name, profileImage, totalMedia, time.Now().UTC(), compressed)
+	if err != nil {
+		return err
+	}
 
-	return err
+	// Keep the media table and search index in sync; the account itself is already saved
+	if id, err := accountIDByUsername(username); err == nil {
+		if response, err := parseStoredResponse(responseJSON); err == nil {
+			syncMediaEntries(id, response)
+			indexAccountSearch(id, username, name, response)
+		}
+	}
+
+	return nil
 }
 
 // GetAllAccounts returns all saved accounts
@@ -124,8 +353,7 @@ func GetAllAccounts() ([]AccountListItem, error) {
 	}
 
 	rows, err := db.Query(`
-		SELECT id, username, name, profile_image, total_media, last_fetched, 
-		       COALESCE(group_name, '') as group_name, COALESCE(group_color, '') as group_color
+		SELECT ` + accountListColumns + `
 		FROM accounts
 		ORDER BY group_name ASC, last_fetched DESC
 	`)
@@ -136,18 +364,90 @@ func GetAllAccounts() ([]AccountListItem, error) {
 
 	var accounts []AccountListItem
 	for rows.Next() {
-		var acc AccountListItem
-		var lastFetched time.Time
-		if err := rows.Scan(&acc.ID, &acc.Username, &acc.Name, &acc.ProfileImage, &acc.TotalMedia, &lastFetched, &acc.GroupName, &acc.GroupColor); err != nil {
+		acc, err := scanAccountListItem(rows)
+		if err != nil {
 			continue
 		}
-		acc.LastFetched = lastFetched.Format("2006-01-02 15:04")
 		accounts = append(accounts, acc)
 	}
 
 	return accounts, nil
 }
 
+// accountListColumns are the columns read by scanAccountListItem
+const accountListColumns = `id, username, name, profile_image, total_media, last_fetched,
+		       COALESCE(group_name, '') as group_name, COALESCE(group_color, '') as group_color,
+		       COALESCE(auto_download, 0) as auto_download, COALESCE(pinned, 0) as pinned,
+		       COALESCE(download_dir, '') as download_dir, last_viewed`
+
+// scanAccountListItem scans a row selected with accountListColumns
+func scanAccountListItem(rows *sql.Rows) (AccountListItem, error) {
+	var acc AccountListItem
+	var lastFetched time.Time
+	var lastViewed sql.NullTime
+	if err := rows.Scan(&acc.ID, &acc.Username, &acc.Name, &acc.ProfileImage, &acc.TotalMedia, &lastFetched, &acc.GroupName, &acc.GroupColor, &acc.AutoDownload, &acc.Pinned, &acc.DownloadDir, &lastViewed); err != nil {
+		return acc, err
+	}
+	acc.LastFetched = lastFetched.UTC().Format(time.RFC3339)
+	if lastViewed.Valid {
+		acc.LastViewed = lastViewed.Time.UTC().Format(time.RFC3339)
+	}
+	return acc, nil
+}
+
+// TouchAccount records that an account was just opened, for the recently viewed list
+func TouchAccount(id int64) error {
+	if db == nil {
+		if err := InitDB(); err != nil {
+			return err
+		}
+	}
+
+	result, err := db.Exec("UPDATE accounts SET last_viewed = ? WHERE id = ?", time.Now().UTC(), id)
+	if err != nil {
+		return err
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return fmt.Errorf("account not found: %d", id)
+	}
+	return nil
+}
+
+// GetRecentlyViewed returns the most recently opened accounts, newest first
+// (limit <= 0 = all). Accounts never opened are not included.
+func GetRecentlyViewed(limit int) ([]AccountListItem, error) {
+	if db == nil {
+		if err := InitDB(); err != nil {
+			return nil, err
+		}
+	}
+
+	if limit <= 0 {
+		limit = -1 // SQLite: no limit
+	}
+	rows, err := db.Query(`
+		SELECT `+accountListColumns+`
+		FROM accounts
+		WHERE last_viewed IS NOT NULL
+		ORDER BY last_viewed DESC
+		LIMIT ?
+	`, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	accounts := []AccountListItem{}
+	for rows.Next() {
+		acc, err := scanAccountListItem(rows)
+		if err != nil {
+			continue
+		}
+		accounts = append(accounts, acc)
+	}
+	return accounts, rows.Err()
+}
+
 // UpdateAccountGroup updates the group for an account
 func UpdateAccountGroup(id int64, groupName, groupColor string) error {
 	if db == nil {
@@ -160,6 +460,144 @@ func UpdateAccountGroup(id int64, groupName, groupColor string) error {
 	return err
 }
 
+// RenameGroup renames a group and sets its color on every member account in one transaction
+func RenameGroup(oldName, newName, newColor string) error {
+	if db == nil {
+		if err := InitDB(); err != nil {
+			return err
+		}
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec("UPDATE accounts SET group_name = ?, group_color = ? WHERE group_name = ?", newName, newColor, oldName)
+	if err != nil {
+		return err
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return fmt.Errorf("group not found: %s", oldName)
+	}
+
+	return tx.Commit()
+}
+
+// DeleteGroup moves every member of a group to ungrouped without deleting the accounts
+func DeleteGroup(name string) error {
+	if db == nil {
+		if err := InitDB(); err != nil {
+			return err
+		}
+	}
+
+	_, err := db.Exec("UPDATE accounts SET group_name = '', group_color = '' WHERE group_name = ?", name)
+	return err
+}
+
+// SetAutoDownload sets whether new media is downloaded automatically when an account is refreshed
+func SetAutoDownload(id int64, enabled bool) error {
+	if db == nil {
+		if err := InitDB(); err != nil {
+			return err
+		}
+	}
+
+	_, err := db.Exec("UPDATE accounts SET auto_download = ? WHERE id = ?", enabled, id)
+	return err
+}
+
+// GetAutoDownload returns whether auto-download is enabled for an account
+func GetAutoDownload(id int64) (bool, error) {
+	if db == nil {
+		if err := InitDB(); err != nil {
+			return false, err
+		}
+	}
+
+	var enabled bool
+	err := db.QueryRow("SELECT COALESCE(auto_download, 0) FROM accounts WHERE id = ?", id).Scan(&enabled)
+	return enabled, err
+}
+
+// SetAccountPinned pins or unpins an account; pinned accounts are never purged as stale
+func SetAccountPinned(id int64, pinned bool) error {
+	if db == nil {
+		if err := InitDB(); err != nil {
+			return err
+		}
+	}
+
+	_, err := db.Exec("UPDATE accounts SET pinned = ? WHERE id = ?", pinned, id)
+	return err
+}
+
+// SetAccountDownloadDir sets the folder an account downloads to when no output
+// directory is given; "" reverts to the default download path
+func SetAccountDownloadDir(id int64, path string) error {
+	if db == nil {
+		if err := InitDB(); err != nil {
+			return err
+		}
+	}
+
+	_, err := db.Exec("UPDATE accounts SET download_dir = ? WHERE id = ?", path, id)
+	return err
+}
+
+// GetAccountDownloadDir returns the stored download folder of an account by
+// username, or "" when none is set or the account isn't saved
+func GetAccountDownloadDir(username string) string {
+	if db == nil {
+		if err := InitDB(); err != nil {
+			return ""
+		}
+	}
+
+	var dir string
+	db.QueryRow("SELECT COALESCE(download_dir, '') FROM accounts WHERE username = ?", username).Scan(&dir)
+	return dir
+}
+
+// PurgeStaleAccounts finds unpinned accounts not fetched in the last olderThanDays days
+// and deletes them unless dryRun is set. The matching accounts are returned either way.
+func PurgeStaleAccounts(olderThanDays int, dryRun bool) ([]AccountListItem, error) {
+	if olderThanDays <= 0 {
+		return nil, fmt.Errorf("olderThanDays must be positive, got %d", olderThanDays)
+	}
+
+	accounts, err := GetAllAccounts()
+	if err != nil {
+		return nil, err
+	}
+
+	cutoff := time.Now().UTC().AddDate(0, 0, -olderThanDays)
+	stale := []AccountListItem{}
+	for _, acc := range accounts {
+		if acc.Pinned {
+			continue
+		}
+		lastFetched, err := time.Parse(time.RFC3339, acc.LastFetched)
+		if err != nil || !lastFetched.Before(cutoff) {
+			continue
+		}
+		stale = append(stale, acc)
+	}
+
+	if dryRun {
+		return stale, nil
+	}
+
+	for _, acc := range stale {
+		if err := DeleteAccount(acc.ID); err != nil {
+			return stale, fmt.Errorf("failed to delete %s: %v", acc.Username, err)
+		}
+	}
+	return stale, nil
+}
+
 // GetAllGroups returns all unique groups
 func GetAllGroups() ([]map[string]string, error) {
 	if db == nil {
@@ -199,7 +637,13 @@ func ClearAllAccounts() error {
 		}
 	}
 
-	_, err := db.Exec("DELETE FROM accounts")
+	if _, err := db.Exec("DELETE FROM accounts"); err != nil {
+		return err
+	}
+	if accountSearchFTS {
+		db.Exec("DELETE FROM accounts_search")
+	}
+	_, err := db.Exec("DELETE FROM media")
 	return err
 }
 
@@ -213,15 +657,21 @@ func GetAccountByUsername(username string) (*AccountDB, error) {
 
 	var acc AccountDB
 	var lastFetched time.Time
+	var responseData []byte
 	err := db.QueryRow(`
 		SELECT id, username, name, profile_image, total_media, last_fetched, response_json
 		FROM accounts WHERE username = ?
-	`, username).Scan(&acc.ID, &acc.Username, &acc.Name, &acc.ProfileImage, &acc.TotalMedia, &lastFetched, &acc.ResponseJSON)
+	`, username).Scan(&acc.ID, &acc.Username, &acc.Name, &acc.ProfileImage, &acc.TotalMedia, &lastFetched, &responseData)
+
+	if err != nil {
+		return nil, err
+	}
+	acc.LastFetched = lastFetched.UTC()
 
+	acc.ResponseJSON, err = decompressResponseJSON(responseData)
 	if err != nil {
 		return nil, err
 	}
-	acc.LastFetched = lastFetched
 
 	// Convert legacy format if needed
 	if converted, err := ConvertLegacyToNewFormat(acc.ResponseJSON); err == nil {
@@ -241,15 +691,21 @@ func GetAccountByID(id int64) (*AccountDB, error) {
 
 	var acc AccountDB
 	var lastFetched time.Time
+	var responseData []byte
 	err := db.QueryRow(`
 		SELECT id, username, name, profile_image, total_media, last_fetched, response_json
 		FROM accounts WHERE id = ?
-	`, id).Scan(&acc.ID, &acc.Username, &acc.Name, &acc.ProfileImage, &acc.TotalMedia, &lastFetched, &acc.ResponseJSON)
+	`, id).Scan(&acc.ID, &acc.Username, &acc.Name, &acc.ProfileImage, &acc.TotalMedia, &lastFetched, &responseData)
 
 	if err != nil {
 		return nil, err
 	}
-	acc.LastFetched = lastFetched
+	acc.LastFetched = lastFetched.UTC()
+
+	acc.ResponseJSON, err = decompressResponseJSON(responseData)
+	if err != nil {
+		return nil, err
+	}
 
 	// Convert legacy format if needed
 	if converted, err := ConvertLegacyToNewFormat(acc.ResponseJSON); err == nil {
@@ -267,8 +723,11 @@ func DeleteAccount(id int64) error {
 		}
 	}
 
-	_, err := db.Exec("DELETE FROM accounts WHERE id = ?", id)
-	return err
+	if _, err := db.Exec("DELETE FROM accounts WHERE id = ?", id); err != nil {
+		return err
+	}
+	deleteAccountSearch(id)
+	return deleteAccountMedia(id)
 }
 
 // ParseResponseJSON parses the stored JSON response
@@ -389,7 +848,7 @@ func ExportAccountToFile(id int64, outputDir string) (string, error) {
 		filename = acc.Name
 	}
 
-	filePath := filepath.Join(exportDir, filename+".json")
+	filePath := filepath.Join(exportDir, SanitizeFilename(filename+".json"))
 
 	if err := os.WriteFile(filePath, []byte(acc.ResponseJSON), 0644); err != nil {
 		return "", err
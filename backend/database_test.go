@@ -0,0 +1,132 @@
+package backend
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestConcurrentAccountReadsAndWrites(t *testing.T) {
+	useTempDB(t)
+
+	var mode string
+	if err := db.QueryRow("PRAGMA journal_mode").Scan(&mode); err != nil || !strings.EqualFold(mode, "wal") {
+		t.Fatalf("journal_mode = %q, %v, want wal", mode, err)
+	}
+
+	const workers, rounds = 8, 25
+	errs := make(chan error, 2*workers*rounds)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(2)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < rounds; i++ {
+				username := fmt.Sprintf("user%d_%d", w, i%5)
+				response := fmt.Sprintf(`{"account_info":{"name":%q},"timeline":[]}`, username)
+				if err := SaveAccount(username, username, "", i, response); err != nil {
+					errs <- fmt.Errorf("SaveAccount: %v", err)
+				}
+			}
+		}(w)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < rounds; i++ {
+				if _, err := GetAllAccounts(); err != nil {
+					errs <- fmt.Errorf("GetAllAccounts: %v", err)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if strings.Contains(err.Error(), "locked") {
+			t.Errorf("lock contention: %v", err)
+		} else {
+			t.Error(err)
+		}
+	}
+
+	accounts, err := GetAllAccounts()
+	if err != nil {
+		t.Fatalf("GetAllAccounts: %v", err)
+	}
+	if len(accounts) != workers*5 {
+		t.Errorf("%d accounts saved, want %d", len(accounts), workers*5)
+	}
+}
+
+func TestInitDBRetriesAfterFailure(t *testing.T) {
+	CloseDB()
+	t.Cleanup(CloseDB)
+
+	// A home "directory" that is a file makes the data folder impossible to create
+	home := filepath.Join(t.TempDir(), "home")
+	if err := os.WriteFile(home, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("HOME", home)
+	t.Setenv("USERPROFILE", home)
+
+	if err := InitDB(); !errors.Is(err, ErrDatabaseUnavailable) {
+		t.Fatalf("InitDB = %v, want ErrDatabaseUnavailable", err)
+	}
+	if IsDatabaseAvailable() || DatabaseError() == "" {
+		t.Fatal("database reported available after a failed open")
+	}
+
+	// Fix the cause; the cached error holds until the retry interval passes
+	if err := os.Remove(home); err != nil {
+		t.Fatal(err)
+	}
+	if err := InitDB(); err == nil {
+		t.Fatal("InitDB retried within the retry interval")
+	}
+	dbInitFailedAt = time.Now().Add(-dbRetryInterval)
+
+	if err := InitDB(); err != nil {
+		t.Fatalf("InitDB after fixing the cause: %v", err)
+	}
+	if !IsDatabaseAvailable() || DatabaseError() != "" {
+		t.Errorf("database unavailable after a successful open: %q", DatabaseError())
+	}
+}
+
+func TestConcurrentInitDB(t *testing.T) {
+	CloseDB()
+	t.Cleanup(CloseDB)
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("USERPROFILE", t.TempDir())
+
+	const workers = 8
+	pools := make([]*sql.DB, workers)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if err := InitDB(); err != nil {
+				t.Errorf("InitDB: %v", err)
+				return
+			}
+			dbInitMu.Lock()
+			pools[i] = db
+			dbInitMu.Unlock()
+		}(i)
+	}
+	wg.Wait()
+
+	for i, pool := range pools {
+		if pool != pools[0] {
+			t.Fatalf("caller %d got a different pool, want a single one", i)
+		}
+	}
+}
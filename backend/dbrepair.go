@@ -0,0 +1,410 @@
+package backend
+
+import (
+	"bytes"
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// maxDatabaseBackups is how many automatic database backups are kept
+	maxDatabaseBackups = 5
+	// databaseBackupInterval is how often an automatic backup is taken
+	databaseBackupInterval = 24 * time.Hour
+	// backupFileGlob matches the automatic backup files
+	backupFileGlob = "accounts-*.db"
+)
+
+// Repair actions reported by RepairDatabase
+const (
+	RepairNone    = "none"    // the database was healthy
+	RepairRecover = "recover" // rows were copied into a fresh database
+	RepairRestore = "restore" // the most recent backup was restored
+	RepairReset   = "reset"   // nothing could be saved, a new empty database was created
+)
+
+// DatabaseIntegrity is the result of PRAGMA integrity_check
+type DatabaseIntegrity struct {
+	OK       bool     `json:"ok"`
+	Problems []string `json:"problems,omitempty"`
+}
+
+// DatabaseRepairResult reports what RepairDatabase did
+type DatabaseRepairResult struct {
+	Action        string `json:"action"`
+	Message       string `json:"message"`
+	RecoveredRows int    `json:"recovered_rows,omitempty"`
+	Backup        string `json:"backup,omitempty"`       // backup restored from
+	CorruptCopy   string `json:"corrupt_copy,omitempty"` // where the damaged file was moved
+}
+
+// getDatabaseBackupDir returns the folder holding automatic database backups
+func getDatabaseBackupDir() string {
+	return filepath.Join(GetDataDir(), "db-backups")
+}
+
+// CheckDatabaseIntegrity runs PRAGMA integrity_check on the database file. It
+// works even when the database failed to open, using a separate connection.
+func CheckDatabaseIntegrity() (*DatabaseIntegrity, error) {
+	conn := db
+	if conn == nil {
+		if _, err := os.Stat(GetDBPath()); err != nil {
+			return nil, fmt.Errorf("database file not found: %v", err)
+		}
+		var err error
+		conn, err = sql.Open("sqlite3", GetDBPath())
+		if err != nil {
+			return &DatabaseIntegrity{Problems: []string{err.Error()}}, nil
+		}
+		defer conn.Close()
+	}
+
+	return integrityCheck(conn), nil
+}
+
+// integrityCheck runs PRAGMA integrity_check, treating a failed query as a problem
+func integrityCheck(conn *sql.DB) *DatabaseIntegrity {
+	rows, err := conn.Query("PRAGMA integrity_check")
+	if err != nil {
+		return &DatabaseIntegrity{Problems: []string{err.Error()}}
+	}
+	defer rows.Close()
+
+	result := &DatabaseIntegrity{}
+	for rows.Next() {
+		var line string
+		if err := rows.Scan(&line); err != nil {
+			result.Problems = append(result.Problems, err.Error())
+			continue
+		}
+		if line != "ok" {
+			result.Problems = append(result.Problems, line)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		result.Problems = append(result.Problems, err.Error())
+	}
+	result.OK = len(result.Problems) == 0
+	return result
+}
+
+// BackupDatabase writes a consistent copy of the database to the backup
+// folder and keeps only the newest maxDatabaseBackups copies
+func BackupDatabase() (string, error) {
+	if db == nil {
+		if err := InitDB(); err != nil {
+			return "", err
+		}
+	}
+
+	dir := getDatabaseBackupDir()
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+
+	path := filepath.Join(dir, fmt.Sprintf("accounts-%s.db", time.Now().UTC().Format("20060102-150405")))
+	if _, err := db.Exec("VACUUM INTO ?", path); err != nil {
+		return "", fmt.Errorf("failed to back up database: %v", err)
+	}
+
+	backups := listDatabaseBackups()
+	for len(backups) > maxDatabaseBackups {
+		os.Remove(backups[len(backups)-1])
+		backups = backups[:len(backups)-1]
+	}
+	return path, nil
+}
+
+// BackupDatabaseIfDue takes a backup when the newest one is older than a day
+func BackupDatabaseIfDue() error {
+	if backups := listDatabaseBackups(); len(backups) > 0 {
+		if info, err := os.Stat(backups[0]); err == nil && time.Since(info.ModTime()) < databaseBackupInterval {
+			return nil
+		}
+	}
+	_, err := BackupDatabase()
+	return err
+}
+
+// listDatabaseBackups returns the backup files, newest first. The timestamped
+// names sort chronologically.
+func listDatabaseBackups() []string {
+	backups, _ := filepath.Glob(filepath.Join(getDatabaseBackupDir(), backupFileGlob))
+	sort.Sort(sort.Reverse(sort.StringSlice(backups)))
+	return backups
+}
+
+// RepairDatabase recovers a corrupt database. The damaged file is moved
+// aside and every readable row is copied into a freshly created database; if
+// nothing can be read, the most recent backup is restored instead. A healthy
+// database is left untouched.
+func RepairDatabase() (*DatabaseRepairResult, error) {
+	integrity, err := CheckDatabaseIntegrity()
+	if err != nil {
+		return nil, err
+	}
+	if integrity.OK && IsDatabaseAvailable() {
+		return &DatabaseRepairResult{Action: RepairNone, Message: "Database is healthy, nothing to repair"}, nil
+	}
+
+	// Move the damaged file (and its WAL) aside so a fresh database can be created
+	CloseDB()
+
+	dbPath := GetDBPath()
+	corruptPath := fmt.Sprintf("%s.corrupt-%s", dbPath, time.Now().UTC().Format("20060102-150405"))
+	for _, suffix := range []string{"", "-wal", "-shm"} {
+		if _, err := os.Stat(dbPath + suffix); err == nil {
+			if err := os.Rename(dbPath+suffix, corruptPath+suffix); err != nil {
+				return nil, fmt.Errorf("failed to move damaged database: %v", err)
+			}
+		}
+	}
+
+	result := &DatabaseRepairResult{CorruptCopy: corruptPath}
+
+	if err := InitDB(); err != nil {
+		return nil, fmt.Errorf("failed to create new database: %v", err)
+	}
+
+	recovered, recoverErr := copyReadableRows(corruptPath)
+	if recoverErr == nil {
+		SetSetting(SettingSearchIndexed, "")
+		result.Action = RepairRecover
+		result.RecoveredRows = recovered
+		result.Message = fmt.Sprintf("Recovered %d rows into a new database", recovered)
+		return result, nil
+	}
+
+	// Nothing was readable, fall back to the newest backup
+	backups := listDatabaseBackups()
+	if len(backups) == 0 {
+		result.Action = RepairReset
+		result.Message = fmt.Sprintf("Database could not be read (%v) and no backup exists; started a new empty database", recoverErr)
+		return result, nil
+	}
+
+	CloseDB()
+	for _, suffix := range []string{"-wal", "-shm"} {
+		os.Remove(dbPath + suffix)
+	}
+	if err := copyFile(backups[0], dbPath); err != nil {
+		return nil, fmt.Errorf("failed to restore backup: %v", err)
+	}
+	if err := InitDB(); err != nil {
+		return nil, fmt.Errorf("failed to open restored backup: %v", err)
+	}
+
+	result.Action = RepairRestore
+	result.Backup = backups[0]
+	result.Message = fmt.Sprintf("Database could not be read (%v); restored backup %s", recoverErr, filepath.Base(backups[0]))
+	return result, nil
+}
+
+// copyReadableRows copies every row still readable from the damaged database
+// at path into the open database, table by table. Rows past the first read
+// error in a table are lost. Fails only if the accounts table can't be read.
+func copyReadableRows(path string) (int, error) {
+	src, err := sql.Open("sqlite3", path+"?mode=ro")
+	if err != nil {
+		return 0, err
+	}
+	defer src.Close()
+
+	// The full-text index and its shadow tables are rebuilt from the accounts
+	// by BackfillSearchIndex rather than copied
+	tables, err := db.Query("SELECT name FROM sqlite_master WHERE type = 'table' AND name NOT LIKE 'sqlite_%' AND name NOT LIKE 'accounts_search%'")
+	if err != nil {
+		return 0, err
+	}
+	var names []string
+	for tables.Next() {
+		var name string
+		if err := tables.Scan(&name); err == nil {
+			names = append(names, name)
+		}
+	}
+	tables.Close()
+
+	total := 0
+	var accountsErr error
+	for _, name := range names {
+		count, err := copyTableRows(src, name)
+		total += count
+		if name == "accounts" && err != nil && count == 0 {
+			accountsErr = err
+		}
+	}
+
+	if accountsErr != nil {
+		return total, accountsErr
+	}
+	return total, nil
+}
+
+// copyTableRows copies the columns a table has in both databases, skipping
+// rows that conflict with ones already copied
+func copyTableRows(src *sql.DB, table string) (int, error) {
+	destColumns := make(map[string]bool)
+	info, err := db.Query(fmt.Sprintf("PRAGMA table_info(%q)", table))
+	if err != nil {
+		return 0, err
+	}
+	for info.Next() {
+		var cid, notNull, pk int
+		var name, colType string
+		var defaultValue sql.NullString
+		if err := info.Scan(&cid, &name, &colType, &notNull, &defaultValue, &pk); err == nil {
+			destColumns[name] = true
+		}
+	}
+	info.Close()
+
+	rows, err := src.Query(fmt.Sprintf("SELECT * FROM %q", table))
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	srcColumns, err := rows.Columns()
+	if err != nil {
+		return 0, err
+	}
+
+	// Only copy columns the new schema knows about
+	var keep []int
+	var quoted []string
+	for i, column := range srcColumns {
+		if destColumns[column] {
+			keep = append(keep, i)
+			quoted = append(quoted, fmt.Sprintf("%q", column))
+		}
+	}
+	if len(keep) == 0 {
+		return 0, nil
+	}
+
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(keep)), ", ")
+	insert := fmt.Sprintf("INSERT OR IGNORE INTO %q (%s) VALUES (%s)", table, strings.Join(quoted, ", "), placeholders)
+
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+	stmt, err := tx.Prepare(insert)
+	if err != nil {
+		return 0, err
+	}
+	defer stmt.Close()
+
+	copied := 0
+	values := make([]interface{}, len(srcColumns))
+	pointers := make([]interface{}, len(srcColumns))
+	for i := range values {
+		pointers[i] = &values[i]
+	}
+	for rows.Next() {
+		if err := rows.Scan(pointers...); err != nil {
+			continue
+		}
+		args := make([]interface{}, len(keep))
+		for i, index := range keep {
+			args[i] = values[index]
+		}
+		if _, err := stmt.Exec(args...); err == nil {
+			copied++
+		}
+	}
+	readErr := rows.Err()
+
+	// Keep whatever was read before an error
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return copied, readErr
+}
+
+// Problems reported by FindCorruptAccounts
+const (
+	AccountProblemEmpty       = "empty"       // no response JSON saved
+	AccountProblemUnreadable  = "unreadable"  // the stored data can't be decompressed
+	AccountProblemUnparseable = "unparseable" // the JSON doesn't parse
+	AccountProblemNoEntries   = "no_entries"  // parses but has no timeline entries
+)
+
+// CorruptAccount is a saved account whose stored response is unusable
+type CorruptAccount struct {
+	ID          int64  `json:"id"`
+	Username    string `json:"username"`
+	Name        string `json:"name"`
+	LastFetched string `json:"last_fetched"` // RFC3339 in UTC, "" = unknown
+	Problem     string `json:"problem"`
+	Detail      string `json:"detail,omitempty"` // parse error, when there is one
+}
+
+// FindCorruptAccounts reports the saved accounts whose response JSON is
+// empty, unreadable, unparseable or has no timeline entries, typically saved
+// after a failed extraction, so they can be re-fetched or deleted. Nothing is
+// changed.
+func FindCorruptAccounts() ([]CorruptAccount, error) {
+	if db == nil {
+		if err := InitDB(); err != nil {
+			return nil, err
+		}
+	}
+
+	rows, err := db.Query("SELECT id, username, COALESCE(name, ''), last_fetched, response_json FROM accounts ORDER BY username")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	corrupt := []CorruptAccount{}
+	for rows.Next() {
+		var acc CorruptAccount
+		var lastFetched sql.NullTime
+		var data []byte
+		if err := rows.Scan(&acc.ID, &acc.Username, &acc.Name, &lastFetched, &data); err != nil {
+			continue
+		}
+		if lastFetched.Valid {
+			acc.LastFetched = lastFetched.Time.UTC().Format(time.RFC3339)
+		}
+
+		acc.Problem, acc.Detail = storedResponseProblem(data)
+		if acc.Problem != "" {
+			corrupt = append(corrupt, acc)
+		}
+	}
+	return corrupt, rows.Err()
+}
+
+// storedResponseProblem returns what is wrong with a stored response_json
+// value, or "" when it holds at least one timeline entry
+func storedResponseProblem(data []byte) (problem, detail string) {
+	if len(bytes.TrimSpace(data)) == 0 {
+		return AccountProblemEmpty, ""
+	}
+
+	jsonStr, err := decompressResponseJSON(data)
+	if err != nil {
+		return AccountProblemUnreadable, err.Error()
+	}
+	if strings.TrimSpace(jsonStr) == "" {
+		return AccountProblemEmpty, ""
+	}
+
+	response, err := parseStoredResponse(jsonStr)
+	if err != nil {
+		return AccountProblemUnparseable, err.Error()
+	}
+	if len(response.Timeline) == 0 {
+		return AccountProblemNoEntries, ""
+	}
+	return "", ""
+}
@@ -0,0 +1,41 @@
+package backend
+
+import (
+	"encoding/json"
+)
+
+// ExtractionDefaults are the saved values used when a request leaves a field unset
+type ExtractionDefaults struct {
+	TimelineType string `json:"timeline_type"`
+	MediaType    string `json:"media_type"`
+	BatchSize    int    `json:"batch_size"`
+	Retweets     bool   `json:"retweets"`
+}
+
+// GetExtractionDefaults returns the saved extraction defaults (zero values if unset)
+func GetExtractionDefaults() (ExtractionDefaults, error) {
+	var defaults ExtractionDefaults
+
+	value, err := GetSetting(SettingExtractionDefaults)
+	if err != nil || value == "" {
+		return defaults, err
+	}
+
+	if err := json.Unmarshal([]byte(value), &defaults); err != nil {
+		return ExtractionDefaults{}, err
+	}
+	return defaults, nil
+}
+
+// SetExtractionDefaults saves the extraction defaults
+func SetExtractionDefaults(defaults ExtractionDefaults) error {
+	if defaults.BatchSize < 0 {
+		defaults.BatchSize = 0
+	}
+
+	data, err := json.Marshal(defaults)
+	if err != nil {
+		return err
+	}
+	return SetSetting(SettingExtractionDefaults, string(data))
+}
@@ -0,0 +1,111 @@
+package backend
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// activeDownloads tracks output paths that are currently being written
+var (
+	activeDownloadsMu sync.Mutex
+	activeDownloads   = make(map[string]bool)
+)
+
+// markDownloading records that path is being written by a download worker
+func markDownloading(path string) {
+	activeDownloadsMu.Lock()
+	activeDownloads[filepath.Clean(path)] = true
+	activeDownloadsMu.Unlock()
+}
+
+// unmarkDownloading records that path is no longer being written
+func unmarkDownloading(path string) {
+	activeDownloadsMu.Lock()
+	delete(activeDownloads, filepath.Clean(path))
+	activeDownloadsMu.Unlock()
+}
+
+// isDownloading reports whether path is currently being written
+func isDownloading(path string) bool {
+	activeDownloadsMu.Lock()
+	defer activeDownloadsMu.Unlock()
+	return activeDownloads[filepath.Clean(path)]
+}
+
+// knownDownloadDirs returns the default download path plus every output
+// directory recorded in the download history
+func knownDownloadDirs() []string {
+	dirs := []string{GetDefaultDownloadPath()}
+
+	records, err := GetDownloadHistory(0)
+	if err != nil {
+		return dirs
+	}
+
+	seen := map[string]bool{dirs[0]: true}
+	for _, record := range records {
+		if record.OutputDir != "" && !seen[record.OutputDir] {
+			seen[record.OutputDir] = true
+			dirs = append(dirs, record.OutputDir)
+		}
+	}
+
+	return dirs
+}
+
+// isInsideDir reports whether path is strictly inside dir
+func isInsideDir(path, dir string) bool {
+	rel, err := filepath.Rel(filepath.Clean(dir), path)
+	if err != nil {
+		return false
+	}
+	return rel != "." && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) && !filepath.IsAbs(rel)
+}
+
+// DeleteDownloadedFile removes a downloaded media file and its .json sidecar,
+// refusing paths outside the known download directories and files that are
+// still being downloaded
+func DeleteDownloadedFile(path string) error {
+	cleanPath, err := filepath.Abs(filepath.Clean(path))
+	if err != nil {
+		return fmt.Errorf("invalid path: %v", err)
+	}
+
+	inside := false
+	for _, dir := range knownDownloadDirs() {
+		if isInsideDir(cleanPath, dir) {
+			inside = true
+			break
+		}
+	}
+	if !inside {
+		return fmt.Errorf("path is not inside a download directory: %s", cleanPath)
+	}
+
+	info, err := os.Stat(cleanPath)
+	if err != nil {
+		return fmt.Errorf("file not found: %s", cleanPath)
+	}
+	if info.IsDir() {
+		return fmt.Errorf("path is a directory: %s", cleanPath)
+	}
+
+	if isDownloading(cleanPath) {
+		return fmt.Errorf("file is currently being downloaded: %s", cleanPath)
+	}
+
+	if err := os.Remove(cleanPath); err != nil {
+		return fmt.Errorf("failed to delete file: %v", err)
+	}
+
+	// Remove the metadata sidecar if one was written alongside the file
+	sidecar := cleanPath + ".json"
+	if err := os.Remove(sidecar); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to delete sidecar: %v", err)
+	}
+
+	return nil
+}
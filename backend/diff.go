@@ -0,0 +1,140 @@
+package backend
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+)
+
+// AccountDiff represents the changes between a stored and a new timeline
+type AccountDiff struct {
+	AddedTweetIDs   []TweetIDString `json:"added_tweet_ids"`
+	RemovedTweetIDs []TweetIDString `json:"removed_tweet_ids"`
+	AddedMedia      int             `json:"added_media"`
+	RemovedMedia    int             `json:"removed_media"`
+	Warnings        []string        `json:"warnings"`
+}
+
+// countMediaByTweet parses a response JSON and counts media entries per tweet ID
+func countMediaByTweet(responseJSON string) (map[TweetIDString]int, error) {
+	var response TwitterResponse
+	if err := json.Unmarshal([]byte(responseJSON), &response); err != nil {
+		return nil, err
+	}
+
+	counts := make(map[TweetIDString]int)
+	for _, entry := range response.Timeline {
+		counts[entry.TweetID]++
+	}
+	return counts, nil
+}
+
+// NewTimelineEntries returns the entries of a response whose tweet IDs are not
+// in the stored response JSON (all entries when the stored JSON is unparseable)
+func NewTimelineEntries(storedJSON string, response *TwitterResponse) []TimelineEntry {
+	stored, err := countMediaByTweet(storedJSON)
+	if err != nil {
+		stored = map[TweetIDString]int{}
+	}
+
+	entries := []TimelineEntry{}
+	for _, entry := range response.Timeline {
+		if _, ok := stored[entry.TweetID]; !ok {
+			entries = append(entries, entry)
+		}
+	}
+	return entries
+}
+
+// MaxStoredTweetID returns the highest tweet ID in a stored response JSON, or
+// 0 when it has no entries or can't be parsed
+func MaxStoredTweetID(storedJSON string) int64 {
+	response, err := parseStoredResponse(storedJSON)
+	if err != nil {
+		return 0
+	}
+
+	var maxID int64
+	for _, entry := range response.Timeline {
+		if id := int64(entry.TweetID); id > maxID {
+			maxID = id
+		}
+	}
+	return maxID
+}
+
+// MergeIncremental appends the stored timeline to an incremental response
+// holding only newer entries, skipping stored entries the response repeats.
+// An unparseable stored response leaves the response as is.
+func MergeIncremental(storedJSON string, response *TwitterResponse) *TwitterResponse {
+	stored, err := parseStoredResponse(storedJSON)
+	if err != nil {
+		return response
+	}
+
+	type entryKey struct {
+		tweetID TweetIDString
+		url     string
+	}
+	seen := make(map[entryKey]bool, len(response.Timeline))
+	for _, entry := range response.Timeline {
+		seen[entryKey{entry.TweetID, entry.URL}] = true
+	}
+
+	merged := *response
+	merged.Timeline = append([]TimelineEntry{}, response.Timeline...)
+	for _, entry := range stored.Timeline {
+		if !seen[entryKey{entry.TweetID, entry.URL}] {
+			merged.Timeline = append(merged.Timeline, entry)
+		}
+	}
+	merged.TotalURLs = len(merged.Timeline)
+	return &merged
+}
+
+// DiffAccount compares the stored timeline of an account with a new response
+// JSON by tweet ID; an unparseable side is treated as empty and reported as a warning
+func DiffAccount(id int64, newResponseJSON string) (*AccountDiff, error) {
+	acc, err := GetAccountByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	diff := &AccountDiff{
+		AddedTweetIDs:   []TweetIDString{},
+		RemovedTweetIDs: []TweetIDString{},
+		Warnings:        []string{},
+	}
+
+	stored, err := countMediaByTweet(acc.ResponseJSON)
+	if err != nil {
+		stored = map[TweetIDString]int{}
+		diff.Warnings = append(diff.Warnings, fmt.Sprintf("stored timeline could not be parsed: %v", err))
+	}
+
+	updated, err := countMediaByTweet(newResponseJSON)
+	if err != nil {
+		updated = map[TweetIDString]int{}
+		diff.Warnings = append(diff.Warnings, fmt.Sprintf("new timeline could not be parsed: %v", err))
+	}
+
+	for tweetID, count := range updated {
+		if _, ok := stored[tweetID]; !ok {
+			diff.AddedTweetIDs = append(diff.AddedTweetIDs, tweetID)
+			diff.AddedMedia += count
+		}
+	}
+
+	for tweetID, count := range stored {
+		if _, ok := updated[tweetID]; !ok {
+			diff.RemovedTweetIDs = append(diff.RemovedTweetIDs, tweetID)
+			diff.RemovedMedia += count
+		}
+	}
+
+	// Newest first
+	sort.Slice(diff.AddedTweetIDs, func(i, j int) bool { return diff.AddedTweetIDs[i] > diff.AddedTweetIDs[j] })
+	sort.Slice(diff.RemovedTweetIDs, func(i, j int) bool { return diff.RemovedTweetIDs[i] > diff.RemovedTweetIDs[j] })
+
+	return diff, nil
+}
@@ -0,0 +1,57 @@
+package backend
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+)
+
+// GetFolderSize returns the total size in bytes of the regular files under
+// path. Symlinks are neither followed nor counted, so links pointing outside
+// the tree or back into it can't inflate the total or loop. Unreadable
+// entries are skipped. The walk stops early when ctx is cancelled.
+func GetFolderSize(ctx context.Context, path string) (int64, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	var total int64
+	err := filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
+		if err != nil {
+			// The root itself must be readable; anything below is best effort
+			if p == path {
+				return err
+			}
+			if d != nil && d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		total += info.Size()
+		return nil
+	})
+	if err != nil {
+		return total, fmt.Errorf("failed to measure folder: %v", err)
+	}
+	return total, nil
+}
+
+// GetAccountArchiveSize returns the size in bytes of an account's download folder
+func GetAccountArchiveSize(ctx context.Context, username, baseDir string) (int64, error) {
+	if baseDir == "" {
+		baseDir = GetDefaultDownloadPath()
+	}
+	return GetFolderSize(ctx, filepath.Join(baseDir, SanitizeFilename(username)))
+}
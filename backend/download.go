@@ -2,12 +2,14 @@ package backend
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"net/url"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 	"sync"
 	"sync/atomic"
@@ -26,6 +28,8 @@ type MediaItem struct {
 	TweetID  int64  `json:"tweet_id"`
 	Type     string `json:"type"`
 	Username string `json:"username"`
+	Text     string `json:"text,omitempty"`
+	AltText  string `json:"alt_text,omitempty"`
 }
 
 // DownloadMediaFiles downloads media files from URLs to the output directory (legacy)
@@ -35,12 +39,10 @@ func DownloadMediaFiles(urls []string, outputDir string) (downloaded int, failed
 		return 0, len(urls), fmt.Errorf("failed to create output directory: %v", err)
 	}
 
-	client := &http.Client{
-		Timeout: 60 * time.Second,
-	}
+	client := newHTTPClient(60 * time.Second)
 
 	for _, mediaURL := range urls {
-		filename := extractFilename(mediaURL)
+		filename := SanitizeFilename(extractFilename(mediaURL))
 		outputPath := filepath.Join(outputDir, filename)
 
 		// Skip if file already exists
@@ -59,9 +61,177 @@ func DownloadMediaFiles(urls []string, outputDir string) (downloaded int, failed
 	return downloaded, failed, nil
 }
 
+// DownloadOptions holds optional settings for downloading media with metadata
+type DownloadOptions struct {
+	FilenameTemplate  string         // "" = DefaultFilenameTemplate
+	MaxFilenameLength int            // 0 = DefaultMaxFilenameLength
+	DateInputFormat   string         // Go layout tried before the known extractor formats
+	DateOutputFormat  string         // Go layout for dates in filenames, "" = DefaultDateOutputFormat
+	GenerateGallery   bool           // write an index.html gallery after downloading
+	ArchiveIndex      bool           // append downloaded items to archive-index.jsonl
+	EmbedMetadata     bool           // write tweet text and URL into images (XMP or .json sidecar)
+	WriteAltText      bool           // write alt text, when present, to a .json sidecar next to the file
+	FullResolution    bool           // request the original-quality image variant (name=orig)
+	ImageFormat       string         // force jpg, png or webp for images, "" = as served
+	Retries           int            // extra attempts for transient failures, 0 = DefaultDownloadRetries, < 0 = none
+	MaxRetryAfter     time.Duration  // longest wait honoured from a Retry-After header, 0 = DefaultMaxRetryAfter
+	HostConcurrency   map[string]int // per-host limits merged over DefaultHostConcurrency, <= 0 = unlimited
+	CircuitBreaker    int            // consecutive failures that stop the batch, 0 = DefaultCircuitBreakerThreshold, < 0 = off
+	Pause             *PauseController
+	Throughput        *ThroughputMeter                    // optional, aggregates bytes across workers
+	MinWidth          int                                 // skip images narrower than this (0 = no minimum)
+	MinHeight         int                                 // skip images shorter than this (0 = no minimum)
+	FilterHosts       bool                                // skip items whose URL host is not allowed
+	AllowedHosts      []string                            // empty = pbs.twimg.com and video.twimg.com
+	BlockedHosts      []string                            // always skipped, takes precedence over AllowedHosts
+	OnFailure         func(item MediaItem, err error)     // called from worker goroutines
+	OnSkipped         func(item MediaItem, reason string) // called from worker goroutines
+	OverwritePolicy   string                              // what to do when a file exists, "" = OverwriteSkip
+	OnExisting        func(item MediaItem, policy string) // called from worker goroutines when the file exists
+	Cookies           []*http.Cookie                      // sent to the media CDN, e.g. SessionCookies for session-gated media
+
+	// OnAttempts is called from worker goroutines with the number of HTTP
+	// attempts an item took and its final error
+	OnAttempts func(item MediaItem, attempts int, err error)
+
+	// OnDone is called from worker goroutines once an item has been handled,
+	// whatever the outcome. Items never started, and those finishing after
+	// the batch was cancelled, are not reported.
+	OnDone func(item MediaItem)
+
+	// MinFreeBytes stops the batch cleanly, with ErrLowDiskSpace, once free
+	// space on the output volume drops below it (0 = no check). Space is
+	// checked before starting and every FreeSpaceCheckEvery completed items.
+	MinFreeBytes        int64
+	FreeSpaceCheckEvery int // 0 = DefaultFreeSpaceCheckEvery
+}
+
+// DefaultFreeSpaceCheckEvery is how many completed items pass between free
+// space checks when MinFreeBytes is set
+const DefaultFreeSpaceCheckEvery = 10
+
+// ErrLowDiskSpace is returned when a batch stopped because free space fell
+// below DownloadOptions.MinFreeBytes; the unattempted items are reported as failed
+var ErrLowDiskSpace = errors.New("low disk space: download stopped before the disk filled up, free some space and retry")
+
+// Overwrite policies for files that already exist at the output path
+const (
+	OverwriteSkip    = "skip"      // keep the existing file and count it as downloaded
+	OverwriteReplace = "overwrite" // download again and replace the existing file
+	OverwriteRename  = "rename"    // download to a numbered name, keeping both copies
+)
+
+// ValidateOverwritePolicy checks an overwrite policy name; "" is the default
+func ValidateOverwritePolicy(policy string) error {
+	switch policy {
+	case "", OverwriteSkip, OverwriteReplace, OverwriteRename:
+		return nil
+	}
+	return fmt.Errorf("unknown overwrite policy: %s", policy)
+}
+
+// uniqueOutputPath returns path with the first free numeric suffix, e.g.
+// name_1.jpg, shortening the name so it stays within maxLength bytes
+// (0 = DefaultMaxFilenameLength)
+func uniqueOutputPath(path string, maxLength int) string {
+	if maxLength <= 0 {
+		maxLength = DefaultMaxFilenameLength
+	}
+	dir, name := filepath.Split(path)
+	ext := filepath.Ext(name)
+	stem := strings.TrimSuffix(name, ext)
+	for i := 1; ; i++ {
+		suffix := fmt.Sprintf("_%d", i)
+		keep := maxLength - len(suffix) - len(ext)
+		if keep < 1 {
+			keep = 1
+		}
+		candidate := dir + truncateUTF8(stem, keep) + suffix + ext
+		if _, err := os.Stat(candidate); os.IsNotExist(err) {
+			return candidate
+		}
+	}
+}
+
+// FilterByTweetIDRange keeps items whose tweet ID is within [minID, maxID]
+// (0 = no bound) and returns them with the number filtered out. Tweet IDs are
+// time-ordered snowflakes, so this gives exact incremental boundaries.
+func FilterByTweetIDRange(items []MediaItem, minID, maxID int64) ([]MediaItem, int) {
+	if minID <= 0 && maxID <= 0 {
+		return items, 0
+	}
+
+	kept := make([]MediaItem, 0, len(items))
+	for _, item := range items {
+		if (minID > 0 && item.TweetID < minID) || (maxID > 0 && item.TweetID > maxID) {
+			continue
+		}
+		kept = append(kept, item)
+	}
+	return kept, len(items) - len(kept)
+}
+
+// SortByDateNewest sorts items newest first by their parsed date, trying
+// inputFormat before the known extractor formats. Items whose date can't be
+// parsed fall back to tweet ID order after the dated ones.
+func SortByDateNewest(items []MediaItem, inputFormat string) {
+	dates := make([]time.Time, len(items))
+	for i, item := range items {
+		if t, ok := parseTweetDate(item.Date, inputFormat); ok {
+			dates[i] = t
+		}
+	}
+	sort.Stable(newestFirst{items: items, dates: dates})
+}
+
+// newestFirst sorts items and their parsed dates together
+type newestFirst struct {
+	items []MediaItem
+	dates []time.Time
+}
+
+func (s newestFirst) Len() int { return len(s.items) }
+
+func (s newestFirst) Swap(i, j int) {
+	s.items[i], s.items[j] = s.items[j], s.items[i]
+	s.dates[i], s.dates[j] = s.dates[j], s.dates[i]
+}
+
+func (s newestFirst) Less(i, j int) bool {
+	if !s.dates[i].Equal(s.dates[j]) {
+		return s.dates[i].After(s.dates[j])
+	}
+	return s.items[i].TweetID > s.items[j].TweetID
+}
+
+// LimitNewest keeps the limit newest items (0 = no limit) and returns them
+// sorted newest first with the number trimmed
+func LimitNewest(items []MediaItem, limit int, inputFormat string) ([]MediaItem, int) {
+	if limit <= 0 || len(items) <= limit {
+		return items, 0
+	}
+
+	sorted := make([]MediaItem, len(items))
+	copy(sorted, items)
+	SortByDateNewest(sorted, inputFormat)
+	return sorted[:limit], len(items) - limit
+}
+
+// skipError marks an item that was intentionally not downloaded
+type skipError struct {
+	reason string
+}
+
+func (e *skipError) Error() string {
+	return e.reason
+}
+
 // ProgressCallback is a function type for progress updates
 type ProgressCallback func(current, total int)
 
+// partialDownloadSuffix marks a file that is still being written
+const partialDownloadSuffix = ".downloading"
+
 // downloadTask represents a single download task
 type downloadTask struct {
 	item       MediaItem
@@ -71,16 +241,33 @@ type downloadTask struct {
 
 // DownloadMediaWithMetadata downloads media files with proper naming and categorization
 func DownloadMediaWithMetadata(items []MediaItem, outputDir string, username string) (downloaded int, failed int, err error) {
-	return DownloadMediaWithMetadataProgress(items, outputDir, username, nil, nil)
+	return DownloadMediaWithMetadataProgress(items, outputDir, username, DownloadOptions{}, nil, nil)
 }
 
 // DownloadMediaWithMetadataProgress downloads media files with progress callback and cancellation support
-func DownloadMediaWithMetadataProgress(items []MediaItem, outputDir string, username string, progress ProgressCallback, ctx context.Context) (downloaded int, failed int, err error) {
+func DownloadMediaWithMetadataProgress(items []MediaItem, outputDir string, username string, opts DownloadOptions, progress ProgressCallback, ctx context.Context) (downloaded int, failed int, err error) {
 	if ctx == nil {
 		ctx = context.Background()
 	}
 
+	if opts.FilenameTemplate != "" {
+		if err := ValidateFilenameTemplate(opts.FilenameTemplate); err != nil {
+			return 0, len(items), err
+		}
+	}
+	if err := ValidateOverwritePolicy(opts.OverwritePolicy); err != nil {
+		return 0, len(items), err
+	}
+	if err := ValidateImageFormat(opts.ImageFormat); err != nil {
+		return 0, len(items), err
+	}
+	policy := opts.OverwritePolicy
+	if policy == "" {
+		policy = OverwriteSkip
+	}
+
 	// Create base output directory
+	username = SanitizeFilename(username)
 	baseDir := filepath.Join(outputDir, username)
 	if err := os.MkdirAll(baseDir, 0755); err != nil {
 		return 0, len(items), fmt.Errorf("failed to create output directory: %v", err)
@@ -96,6 +283,15 @@ func DownloadMediaWithMetadataProgress(items []MediaItem, outputDir string, user
 	tasks := make([]downloadTask, 0, total)
 
 	for i, item := range items {
+		// Rewrite image URLs before the extension is taken from them
+		if item.Type == "photo" && (opts.FullResolution || opts.ImageFormat != "") {
+			name := ""
+			if opts.FullResolution {
+				name = "orig"
+			}
+			item.URL = mediaURLVariant(item.URL, opts.ImageFormat, name)
+		}
+
 		// Determine subfolder based on type
 		var subfolder string
 		switch item.Type {
@@ -116,7 +312,7 @@ func DownloadMediaWithMetadataProgress(items []MediaItem, outputDir string, user
 		}
 
 		// Format timestamp from date
-		timestamp := formatTimestamp(item.Date)
+		timestamp := formatTimestamp(item.Date, opts.DateInputFormat, opts.DateOutputFormat)
 
 		// Get file extension
 		ext := getExtension(item.URL, item.Type)
@@ -125,8 +321,15 @@ func DownloadMediaWithMetadataProgress(items []MediaItem, outputDir string, user
 		tweetMediaCount[item.TweetID]++
 		mediaIndex := tweetMediaCount[item.TweetID]
 
-		// Create filename: {username}_{timestamp}_{tweet_id}_{index}.{ext}
-		filename := fmt.Sprintf("%s_%s_%d_%02d%s", username, timestamp, item.TweetID, mediaIndex, ext)
+		// Create filename from the template, by default {username}_{timestamp}_{tweet_id}_{index}.{ext}
+		fields := filenameFields{
+			Username: username,
+			Date:     timestamp,
+			TweetID:  item.TweetID,
+			Index:    mediaIndex,
+			Type:     item.Type,
+		}
+		filename := SanitizeFilename(buildMediaFilename(opts.FilenameTemplate, fields, ext, opts.MaxFilenameLength))
 		outputPath := filepath.Join(typeDir, filename)
 
 		tasks = append(tasks, downloadTask{
@@ -139,9 +342,53 @@ func DownloadMediaWithMetadataProgress(items []MediaItem, outputDir string, user
 	// Counters for parallel downloads
 	var downloadedCount int64
 	var failedCount int64
+	var skippedCount int64
 	var completedCount int64
 
-	// Create worker pool
+	// Items that ended up on disk, for the archive index
+	var archiveMu sync.Mutex
+	archiveEntries := []ArchiveIndexEntry{}
+	recordArchived := func(task downloadTask) {
+		if !opts.ArchiveIndex {
+			return
+		}
+		relPath, _ := filepath.Rel(baseDir, task.outputPath)
+		// Hashed outside the lock; a file that can't be read is indexed without one
+		sum, size, _ := fileSHA256(task.outputPath)
+		archiveMu.Lock()
+		archiveEntries = append(archiveEntries, ArchiveIndexEntry{
+			TweetID: task.item.TweetID,
+			URL:     task.item.URL,
+			Type:    task.item.Type,
+			Date:    task.item.Date,
+			File:    filepath.ToSlash(relPath),
+			Size:    size,
+			SHA256:  sum,
+		})
+		archiveMu.Unlock()
+	}
+
+	// Stop before filling the disk; in-flight files finish, nothing new starts
+	checkEvery := int64(opts.FreeSpaceCheckEvery)
+	if checkEvery <= 0 {
+		checkEvery = DefaultFreeSpaceCheckEvery
+	}
+	var lowDiskOnce sync.Once
+	lowDisk := make(chan struct{})
+	checkFreeSpace := func() {
+		if opts.MinFreeBytes <= 0 {
+			return
+		}
+		// An unreadable volume is left to fail on write instead
+		if free, err := freeDiskSpace(baseDir); err == nil && free < uint64(opts.MinFreeBytes) {
+			lowDiskOnce.Do(func() { close(lowDisk) })
+		}
+	}
+	checkFreeSpace()
+
+	// Create worker pool, throttled per host
+	limiter := newHostLimiter(opts.HostConcurrency)
+	breaker := newCircuitBreaker(opts.CircuitBreaker)
 	taskChan := make(chan downloadTask, len(tasks))
 	var wg sync.WaitGroup
 
@@ -155,25 +402,60 @@ func DownloadMediaWithMetadataProgress(items []MediaItem, outputDir string, user
 		wg.Add(1)
 		go func() {
 			defer wg.Done()
-			client := &http.Client{
-				Timeout: 60 * time.Second,
+			client := newHTTPClient(60 * time.Second)
+			if len(opts.Cookies) > 0 {
+				client.Jar = mediaCookieJar(opts.Cookies)
 			}
 
 			for task := range taskChan {
+				// Block here while paused; in-flight files finish normally
+				if err := opts.Pause.Wait(ctx); err != nil {
+					return
+				}
+
 				// Check for cancellation
 				select {
 				case <-ctx.Done():
 					return
+				case <-lowDisk:
+					return
 				default:
 				}
 
-				// Skip if file already exists
+				// Apply the overwrite policy if the file already exists
+				exists := false
 				if _, err := os.Stat(task.outputPath); err == nil {
+					exists = true
+					if opts.OnExisting != nil {
+						opts.OnExisting(task.item, policy)
+					}
+					if policy == OverwriteRename {
+						task.outputPath = uniqueOutputPath(task.outputPath, opts.MaxFilenameLength)
+					}
+				}
+
+				if exists && policy == OverwriteSkip {
 					atomic.AddInt64(&downloadedCount, 1)
-				} else if err := downloadFileWithContext(ctx, client, task.item.URL, task.outputPath); err != nil {
-					atomic.AddInt64(&failedCount, 1)
+					recordArchived(task)
+				} else if err := downloadTrackedTask(ctx, client, task, opts, limiter, breaker); err != nil {
+					var skip *skipError
+					if errors.As(err, &skip) {
+						atomic.AddInt64(&skippedCount, 1)
+						if opts.OnSkipped != nil {
+							opts.OnSkipped(task.item, skip.reason)
+						}
+					} else {
+						atomic.AddInt64(&failedCount, 1)
+						if opts.OnFailure != nil {
+							opts.OnFailure(task.item, err)
+						}
+					}
 				} else {
 					atomic.AddInt64(&downloadedCount, 1)
+					recordArchived(task)
+				}
+				if opts.OnDone != nil && ctx.Err() == nil {
+					opts.OnDone(task.item)
 				}
 
 				// Update progress
@@ -181,6 +463,9 @@ func DownloadMediaWithMetadataProgress(items []MediaItem, outputDir string, user
 				if progress != nil {
 					progress(int(completed), total)
 				}
+				if completed%checkEvery == 0 {
+					checkFreeSpace()
+				}
 			}
 		}()
 	}
@@ -191,6 +476,9 @@ func DownloadMediaWithMetadataProgress(items []MediaItem, outputDir string, user
 		case <-ctx.Done():
 			close(taskChan)
 			wg.Wait()
+			if opts.ArchiveIndex {
+				appendArchiveIndex(baseDir, archiveEntries)
+			}
 			return int(downloadedCount), int(failedCount) + (total - int(completedCount)), ctx.Err()
 		case taskChan <- task:
 		}
@@ -200,15 +488,108 @@ func DownloadMediaWithMetadataProgress(items []MediaItem, outputDir string, user
 	// Wait for all workers to finish
 	wg.Wait()
 
+	if opts.ArchiveIndex {
+		appendArchiveIndex(baseDir, archiveEntries)
+	}
+
+	// Workers stop taking tasks once cancelled, so the rest were never attempted
+	if err := ctx.Err(); err != nil {
+		return int(downloadedCount), int(failedCount) + (total - int(completedCount)), err
+	}
+
+	select {
+	case <-lowDisk:
+		return int(downloadedCount), int(failedCount) + (total - int(completedCount)), ErrLowDiskSpace
+	default:
+	}
+
+	if opts.GenerateGallery {
+		GenerateGallery(baseDir)
+	}
+
+	// Every item was attempted or failed fast, so the failures are complete
+	if breaker.isTripped() {
+		return int(downloadedCount), int(failedCount), ErrCDNUnreachable
+	}
+
 	return int(downloadedCount), int(failedCount), nil
 }
 
+// downloadTrackedTask downloads a task within its host's concurrency limit while
+// marking its output path as in use
+func downloadTrackedTask(ctx context.Context, client *http.Client, task downloadTask, opts DownloadOptions, limiter *hostLimiter, breaker *circuitBreaker) error {
+	release, err := limiter.acquire(ctx, task.item.URL)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	markDownloading(task.outputPath)
+	defer unmarkDownloading(task.outputPath)
+	attempts, err := withDownloadRetries(ctx, opts.Retries, opts.MaxRetryAfter, breaker, func() error {
+		return downloadTaskFile(ctx, client, task, opts)
+	})
+
+	// Items skipped by a filter weren't really attempted
+	var skip *skipError
+	if opts.OnAttempts != nil && attempts > 0 && !errors.As(err, &skip) {
+		opts.OnAttempts(task.item, attempts, err)
+	}
+	return err
+}
+
+// downloadTaskFile downloads a single task, applying the per-item filters
+func downloadTaskFile(ctx context.Context, client *http.Client, task downloadTask, opts DownloadOptions) error {
+	if reason := checkMediaHost(task.item.URL, opts); reason != "" {
+		return &skipError{reason: reason}
+	}
+
+	// Resolution filter applies to images only
+	checkResolution := task.item.Type == "photo" && (opts.MinWidth > 0 || opts.MinHeight > 0)
+	if checkResolution {
+		if width, height, ok := probeImageSize(ctx, client, task.item.URL); ok {
+			if !meetsMinResolution(width, height, opts.MinWidth, opts.MinHeight) {
+				return &skipError{reason: fmt.Sprintf("image is %dx%d, below minimum resolution", width, height)}
+			}
+			checkResolution = false
+		}
+	}
+
+	if err := downloadFileCounted(ctx, client, task.item.URL, task.outputPath, opts.Throughput); err != nil {
+		return err
+	}
+
+	// The header probe was inconclusive, so check the downloaded file instead
+	if checkResolution {
+		if width, height, ok := readImageFileSize(task.outputPath); ok && !meetsMinResolution(width, height, opts.MinWidth, opts.MinHeight) {
+			os.Remove(task.outputPath)
+			return &skipError{reason: fmt.Sprintf("image is %dx%d, below minimum resolution", width, height)}
+		}
+	}
+
+	// Metadata is best effort and never fails the download
+	if opts.EmbedMetadata {
+		embedTweetMetadata(task.outputPath, task.item)
+	}
+	if opts.WriteAltText && task.item.AltText != "" {
+		writeMediaSidecar(task.outputPath, task.item, tweetSourceURL(task.item))
+	}
+
+	return nil
+}
+
 // downloadFileWithContext downloads a single file with context support for cancellation
 func downloadFileWithContext(ctx context.Context, client *http.Client, url, outputPath string) error {
+	return downloadFileCounted(ctx, client, url, outputPath, nil)
+}
+
+// downloadFileCounted downloads a single file, reporting written bytes to meter if set
+func downloadFileCounted(ctx context.Context, client *http.Client, url, outputPath string, meter *ThroughputMeter) error {
 	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
 		return err
 	}
+	setAcceptEncoding(req)
 
 	resp, err := client.Do(req)
 	if err != nil {
@@ -217,37 +598,108 @@ func downloadFileWithContext(ctx context.Context, client *http.Client, url, outp
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("bad status: %s", resp.Status)
+		return newStatusError(resp)
 	}
 
-	out, err := os.Create(outputPath)
+	if err := decodeResponseBody(resp); err != nil {
+		return err
+	}
+
+	// Write to a temporary name so an interrupted transfer never leaves a
+	// truncated file under the final name
+	tempPath := outputPath + partialDownloadSuffix
+	out, err := os.Create(tempPath)
 	if err != nil {
 		return err
 	}
-	defer out.Close()
 
-	_, err = io.Copy(out, resp.Body)
-	return err
+	counter := &countingWriter{w: out, meter: meter}
+	_, err = io.Copy(counter, resp.Body)
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil && resp.ContentLength >= 0 && counter.n != resp.ContentLength {
+		err = fmt.Errorf("incomplete download: got %d of %d bytes", counter.n, resp.ContentLength)
+	}
+	if err != nil {
+		os.Remove(tempPath)
+		meter.attemptFailed(counter.n)
+		return err
+	}
+
+	if err := os.Rename(tempPath, outputPath); err != nil {
+		os.Remove(tempPath)
+		meter.attemptFailed(counter.n)
+		return err
+	}
+
+	meter.fileDone(counter.n)
+	return nil
 }
 
-// formatTimestamp converts date string to timestamp format
-func formatTimestamp(dateStr string) string {
-	// Try parsing various date formats
-	formats := []string{
-		"2006-01-02T15:04:05.000Z",
-		"2006-01-02T15:04:05Z",
-		"2006-01-02 15:04:05",
-		"Mon Jan 02 15:04:05 -0700 2006",
+// DefaultDateOutputFormat is the default layout for dates in filenames
+const DefaultDateOutputFormat = "20060102_150405"
+
+// tweetDateFormats are the date layouts the extractor is known to produce
+var tweetDateFormats = []string{
+	"2006-01-02 15:04:05",
+	"2006-01-02T15:04:05.000Z",
+	"2006-01-02T15:04:05Z",
+	time.RFC3339,
+	"Mon Jan 02 15:04:05 -0700 2006",
+	"2006-01-02",
+}
+
+// parseTweetDate parses a tweet date, trying the custom layout first and then
+// the known extractor formats
+func parseTweetDate(dateStr string, customFormat string) (time.Time, bool) {
+	dateStr = strings.TrimSpace(dateStr)
+	if customFormat != "" {
+		if t, err := time.Parse(customFormat, dateStr); err == nil {
+			return t, true
+		}
 	}
 
-	for _, format := range formats {
+	for _, format := range tweetDateFormats {
 		if t, err := time.Parse(format, dateStr); err == nil {
-			return t.Format("20060102_150405")
+			return t, true
 		}
 	}
 
+	return time.Time{}, false
+}
+
+// formatTimestamp converts date string to timestamp format using the given
+// input and output layouts; unparseable dates fall back to a sanitized raw string
+func formatTimestamp(dateStr string, inputFormat string, outputFormat string) string {
+	if outputFormat == "" {
+		outputFormat = DefaultDateOutputFormat
+	}
+
+	if t, ok := parseTweetDate(dateStr, inputFormat); ok {
+		return sanitizeDateComponent(t.Format(outputFormat))
+	}
+
+	if raw := sanitizeDateComponent(dateStr); raw != "" {
+		return raw
+	}
+
 	// Fallback: use current timestamp
-	return time.Now().Format("20060102_150405")
+	return time.Now().Format(outputFormat)
+}
+
+// sanitizeDateComponent replaces characters that are not safe in filenames
+func sanitizeDateComponent(value string) string {
+	var b strings.Builder
+	for _, r := range strings.TrimSpace(value) {
+		switch {
+		case r >= '0' && r <= '9', r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r == '-', r == '_':
+			b.WriteRune(r)
+		default:
+			b.WriteRune('_')
+		}
+	}
+	return b.String()
 }
 
 // getExtension determines file extension from URL and type
@@ -324,22 +776,5 @@ func extractFilename(mediaURL string) string {
 
 // downloadFile downloads a single file from URL
 func downloadFile(client *http.Client, url, outputPath string) error {
-	resp, err := client.Get(url)
-	if err != nil {
-		return err
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("bad status: %s", resp.Status)
-	}
-
-	out, err := os.Create(outputPath)
-	if err != nil {
-		return err
-	}
-	defer out.Close()
-
-	_, err = io.Copy(out, resp.Body)
-	return err
+	return downloadFileWithContext(context.Background(), client, url, outputPath)
 }
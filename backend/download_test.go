@@ -0,0 +1,157 @@
+package backend
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestCancelledDownloadKeepsRetryQueue(t *testing.T) {
+	useTempDB(t)
+
+	// The first item downloads at once; the rest hang until cancelled
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, "/ok/") {
+			w.Write([]byte("data"))
+			return
+		}
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+
+	items := []MediaItem{{URL: server.URL + "/ok/0.jpg", Type: "photo", TweetID: 1}}
+	for i := 1; i < 3*MaxConcurrentDownloads; i++ {
+		items = append(items, MediaItem{URL: fmt.Sprintf("%s/hang/%d.jpg", server.URL, i), Type: "photo", TweetID: int64(i + 1)})
+	}
+
+	// Every item starts out queued from an earlier failed run
+	outputDir := t.TempDir()
+	queued := make([]FailedItem, len(items))
+	for i, item := range items {
+		queued[i] = FailedItem{URL: item.URL, TweetID: item.TweetID, Error: "earlier failure"}
+	}
+	if err := RecordDownloadResults("user", outputDir, items, queued); err != nil {
+		t.Fatalf("RecordDownloadResults: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var mu sync.Mutex
+	var attempted []MediaItem
+	var failures []FailedItem
+	opts := DownloadOptions{
+		CircuitBreaker: -1,
+		OnDone: func(item MediaItem) {
+			mu.Lock()
+			attempted = append(attempted, item)
+			mu.Unlock()
+			cancel()
+		},
+		OnFailure: func(item MediaItem, err error) {
+			mu.Lock()
+			failures = append(failures, NewFailedItem(item, err))
+			mu.Unlock()
+		},
+	}
+
+	downloaded, failed, err := DownloadMediaWithMetadataProgress(items, outputDir, "user", opts, nil, ctx)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("err = %v, want context.Canceled", err)
+	}
+	if downloaded != 1 || downloaded+failed != len(items) {
+		t.Errorf("downloaded %d, failed %d, want 1 and %d", downloaded, failed, len(items)-1)
+	}
+	if len(attempted) != 1 || attempted[0].URL != items[0].URL {
+		t.Fatalf("attempted = %v, want only the first item", attempted)
+	}
+
+	if err := RecordDownloadRun("user", outputDir, attempted, failures, err); err != nil {
+		t.Fatalf("RecordDownloadRun: %v", err)
+	}
+	remaining, err := GetFailedDownloads()
+	if err != nil {
+		t.Fatalf("GetFailedDownloads: %v", err)
+	}
+	if len(remaining) != len(items) {
+		t.Errorf("%d items queued after a cancelled run, want %d", len(remaining), len(items))
+	}
+
+	// A run that ended otherwise only clears the items it attempted
+	if err := RecordDownloadRun("user", outputDir, attempted, nil, ErrLowDiskSpace); err != nil {
+		t.Fatalf("RecordDownloadRun: %v", err)
+	}
+	remaining, _ = GetFailedDownloads()
+	if len(remaining) != len(items)-1 {
+		t.Errorf("%d items queued, want %d", len(remaining), len(items)-1)
+	}
+	for _, entry := range remaining {
+		if entry.URL == items[0].URL {
+			t.Errorf("downloaded item %s is still queued", entry.URL)
+		}
+	}
+}
+
+func TestUniqueOutputPathKeepsMaxLength(t *testing.T) {
+	dir := t.TempDir()
+	name := strings.Repeat("a", 46) + ".jpg"
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	first := uniqueOutputPath(path, 50)
+	if got, want := filepath.Base(first), strings.Repeat("a", 44)+"_1.jpg"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if err := os.WriteFile(first, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	second := filepath.Base(uniqueOutputPath(path, 50))
+	if second != strings.Repeat("a", 44)+"_2.jpg" || len(second) > 50 {
+		t.Errorf("got %q (%d bytes), want the next suffix within 50 bytes", second, len(second))
+	}
+}
+
+func TestParseTweetDate(t *testing.T) {
+	want := time.Date(2024, 3, 5, 14, 7, 9, 0, time.UTC)
+	tests := []struct {
+		name   string
+		input  string
+		custom string
+		want   time.Time
+		ok     bool
+	}{
+		{"space separated", "2024-03-05 14:07:09", "", want, true},
+		{"milliseconds Z", "2024-03-05T14:07:09.000Z", "", want, true},
+		{"Z", "2024-03-05T14:07:09Z", "", want, true},
+		{"RFC3339 offset", "2024-03-05T16:07:09+02:00", "", want, true},
+		{"ruby style", "Tue Mar 05 14:07:09 +0000 2024", "", want, true},
+		{"date only", "2024-03-05", "", time.Date(2024, 3, 5, 0, 0, 0, 0, time.UTC), true},
+		{"surrounding spaces", "  2024-03-05 14:07:09 ", "", want, true},
+		{"custom layout", "05/03/2024 14:07", "02/01/2006 15:04", time.Date(2024, 3, 5, 14, 7, 0, 0, time.UTC), true},
+		{"custom layout falls back", "2024-03-05 14:07:09", "02/01/2006", want, true},
+		{"empty", "", "", time.Time{}, false},
+		{"garbage", "yesterday", "", time.Time{}, false},
+		{"invalid month", "2024-13-05 14:07:09", "", time.Time{}, false},
+		{"unknown layout", "05.03.2024", "", time.Time{}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := parseTweetDate(tt.input, tt.custom)
+			if ok != tt.ok || !got.Equal(tt.want) {
+				t.Errorf("parseTweetDate(%q, %q) = %v, %v, want %v, %v", tt.input, tt.custom, got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
@@ -0,0 +1,55 @@
+package backend
+
+import (
+	"fmt"
+)
+
+// engagementColumns maps a sort metric to its media table expression
+var engagementColumns = map[string]string{
+	"likes":    "likes",
+	"retweets": "retweets",
+	"replies":  "replies",
+	"total":    "COALESCE(likes, 0) + COALESCE(retweets, 0) + COALESCE(replies, 0)",
+}
+
+// GetMediaByEngagement returns an account's stored media sorted by the given
+// metric (likes, retweets, replies or total), most popular first. Entries
+// without engagement data sort last; minCount > 0 drops entries below it.
+func GetMediaByEngagement(accountID int64, metric string, minCount int) ([]TimelineEntry, error) {
+	if db == nil {
+		if err := InitDB(); err != nil {
+			return nil, err
+		}
+	}
+
+	if metric == "" {
+		metric = "likes"
+	}
+	column, ok := engagementColumns[metric]
+	if !ok {
+		return nil, fmt.Errorf("unknown engagement metric: %s", metric)
+	}
+
+	rows, err := db.Query(fmt.Sprintf(`
+		SELECT tweet_id, url, COALESCE(type, ''), COALESCE(date, ''), is_retweet, likes, retweets, replies
+		FROM media
+		WHERE account_id = ? AND COALESCE(%[1]s, 0) >= ?
+		ORDER BY (%[1]s) IS NULL, %[1]s DESC, tweet_id DESC
+	`, column), accountID, minCount)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := []TimelineEntry{}
+	for rows.Next() {
+		var entry TimelineEntry
+		var tweetID int64
+		if err := rows.Scan(&tweetID, &entry.URL, &entry.Type, &entry.Date, &entry.IsRetweet, &entry.Likes, &entry.Retweets, &entry.Replies); err != nil {
+			return nil, err
+		}
+		entry.TweetID = TweetIDString(tweetID)
+		entries = append(entries, entry)
+	}
+	return entries, rows.Err()
+}
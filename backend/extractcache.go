@@ -0,0 +1,146 @@
+package backend
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultExtractCacheTTL is how long an extraction result is reused when no
+// TTL has been set: caching is opt-in, so re-fetching always gets fresh
+// timelines until the user sets a TTL
+const DefaultExtractCacheTTL = time.Duration(0)
+
+// initExtractCacheTable creates the table of cached extraction results,
+// keyed by a hash of the extractor arguments
+func initExtractCacheTable() error {
+	if _, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS extract_cache (
+			key TEXT PRIMARY KEY,
+			response_json BLOB NOT NULL,
+			fetched_at INTEGER NOT NULL
+		)
+	`); err != nil {
+		return err
+	}
+	_, err := db.Exec("CREATE INDEX IF NOT EXISTS idx_extract_cache_fetched ON extract_cache (fetched_at)")
+	return err
+}
+
+// GetExtractCacheTTL returns how long extraction results are reused; 0 means
+// the cache is off
+func GetExtractCacheTTL() time.Duration {
+	value, err := GetSetting(SettingExtractCacheTTL)
+	if err != nil || value == "" {
+		return DefaultExtractCacheTTL
+	}
+	seconds, err := strconv.Atoi(value)
+	if err != nil || seconds < 0 {
+		return DefaultExtractCacheTTL
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+// SetExtractCacheTTL sets how long extraction results are reused, in whole
+// seconds; 0 turns the cache off
+func SetExtractCacheTTL(ttl time.Duration) error {
+	if ttl < 0 {
+		return fmt.Errorf("cache TTL cannot be negative")
+	}
+	return SetSetting(SettingExtractCacheTTL, strconv.Itoa(int(ttl/time.Second)))
+}
+
+// extractCacheKey hashes the extractor arguments. The auth token is part of
+// them, so accounts with different access never share results, and only its
+// hash is stored.
+func extractCacheKey(args []string) string {
+	sum := sha256.Sum256([]byte(strings.Join(args, "\x00")))
+	return hex.EncodeToString(sum[:])
+}
+
+// cachedExtraction returns the cached response for key if it is younger than ttl
+func cachedExtraction(key string, ttl time.Duration) (*TwitterResponse, bool) {
+	if ttl <= 0 || !IsDatabaseAvailable() {
+		return nil, false
+	}
+
+	var data []byte
+	err := db.QueryRow("SELECT response_json FROM extract_cache WHERE key = ? AND fetched_at > ?",
+		key, time.Now().Add(-ttl).Unix()).Scan(&data)
+	if err != nil {
+		return nil, false
+	}
+
+	jsonStr, err := decompressResponseJSON(data)
+	if err != nil {
+		return nil, false
+	}
+	var response TwitterResponse
+	if err := json.Unmarshal([]byte(jsonStr), &response); err != nil {
+		return nil, false
+	}
+	return &response, true
+}
+
+// cacheExtraction stores a response under key and prunes expired entries.
+// Failures only mean the next call extracts again, so they are ignored.
+func cacheExtraction(key string, response *TwitterResponse, ttl time.Duration) {
+	if ttl <= 0 || !IsDatabaseAvailable() {
+		return
+	}
+
+	data, err := json.Marshal(response)
+	if err != nil {
+		return
+	}
+	compressed, err := compressResponseJSON(string(data))
+	if err != nil {
+		return
+	}
+
+	db.Exec(`
+		INSERT INTO extract_cache (key, response_json, fetched_at) VALUES (?, ?, ?)
+		ON CONFLICT(key) DO UPDATE SET response_json = excluded.response_json, fetched_at = excluded.fetched_at
+	`, key, compressed, time.Now().Unix())
+	pruneExtractCache(ttl)
+}
+
+// pruneExtractCache deletes entries older than ttl
+func pruneExtractCache(ttl time.Duration) (int64, error) {
+	result, err := db.Exec("DELETE FROM extract_cache WHERE fetched_at <= ?", time.Now().Add(-ttl).Unix())
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// PruneExtractCache deletes the cached extraction results that have expired,
+// or all of them when the cache is off, returning how many were removed
+func PruneExtractCache() (int64, error) {
+	if db == nil {
+		if err := InitDB(); err != nil {
+			return 0, err
+		}
+	}
+	return pruneExtractCache(GetExtractCacheTTL())
+}
+
+// ClearExtractCache deletes every cached extraction result, returning how
+// many were removed
+func ClearExtractCache() (int64, error) {
+	if db == nil {
+		if err := InitDB(); err != nil {
+			return 0, err
+		}
+	}
+
+	result, err := db.Exec("DELETE FROM extract_cache")
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,161 @@
+package backend
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"sync"
+	"time"
+)
+
+// Extractor exit codes, kept in sync with helper/cli.py
+const (
+	extractorExitAuthFailed   = 2
+	extractorExitNetworkError = 3
+	extractorExitNotFound     = 4
+	extractorExitRateLimited  = 5
+	extractorExitProtected    = 6
+)
+
+// Typed extractor failures, matched with errors.Is
+var (
+	ErrExtractorAuthFailed  = errors.New("authentication failed: check that your auth token is valid and not expired")
+	ErrExtractorNetwork     = errors.New("network error: check your internet connection or proxy settings")
+	ErrExtractorNotFound    = errors.New("account not found: check the username")
+	ErrExtractorRateLimited = errors.New("rate limited by X: wait a few minutes or use another auth token")
+)
+
+// extractorExitErrors maps known exit codes to their typed errors
+var extractorExitErrors = map[int]error{
+	extractorExitAuthFailed:   ErrExtractorAuthFailed,
+	extractorExitNetworkError: ErrExtractorNetwork,
+	extractorExitNotFound:     ErrExtractorNotFound,
+	extractorExitRateLimited:  ErrExtractorRateLimited,
+	extractorExitProtected:    ErrAccountProtected,
+}
+
+// ExtractorError is a failed extractor run with a known cause. Error() gives
+// the user-facing message; Output keeps the raw output for debugging.
+type ExtractorError struct {
+	Kind          error
+	ExitCode      int
+	Output        string
+	RetryAfter    time.Duration // server-requested wait, uncapped
+	HasRetryAfter bool
+}
+
+func (e *ExtractorError) Error() string {
+	return e.Kind.Error()
+}
+
+func (e *ExtractorError) Unwrap() error {
+	return e.Kind
+}
+
+// extractorRunError maps a failed run to an ExtractorError by its exit code,
+// or to the generic error carrying the raw output for unknown codes
+func extractorRunError(err error, output []byte) error {
+	code := -1
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		code = exitErr.ExitCode()
+	}
+	return extractorExitCodeError(code, err, output)
+}
+
+// extractorExitCodeError maps the exit code of a failed run (-1 = none) the
+// same way as extractorRunError
+func extractorExitCodeError(code int, err error, output []byte) error {
+	if kind, ok := extractorExitErrors[code]; ok {
+		extractorErr := &ExtractorError{Kind: kind, ExitCode: code, Output: string(output)}
+		extractorErr.RetryAfter, extractorErr.HasRetryAfter = extractorRetryAfter(output)
+		return extractorErr
+	}
+	return fmt.Errorf("failed to execute metadata-extractor: %v, output: %s", err, string(output))
+}
+
+// extractorRetryAfter reads the retry_after field of the extractor's JSON error
+func extractorRetryAfter(output []byte) (time.Duration, bool) {
+	start := bytes.IndexByte(output, '{')
+	if start < 0 {
+		return 0, false
+	}
+	var result struct {
+		RetryAfter string `json:"retry_after"`
+	}
+	if err := json.NewDecoder(bytes.NewReader(output[start:])).Decode(&result); err != nil {
+		return 0, false
+	}
+	return parseRetryAfter(result.RetryAfter, time.Now())
+}
+
+// Extractor runs the metadata extractor with command-line arguments and
+// returns its parsed response, keeping at most maxEntries timeline entries
+// (0 = no limit)
+type Extractor interface {
+	Extract(args []string, maxEntries int) (*TwitterResponse, error)
+}
+
+// subprocessExtractor runs the embedded metadata-extractor binary
+type subprocessExtractor struct{}
+
+// Extract runs the embedded binary and parses its output. The session's
+// cookies are kept for SessionCookies.
+func (subprocessExtractor) Extract(args []string, maxEntries int) (*TwitterResponse, error) {
+	if cookieFile, err := os.CreateTemp("", "xmd-cookies-*.json"); err == nil {
+		cookiePath := cookieFile.Name()
+		cookieFile.Close()
+		defer os.Remove(cookiePath)
+		defer readSessionCookies(cookiePath)
+
+		// Global flags go before the subcommand
+		args = append([]string{"--cookies-out", cookiePath}, args...)
+	}
+
+	output, err := execMetadataExtractor(args)
+	if err != nil {
+		return nil, extractorRunError(err, output)
+	}
+	return parseExtractorOutput(output, maxEntries)
+}
+
+var (
+	extractorMu     sync.RWMutex
+	activeExtractor Extractor = subprocessExtractor{}
+)
+
+// SetExtractor replaces the extractor used by ExtractTimeline, ExtractDateRange
+// and ExtractTweet; nil restores the embedded binary
+func SetExtractor(e Extractor) {
+	extractorMu.Lock()
+	defer extractorMu.Unlock()
+	if e == nil {
+		e = subprocessExtractor{}
+	}
+	activeExtractor = e
+}
+
+// currentExtractor returns the extractor in use
+func currentExtractor() Extractor {
+	extractorMu.RLock()
+	defer extractorMu.RUnlock()
+	return activeExtractor
+}
+
+// parseExtractorOutput finds the JSON in the extractor output, skipping any
+// info messages, and parses it
+func parseExtractorOutput(output []byte, maxEntries int) (*TwitterResponse, error) {
+	jsonStr := extractJSON(string(output))
+	if jsonStr == "" {
+		return nil, fmt.Errorf("no JSON found in output: %s", string(output))
+	}
+
+	response, err := parseTwitterResponse(jsonStr, maxEntries)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse response: %v, output: %s", err, jsonStr)
+	}
+	return response, nil
+}
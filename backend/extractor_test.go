@@ -0,0 +1,247 @@
+package backend
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeExtractor is an Extractor that records its arguments and parses canned
+// output instead of running the binary
+type fakeExtractor struct {
+	Output   string // extractor stdout, parsed like the real output
+	ExitCode int    // non-zero simulates a failed run with this exit code
+	Err      error  // simulates a run that couldn't start, when ExitCode is 0
+
+	mu    sync.Mutex
+	calls [][]string
+}
+
+// Extract records args and fails or parses Output like the subprocess would
+func (f *fakeExtractor) Extract(args []string, maxEntries int) (*TwitterResponse, error) {
+	f.mu.Lock()
+	f.calls = append(f.calls, append([]string(nil), args...))
+	f.mu.Unlock()
+
+	if f.ExitCode != 0 {
+		return nil, extractorExitCodeError(f.ExitCode, fmt.Errorf("exit status %d", f.ExitCode), []byte(f.Output))
+	}
+	if f.Err != nil {
+		return nil, extractorRunError(f.Err, []byte(f.Output))
+	}
+	return parseExtractorOutput([]byte(f.Output), maxEntries)
+}
+
+// Calls returns the arguments of every Extract call so far
+func (f *fakeExtractor) Calls() [][]string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([][]string(nil), f.calls...)
+}
+
+// useExtractor installs e for the duration of the test
+func useExtractor(t *testing.T, e Extractor) {
+	t.Helper()
+	SetExtractor(e)
+	t.Cleanup(func() { SetExtractor(nil) })
+}
+
+const fakeTimelineOutput = `Fetching timeline...
+{
+	"account_info": {"name": "someone", "nick": "Some One"},
+	"total_urls": 3,
+	"timeline": [
+		{"url": "https://pbs.twimg.com/media/a.jpg", "date": "2024-01-01T00:00:00", "tweet_id": 3, "type": "photo"},
+		{"url": "https://video.twimg.com/ext_tw_video/b.mp4", "date": "2024-01-01T00:00:00", "tweet_id": 2},
+		{"url": "https://pbs.twimg.com/media/c.jpg", "date": "2024-01-01T00:00:00", "tweet_id": 1, "type": "photo"}
+	],
+	"metadata": {"new_entries": 3, "page": 0, "batch_size": 0, "has_more": false}
+}`
+
+// argValue returns the value following flag in args
+func argValue(args []string, flag string) (string, bool) {
+	for i, arg := range args {
+		if arg == flag && i+1 < len(args) {
+			return args[i+1], true
+		}
+	}
+	return "", false
+}
+
+func TestExtractTimelineArgs(t *testing.T) {
+	tests := []struct {
+		name    string
+		req     TimelineRequest
+		target  string
+		present []string
+		values  map[string]string
+	}{
+		{
+			name:    "profile URL",
+			req:     TimelineRequest{Username: "https://x.com/someone/media", AuthToken: "tok"},
+			target:  "someone",
+			present: []string{"--no-retweets"},
+			values:  map[string]string{"--token": "tok", "--batch-size": "0"},
+		},
+		{
+			name:    "user ID takes precedence",
+			req:     TimelineRequest{Username: "someone", UserID: "id:12345", Retweets: true},
+			target:  "id:12345",
+			present: []string{"--retweets"},
+		},
+		{
+			name:   "paging and filters",
+			req:    TimelineRequest{Username: "@someone", TimelineType: "tweets", BatchSize: 50, Page: 2, MediaType: "video", Cursor: "abc", SinceID: 99},
+			target: "someone",
+			values: map[string]string{
+				"--timeline-type": "tweets",
+				"--batch-size":    "50",
+				"--page":          "2",
+				"--media-type":    "video",
+				"--cursor":        "abc",
+				"--since-id":      "99",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := &fakeExtractor{Output: fakeTimelineOutput}
+			useExtractor(t, fake)
+
+			tt.req.NoCache = true
+			if _, err := ExtractTimeline(tt.req); err != nil {
+				t.Fatalf("ExtractTimeline: %v", err)
+			}
+			calls := fake.Calls()
+			if len(calls) != 1 {
+				t.Fatalf("%d extractor calls, want 1", len(calls))
+			}
+			args := calls[0]
+
+			if target, ok := argValue(args, "timeline"); !ok || target != tt.target {
+				t.Errorf("target = %q, want %q (args %v)", target, tt.target, args)
+			}
+			for _, flag := range tt.present {
+				if !strings.Contains(" "+strings.Join(args, " ")+" ", " "+flag+" ") {
+					t.Errorf("missing %s in %v", flag, args)
+				}
+			}
+			for flag, want := range tt.values {
+				if got, _ := argValue(args, flag); got != want {
+					t.Errorf("%s = %q, want %q", flag, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestExtractTimelineResponse(t *testing.T) {
+	useExtractor(t, &fakeExtractor{Output: fakeTimelineOutput})
+
+	response, err := ExtractTimeline(TimelineRequest{Username: "someone", NoCache: true})
+	if err != nil {
+		t.Fatalf("ExtractTimeline: %v", err)
+	}
+	if response.AccountInfo.Name != "someone" || len(response.Timeline) != 3 {
+		t.Fatalf("got %+v", response)
+	}
+	if response.Timeline[1].Type != "video" {
+		t.Errorf("untyped mp4 entry has type %q, want video", response.Timeline[1].Type)
+	}
+
+	response, err = ExtractTimeline(TimelineRequest{Username: "someone", MaxEntries: 2, NoCache: true})
+	if err != nil {
+		t.Fatalf("ExtractTimeline: %v", err)
+	}
+	if len(response.Timeline) != 2 || !response.Metadata.HasMore || response.TotalURLs != 2 {
+		t.Errorf("MaxEntries 2: %d entries, has_more %v, total %d", len(response.Timeline), response.Metadata.HasMore, response.TotalURLs)
+	}
+}
+
+func TestExtractTimelineErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		fake *fakeExtractor
+		want error
+	}{
+		{"auth failed", &fakeExtractor{ExitCode: extractorExitAuthFailed}, ErrExtractorAuthFailed},
+		{"network", &fakeExtractor{ExitCode: extractorExitNetworkError}, ErrExtractorNetwork},
+		{"not found", &fakeExtractor{ExitCode: extractorExitNotFound}, ErrExtractorNotFound},
+		{"rate limited", &fakeExtractor{ExitCode: extractorExitRateLimited}, ErrExtractorRateLimited},
+		{"protected", &fakeExtractor{ExitCode: extractorExitProtected}, ErrAccountProtected},
+		{"protected by output", &fakeExtractor{ExitCode: 1, Output: accountProtectedMarker}, ErrAccountProtected},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useExtractor(t, tt.fake)
+			_, err := ExtractTimeline(TimelineRequest{Username: "someone", NoCache: true})
+			if !errors.Is(err, tt.want) {
+				t.Errorf("err = %v, want %v", err, tt.want)
+			}
+		})
+	}
+
+	t.Run("unknown exit code", func(t *testing.T) {
+		useExtractor(t, &fakeExtractor{ExitCode: 1, Output: "boom"})
+		_, err := ExtractTimeline(TimelineRequest{Username: "someone", NoCache: true})
+		var extractorErr *ExtractorError
+		if err == nil || errors.As(err, &extractorErr) || !strings.Contains(err.Error(), "boom") {
+			t.Errorf("err = %v, want the generic error with the output", err)
+		}
+	})
+
+	t.Run("retry after", func(t *testing.T) {
+		useExtractor(t, &fakeExtractor{ExitCode: extractorExitRateLimited, Output: `{"error": "rate limited", "retry_after": "120"}`})
+		_, err := ExtractTimeline(TimelineRequest{Username: "someone", NoCache: true})
+		var extractorErr *ExtractorError
+		if !errors.As(err, &extractorErr) || !extractorErr.HasRetryAfter || extractorErr.RetryAfter != 2*time.Minute {
+			t.Errorf("err = %#v, want a 2m retry after", err)
+		}
+	})
+}
+
+func TestExtractTimelineCache(t *testing.T) {
+	useTempDB(t)
+
+	fake := &fakeExtractor{Output: fakeTimelineOutput}
+	useExtractor(t, fake)
+	extract := func(req TimelineRequest) {
+		t.Helper()
+		if _, err := ExtractTimeline(req); err != nil {
+			t.Fatalf("ExtractTimeline: %v", err)
+		}
+	}
+
+	// Off by default
+	extract(TimelineRequest{Username: "someone"})
+	extract(TimelineRequest{Username: "someone"})
+	if n := len(fake.Calls()); n != 2 {
+		t.Fatalf("%d extractor calls with the cache off, want 2", n)
+	}
+
+	if err := SetExtractCacheTTL(time.Minute); err != nil {
+		t.Fatalf("SetExtractCacheTTL: %v", err)
+	}
+	extract(TimelineRequest{Username: "cached"})
+	extract(TimelineRequest{Username: "cached"})
+	if n := len(fake.Calls()); n != 3 {
+		t.Errorf("%d extractor calls, want the second one cached", n)
+	}
+	extract(TimelineRequest{Username: "cached", NoCache: true})
+	if n := len(fake.Calls()); n != 4 {
+		t.Errorf("%d extractor calls, want NoCache to run the extractor", n)
+	}
+
+	// The fake captures no session, so a token's results are never served
+	// from the cache and the next real run captures its cookies
+	extract(TimelineRequest{Username: "cached", AuthToken: "tok"})
+	extract(TimelineRequest{Username: "cached", AuthToken: "tok"})
+	if n := len(fake.Calls()); n != 6 {
+		t.Errorf("%d extractor calls, want both token calls to run the extractor", n)
+	}
+}
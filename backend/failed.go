@@ -0,0 +1,143 @@
+package backend
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// FailedDownload represents a media item queued for a later retry
+type FailedDownload struct {
+	ID         int64  `json:"id"`
+	URL        string `json:"url"`
+	TweetID    int64  `json:"tweet_id"`
+	Username   string `json:"username"`
+	OutputDir  string `json:"output_dir"`
+	Type       string `json:"type"`
+	Date       string `json:"date"`
+	Error      string `json:"error"`
+	RetryCount int    `json:"retry_count"`
+	UpdatedAt  string `json:"updated_at"` // RFC3339 in UTC
+}
+
+// MediaItem returns the item to pass back to the downloader
+func (f FailedDownload) MediaItem() MediaItem {
+	return MediaItem{
+		URL:      f.URL,
+		Date:     f.Date,
+		TweetID:  f.TweetID,
+		Type:     f.Type,
+		Username: f.Username,
+	}
+}
+
+// initFailedDownloadsTable creates the persistent retry queue
+func initFailedDownloadsTable() error {
+	_, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS failed_downloads (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			url TEXT NOT NULL,
+			tweet_id INTEGER,
+			username TEXT,
+			output_dir TEXT NOT NULL,
+			type TEXT,
+			date TEXT,
+			error TEXT,
+			retry_count INTEGER DEFAULT 0,
+			updated_at DATETIME,
+			UNIQUE (url, output_dir)
+		)
+	`)
+	return err
+}
+
+// RecordDownloadRun updates the retry queue with the outcome of a download
+// run that returned runErr. attempted must hold only the items the run
+// actually handled (see DownloadOptions.OnDone). A cancelled run changes
+// nothing, since it was cut short and its failures may just be the abort.
+func RecordDownloadRun(username, outputDir string, attempted []MediaItem, failures []FailedItem, runErr error) error {
+	if errors.Is(runErr, context.Canceled) || errors.Is(runErr, context.DeadlineExceeded) {
+		return nil
+	}
+	return RecordDownloadResults(username, outputDir, attempted, failures)
+}
+
+// RecordDownloadResults updates the retry queue after a download run: items
+// that were attempted and did not fail are removed, and failed items are
+// added or have their retry counter and last error updated
+func RecordDownloadResults(username, outputDir string, attempted []MediaItem, failures []FailedItem) error {
+	if db == nil {
+		if err := InitDB(); err != nil {
+			return err
+		}
+	}
+
+	failedByURL := make(map[string]FailedItem, len(failures))
+	for _, failure := range failures {
+		failedByURL[failure.URL] = failure
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	now := time.Now().UTC()
+	for _, item := range attempted {
+		failure, failed := failedByURL[item.URL]
+		if !failed {
+			if _, err := tx.Exec("DELETE FROM failed_downloads WHERE url = ? AND output_dir = ?", item.URL, outputDir); err != nil {
+				return err
+			}
+			continue
+		}
+
+		_, err := tx.Exec(`
+			INSERT INTO failed_downloads (url, tweet_id, username, output_dir, type, date, error, retry_count, updated_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?, 0, ?)
+			ON CONFLICT(url, output_dir) DO UPDATE SET
+				error = excluded.error,
+				retry_count = failed_downloads.retry_count + 1,
+				updated_at = excluded.updated_at
+		`, item.URL, item.TweetID, username, outputDir, item.Type, item.Date, failure.Error, now)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// GetFailedDownloads returns every queued failed download, oldest first
+func GetFailedDownloads() ([]FailedDownload, error) {
+	if db == nil {
+		if err := InitDB(); err != nil {
+			return nil, err
+		}
+	}
+
+	rows, err := db.Query(`
+		SELECT id, url, tweet_id, username, output_dir, type, date, error, retry_count, updated_at
+		FROM failed_downloads
+		ORDER BY id
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	failures := []FailedDownload{}
+	for rows.Next() {
+		var failure FailedDownload
+		var updatedAt time.Time
+		if err := rows.Scan(&failure.ID, &failure.URL, &failure.TweetID, &failure.Username, &failure.OutputDir,
+			&failure.Type, &failure.Date, &failure.Error, &failure.RetryCount, &updatedAt); err != nil {
+			continue
+		}
+		failure.UpdatedAt = updatedAt.UTC().Format(time.RFC3339)
+		failures = append(failures, failure)
+	}
+
+	return failures, nil
+}
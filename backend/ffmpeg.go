@@ -3,14 +3,22 @@ package backend
 import (
 	"archive/tar"
 	"archive/zip"
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"io"
 	"net/http"
 	"os"
 	"os/exec"
+	"path"
 	"path/filepath"
 	"runtime"
+	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 
 	"github.com/ulikunitz/xz"
 )
@@ -24,8 +32,7 @@ const (
 
 // GetFFmpegPath returns the path to ffmpeg binary
 func GetFFmpegPath() string {
-	homeDir, _ := os.UserHomeDir()
-	baseDir := filepath.Join(homeDir, ".twitterxmediabatchdownloader")
+	baseDir := GetDataDir()
 
 	switch runtime.GOOS {
 	case "windows":
@@ -44,21 +51,111 @@ func IsFFmpegInstalled() bool {
 	return false
 }
 
-// DownloadFFmpeg downloads ffmpeg binary for current platform
-func DownloadFFmpeg(progressCallback func(downloaded, total int64)) error {
-	var downloadURL string
+// FFmpegInfo describes the installed ffmpeg build
+type FFmpegInfo struct {
+	Installed bool     `json:"installed"`
+	Path      string   `json:"path"`
+	Version   string   `json:"version"`
+	Encoders  []string `json:"encoders"`
+}
+
+// HasEncoder reports whether the ffmpeg build includes the named encoder
+func (info *FFmpegInfo) HasEncoder(name string) bool {
+	for _, encoder := range info.Encoders {
+		if encoder == name {
+			return true
+		}
+	}
+	return false
+}
+
+// GetFFmpegInfo runs the installed ffmpeg to read its version and the
+// encoders it was built with. Installed is false when there is no binary.
+func GetFFmpegInfo() (*FFmpegInfo, error) {
+	info := &FFmpegInfo{Path: GetFFmpegPath()}
+	if !IsFFmpegInstalled() {
+		return info, nil
+	}
+	info.Installed = true
+
+	cmd := exec.Command(info.Path, "-hide_banner", "-version")
+	hideWindow(cmd)
+	output, err := cmd.Output()
+	if err != nil {
+		return info, fmt.Errorf("failed to run ffmpeg: %v", err)
+	}
+	// ffmpeg version 6.1.1-static https://... Copyright (c) ...
+	if fields := strings.Fields(string(output)); len(fields) >= 3 && fields[1] == "version" {
+		info.Version = fields[2]
+	}
+
+	cmd = exec.Command(info.Path, "-hide_banner", "-encoders")
+	hideWindow(cmd)
+	output, err = cmd.Output()
+	if err != nil {
+		return info, fmt.Errorf("failed to list ffmpeg encoders: %v", err)
+	}
+	info.Encoders = parseFFmpegEncoders(string(output))
+
+	return info, nil
+}
+
+// parseFFmpegEncoders reads encoder names from ffmpeg -encoders output, whose
+// rows follow a "------" separator as " V....D libx264  description"
+func parseFFmpegEncoders(output string) []string {
+	encoders := []string{}
+	listing := false
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		if !listing {
+			listing = len(fields) == 1 && strings.HasPrefix(fields[0], "---")
+			continue
+		}
+		if len(fields) >= 2 {
+			encoders = append(encoders, fields[1])
+		}
+	}
+	return encoders
+}
+
+// ffmpegChecksumsURL lists the SHA-256 of every BtbN build archive. The macOS
+// build has no published checksum and is only checked against its length.
+const ffmpegChecksumsURL = "https://github.com/BtbN/FFmpeg-Builds/releases/download/latest/checksums.sha256"
+
+// DownloadFFmpeg downloads ffmpeg binary for current platform, reporting bytes
+// downloaded of the archive's total (-1 when unknown) to progressCallback. The
+// archive is verified before extraction, and a cancelled or failed download
+// leaves nothing behind.
+func DownloadFFmpeg(ctx context.Context, progressCallback func(downloaded, total int64)) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	var downloadURL, checksumURL string
 
 	switch runtime.GOOS {
 	case "windows":
 		downloadURL = ffmpegWindowsURL
+		checksumURL = ffmpegChecksumsURL
 	case "linux":
 		downloadURL = ffmpegLinuxURL
+		checksumURL = ffmpegChecksumsURL
 	case "darwin":
 		downloadURL = ffmpegMacOSURL
 	default:
 		return fmt.Errorf("unsupported platform: %s", runtime.GOOS)
 	}
 
+	// Fetch the expected checksum first so a bad checksum list fails fast
+	var expectedSum string
+	if checksumURL != "" {
+		var err error
+		expectedSum, err = fetchFFmpegChecksum(ctx, checksumURL, path.Base(downloadURL))
+		if err != nil {
+			return err
+		}
+	}
+
 	// Create temp file for download
 	tempFile, err := os.CreateTemp("", "ffmpeg-*")
 	if err != nil {
@@ -69,19 +166,27 @@ func DownloadFFmpeg(progressCallback func(downloaded, total int64)) error {
 	defer tempFile.Close()
 
 	// Download file
-	resp, err := http.Get(downloadURL)
+	req, err := http.NewRequestWithContext(ctx, "GET", downloadURL, nil)
 	if err != nil {
 		return fmt.Errorf("failed to download ffmpeg: %v", err)
 	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return fmt.Errorf("failed to download ffmpeg: %v", err)
+	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
 		return fmt.Errorf("failed to download ffmpeg: status %d", resp.StatusCode)
 	}
 
-	// Copy with progress
+	// Copy with progress, hashing as we go
 	total := resp.ContentLength
 	var downloaded int64
+	hash := sha256.New()
 	buf := make([]byte, 32*1024)
 
 	for {
@@ -91,6 +196,7 @@ func DownloadFFmpeg(progressCallback func(downloaded, total int64)) error {
 			if writeErr != nil {
 				return fmt.Errorf("failed to write temp file: %v", writeErr)
 			}
+			hash.Write(buf[:n])
 			downloaded += int64(n)
 			if progressCallback != nil {
 				progressCallback(downloaded, total)
@@ -100,28 +206,83 @@ func DownloadFFmpeg(progressCallback func(downloaded, total int64)) error {
 			break
 		}
 		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
 			return fmt.Errorf("failed to download: %v", err)
 		}
 	}
 	tempFile.Close()
 
-	// Extract ffmpeg binary
+	if total >= 0 && downloaded != total {
+		return fmt.Errorf("ffmpeg download incomplete: got %d of %d bytes", downloaded, total)
+	}
+	if expectedSum != "" {
+		if sum := hex.EncodeToString(hash.Sum(nil)); sum != expectedSum {
+			return fmt.Errorf("ffmpeg archive checksum mismatch: expected %s, got %s", expectedSum, sum)
+		}
+	}
+
+	// Extract ffmpeg binary next to its final path and move it into place only
+	// once complete, so a failed extraction never looks installed
 	ffmpegPath := GetFFmpegPath()
 	baseDir := filepath.Dir(ffmpegPath)
 	if err := os.MkdirAll(baseDir, 0755); err != nil {
 		return fmt.Errorf("failed to create directory: %v", err)
 	}
 
+	partialPath := ffmpegPath + partialDownloadSuffix
+	defer os.Remove(partialPath)
+
 	switch runtime.GOOS {
 	case "windows", "darwin":
-		return extractFromZip(tempPath, ffmpegPath)
+		err = extractFromZip(tempPath, partialPath)
 	case "linux":
-		return extractFromTarXz(tempPath, ffmpegPath)
+		err = extractFromTarXz(tempPath, partialPath)
+	}
+	if err != nil {
+		return err
 	}
 
+	if err := os.Rename(partialPath, ffmpegPath); err != nil {
+		return fmt.Errorf("failed to install ffmpeg: %v", err)
+	}
 	return nil
 }
 
+// fetchFFmpegChecksum returns the SHA-256 listed for archiveName in a
+// sha256sum-style checksum file
+func fetchFFmpegChecksum(ctx context.Context, checksumURL, archiveName string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", checksumURL, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch ffmpeg checksums: %v", err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		if ctx.Err() != nil {
+			return "", ctx.Err()
+		}
+		return "", fmt.Errorf("failed to fetch ffmpeg checksums: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("failed to fetch ffmpeg checksums: status %d", resp.StatusCode)
+	}
+
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 2 && strings.TrimPrefix(fields[1], "*") == archiveName {
+			return strings.ToLower(fields[0]), nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return "", fmt.Errorf("failed to read ffmpeg checksums: %v", err)
+	}
+	return "", fmt.Errorf("no checksum published for %s", archiveName)
+}
+
 // extractFromZip extracts ffmpeg from zip archive
 func extractFromZip(zipPath, destPath string) error {
 	r, err := zip.OpenReader(zipPath)
@@ -209,6 +370,12 @@ func extractFromTarXz(tarXzPath, destPath string) error {
 
 // ConvertMP4ToGIF converts an MP4 file to GIF using ffmpeg (simple conversion)
 func ConvertMP4ToGIF(inputPath, outputPath string, fps int, width int) error {
+	return ConvertMP4ToGIFContext(context.Background(), inputPath, outputPath, fps, width)
+}
+
+// ConvertMP4ToGIFContext converts an MP4 file to GIF, killing ffmpeg and
+// removing the partial output if ctx is cancelled
+func ConvertMP4ToGIFContext(ctx context.Context, inputPath, outputPath string, fps int, width int) error {
 	ffmpegPath := GetFFmpegPath()
 
 	if !IsFFmpegInstalled() {
@@ -223,34 +390,156 @@ func ConvertMP4ToGIF(inputPath, outputPath string, fps int, width int) error {
 		outputPath,
 	}
 
-	cmd := exec.Command(ffmpegPath, args...)
+	cmd := exec.CommandContext(ctx, ffmpegPath, args...)
 	hideWindow(cmd) // Hide console window on Windows
 	output, err := cmd.CombinedOutput()
+	if ctx.Err() != nil {
+		os.Remove(outputPath)
+		return ctx.Err()
+	}
 	if err != nil {
+		// A partial GIF would look already converted on the next run
+		os.Remove(outputPath)
 		return fmt.Errorf("ffmpeg error: %v, output: %s", err, string(output))
 	}
 
 	return nil
 }
 
+// Output formats of the GIF conversion
+const (
+	GIFFormatGIF  = "gif"
+	GIFFormatWebP = "webp" // animated WebP, needs an ffmpeg built with libwebp
+)
+
+// defaultWebPQuality is used when no WebP quality is given
+const defaultWebPQuality = 75
+
+// ValidateGIFConvertOptions checks the output format ("" = gif) and that
+// quality is within 0-100 (0 = default); quality only applies to WebP
+func ValidateGIFConvertOptions(format string, quality int) error {
+	switch strings.ToLower(format) {
+	case "", GIFFormatGIF, GIFFormatWebP:
+	default:
+		return fmt.Errorf("unsupported output format: %s (use gif or webp)", format)
+	}
+	if quality < 0 || quality > 100 {
+		return fmt.Errorf("quality must be between 0 and 100")
+	}
+	return nil
+}
+
+// webpEncoder returns the libwebp encoder to use, preferring libwebp_anim
+func webpEncoder() (string, error) {
+	info, err := GetFFmpegInfo()
+	if err != nil {
+		return "", err
+	}
+	switch {
+	case info.HasEncoder("libwebp_anim"):
+		return "libwebp_anim", nil
+	case info.HasEncoder("libwebp"):
+		return "libwebp", nil
+	}
+	return "", fmt.Errorf("ffmpeg was built without libwebp, animated WebP is not available")
+}
+
+// ConvertMP4ToWebP converts an MP4 file to an animated WebP with encoder at
+// quality (0-100), resampled to fps and scaled to width when they are set.
+// Cancelling ctx kills ffmpeg and removes the partial output.
+func ConvertMP4ToWebP(ctx context.Context, inputPath, outputPath, encoder string, fps, width, quality int) error {
+	var filters []string
+	if fps > 0 {
+		filters = append(filters, fmt.Sprintf("fps=%d", fps))
+	}
+	if width > 0 {
+		filters = append(filters, fmt.Sprintf("scale=%d:-2:flags=lanczos", width))
+	}
+
+	args := []string{"-i", inputPath}
+	if len(filters) > 0 {
+		args = append(args, "-vf", strings.Join(filters, ","))
+	}
+	args = append(args,
+		"-c:v", encoder,
+		"-quality", strconv.Itoa(quality),
+		"-loop", "0", // Infinite loop
+		"-an",
+		"-y", outputPath,
+	)
+
+	cmd := exec.CommandContext(ctx, GetFFmpegPath(), args...)
+	hideWindow(cmd) // Hide console window on Windows
+	output, err := cmd.CombinedOutput()
+	if ctx.Err() != nil {
+		os.Remove(outputPath)
+		return ctx.Err()
+	}
+	if err != nil {
+		os.Remove(outputPath)
+		return fmt.Errorf("ffmpeg error: %v, output: %s", err, string(output))
+	}
+	return nil
+}
+
 // ConvertGIFsInFolder converts all MP4 files in gifs folder to actual GIF format
-func ConvertGIFsInFolder(folderPath string, fps int, width int, deleteOriginal bool) (converted int, failed int, err error) {
+func ConvertGIFsInFolder(folderPath string, fps int, width int, deleteOriginal bool, force bool) (converted int, skipped int, failed int, err error) {
+	return ConvertGIFsInFolderProgress(context.Background(), folderPath, GIFFormatGIF, fps, width, 0, deleteOriginal, force, 0, nil)
+}
+
+// hasConvertedOutput reports whether the MP4 at inputPath already has a
+// non-empty file with the output extension ext next to it
+func hasConvertedOutput(inputPath, ext string) bool {
+	info, err := os.Stat(strings.TrimSuffix(inputPath, filepath.Ext(inputPath)) + ext)
+	return err == nil && info.Mode().IsRegular() && info.Size() > 0
+}
+
+// ConvertGIFsInFolderProgress converts the MP4 files in the gifs folder to
+// GIF or, with format "webp", animated WebP at quality (0 = 75), using up to
+// workers ffmpeg processes at once (0 = runtime.NumCPU()). MP4s that already
+// have a non-empty output are skipped unless force is set, so re-running only
+// converts what's new. Cancelling ctx kills running conversions and stops new
+// ones from starting.
+func ConvertGIFsInFolderProgress(ctx context.Context, folderPath string, format string, fps int, width int, quality int, deleteOriginal bool, force bool, workers int, progress ProgressCallback) (converted int, skipped int, failed int, err error) {
 	if !IsFFmpegInstalled() {
-		return 0, 0, fmt.Errorf("ffmpeg not installed")
+		return 0, 0, 0, fmt.Errorf("ffmpeg not installed")
+	}
+	if err := ValidateGIFConvertOptions(format, quality); err != nil {
+		return 0, 0, 0, err
+	}
+
+	format = strings.ToLower(format)
+	if format == "" {
+		format = GIFFormatGIF
+	}
+	var encoder string
+	if format == GIFFormatWebP {
+		if encoder, err = webpEncoder(); err != nil {
+			return 0, 0, 0, err
+		}
+		if quality == 0 {
+			quality = defaultWebPQuality
+		}
+	}
+	ext := "." + format
+
+	if ctx == nil {
+		ctx = context.Background()
 	}
 
 	// Clean the path to handle cross-platform path separators
 	cleanPath := filepath.Clean(folderPath)
 	gifsFolder := filepath.Join(cleanPath, "gifs")
 	if _, err := os.Stat(gifsFolder); os.IsNotExist(err) {
-		return 0, 0, fmt.Errorf("gifs folder not found: %s", gifsFolder)
+		return 0, 0, 0, fmt.Errorf("gifs folder not found: %s", gifsFolder)
 	}
 
 	files, err := os.ReadDir(gifsFolder)
 	if err != nil {
-		return 0, 0, fmt.Errorf("failed to read gifs folder: %v", err)
+		return 0, 0, 0, fmt.Errorf("failed to read gifs folder: %v", err)
 	}
 
+	inputs := []string{}
 	for _, file := range files {
 		if file.IsDir() {
 			continue
@@ -260,21 +549,74 @@ func ConvertGIFsInFolder(folderPath string, fps int, width int, deleteOriginal b
 		if !strings.HasSuffix(strings.ToLower(name), ".mp4") {
 			continue
 		}
-
 		inputPath := filepath.Join(gifsFolder, name)
-		outputPath := filepath.Join(gifsFolder, strings.TrimSuffix(name, filepath.Ext(name))+".gif")
-
-		if err := ConvertMP4ToGIF(inputPath, outputPath, fps, width); err != nil {
-			failed++
+		if !force && hasConvertedOutput(inputPath, ext) {
+			skipped++
 			continue
 		}
+		inputs = append(inputs, inputPath)
+	}
 
-		if deleteOriginal {
-			os.Remove(inputPath)
-		}
+	total := len(inputs)
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	if workers > total {
+		workers = total
+	}
 
-		converted++
+	var convertedCount int64
+	var failedCount int64
+	var completedCount int64
+
+	inputChan := make(chan string)
+	var wg sync.WaitGroup
+
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for inputPath := range inputChan {
+				outputPath := strings.TrimSuffix(inputPath, filepath.Ext(inputPath)) + ext
+
+				var err error
+				if format == GIFFormatWebP {
+					err = ConvertMP4ToWebP(ctx, inputPath, outputPath, encoder, fps, width, quality)
+				} else {
+					err = ConvertMP4ToGIFContext(ctx, inputPath, outputPath, fps, width)
+				}
+				if err != nil {
+					if ctx.Err() != nil {
+						return
+					}
+					atomic.AddInt64(&failedCount, 1)
+				} else {
+					if deleteOriginal {
+						os.Remove(inputPath)
+					}
+					atomic.AddInt64(&convertedCount, 1)
+				}
+
+				completed := atomic.AddInt64(&completedCount, 1)
+				if progress != nil {
+					progress(int(completed), total)
+				}
+			}
+		}()
+	}
+
+	// Feed inputs until done or cancelled
+feed:
+	for _, inputPath := range inputs {
+		select {
+		case <-ctx.Done():
+			break feed
+		case inputChan <- inputPath:
+		}
 	}
+	close(inputChan)
+	wg.Wait()
 
-	return converted, failed, nil
+	return int(convertedCount), skipped, int(failedCount), ctx.Err()
 }
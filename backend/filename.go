@@ -0,0 +1,99 @@
+package backend
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	// DefaultMaxFilenameLength is the default maximum filename length in bytes
+	DefaultMaxFilenameLength = 200
+)
+
+// windowsReservedNames are device names that cannot be used as filenames on Windows
+var windowsReservedNames = map[string]bool{
+	"CON": true, "PRN": true, "AUX": true, "NUL": true,
+	"COM1": true, "COM2": true, "COM3": true, "COM4": true, "COM5": true,
+	"COM6": true, "COM7": true, "COM8": true, "COM9": true,
+	"LPT1": true, "LPT2": true, "LPT3": true, "LPT4": true, "LPT5": true,
+	"LPT6": true, "LPT7": true, "LPT8": true, "LPT9": true,
+}
+
+// SanitizeFilename makes a derived file or folder name safe on all platforms by
+// replacing invalid characters, trimming trailing dots/spaces and escaping
+// Windows reserved device names
+func SanitizeFilename(name string) string {
+	var b strings.Builder
+	for _, r := range name {
+		switch {
+		case r < 0x20:
+			b.WriteRune('_')
+		case strings.ContainsRune(`<>:"/\|?*`, r):
+			b.WriteRune('_')
+		default:
+			b.WriteRune(r)
+		}
+	}
+
+	sanitized := strings.TrimRight(b.String(), ". ")
+	if sanitized == "" {
+		return "_"
+	}
+
+	// Reserved names are invalid even with an extension (e.g. CON.txt)
+	base := sanitized
+	if idx := strings.Index(base, "."); idx >= 0 {
+		base = base[:idx]
+	}
+	if windowsReservedNames[strings.ToUpper(strings.TrimRight(base, " "))] {
+		sanitized = "_" + sanitized
+	}
+
+	return sanitized
+}
+
+// buildMediaFilename renders the filename template (DefaultFilenameTemplate when
+// empty) and appends ext, truncating the username and then the date so the
+// result fits in maxLength bytes while always keeping the tweet ID, index and extension
+func buildMediaFilename(template string, fields filenameFields, ext string, maxLength int) string {
+	if maxLength <= 0 {
+		maxLength = DefaultMaxFilenameLength
+	}
+	if template == "" {
+		template = DefaultFilenameTemplate
+	}
+
+	filename := renderFilenameTemplate(template, fields) + ext
+	if len(filename) <= maxLength {
+		return filename
+	}
+
+	// Shorten the username first, then the date
+	for _, value := range []*string{&fields.Username, &fields.Date} {
+		overflow := len(filename) - maxLength
+		keep := len(*value) - overflow
+		if keep < 0 {
+			keep = 0
+		}
+		*value = truncateUTF8(*value, keep)
+
+		filename = renderFilenameTemplate(template, fields) + ext
+		if len(filename) <= maxLength {
+			return filename
+		}
+	}
+
+	return fmt.Sprintf("%d_%02d%s", fields.TweetID, fields.Index, ext)
+}
+
+// truncateUTF8 shortens s to at most maxBytes bytes without splitting a character
+func truncateUTF8(s string, maxBytes int) string {
+	if len(s) <= maxBytes {
+		return s
+	}
+	for maxBytes > 0 && !utf8.RuneStart(s[maxBytes]) {
+		maxBytes--
+	}
+	return s[:maxBytes]
+}
@@ -0,0 +1,120 @@
+package backend
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestBuildMediaFilename(t *testing.T) {
+	fields := filenameFields{Username: "user", Date: "20240101_120000", TweetID: 1234567890123456789, Index: 1, Type: "photo"}
+	withUsername := func(username string) filenameFields {
+		f := fields
+		f.Username = username
+		return f
+	}
+
+	tests := []struct {
+		name      string
+		template  string
+		fields    filenameFields
+		maxLength int
+		want      string
+	}{
+		{
+			name:      "fits",
+			fields:    fields,
+			maxLength: 200,
+			want:      "user_20240101_120000_1234567890123456789_01.jpg",
+		},
+		{
+			name:   "default max length",
+			fields: withUsername(strings.Repeat("a", 300)),
+			want:   strings.Repeat("a", 200-43) + "_20240101_120000_1234567890123456789_01.jpg",
+		},
+		{
+			name:      "username truncated",
+			fields:    withUsername(strings.Repeat("a", 100)),
+			maxLength: 60,
+			want:      strings.Repeat("a", 17) + "_20240101_120000_1234567890123456789_01.jpg",
+		},
+		{
+			name:      "multibyte username truncated on a character boundary",
+			fields:    withUsername(strings.Repeat("é", 50)),
+			maxLength: 60,
+			want:      strings.Repeat("é", 8) + "_20240101_120000_1234567890123456789_01.jpg",
+		},
+		{
+			name:      "date truncated after the username",
+			fields:    withUsername(strings.Repeat("a", 100)),
+			maxLength: 35,
+			want:      "_2024010_1234567890123456789_01.jpg",
+		},
+		{
+			name:      "tweet ID and index fallback",
+			fields:    withUsername(strings.Repeat("a", 100)),
+			maxLength: 26,
+			want:      "1234567890123456789_01.jpg",
+		},
+		{
+			name:      "custom template",
+			template:  "{tweet_id}-{username}",
+			fields:    withUsername(strings.Repeat("b", 50)),
+			maxLength: 30,
+			want:      "1234567890123456789-" + strings.Repeat("b", 6) + ".jpg",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := buildMediaFilename(tt.template, tt.fields, ".jpg", tt.maxLength)
+			if got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+			maxLength := tt.maxLength
+			if maxLength <= 0 {
+				maxLength = DefaultMaxFilenameLength
+			}
+			if len(got) > maxLength {
+				t.Errorf("%q is %d bytes, over %d", got, len(got), maxLength)
+			}
+			if !utf8.ValidString(got) {
+				t.Errorf("%q is not valid UTF-8", got)
+			}
+		})
+	}
+}
+
+func TestSanitizeFilename(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"photo.jpg", "photo.jpg"},
+		{"", "_"},
+		{"...", "_"},
+		{"   ", "_"},
+		{"CON", "_CON"},
+		{"con", "_con"},
+		{"CON.txt", "_CON.txt"},
+		{"nul.tar.gz", "_nul.tar.gz"},
+		{"LPT1", "_LPT1"},
+		{"COM9.jpg", "_COM9.jpg"},
+		{"CON .txt", "_CON .txt"},
+		{"CONSOLE.txt", "CONSOLE.txt"},
+		{"COM10", "COM10"},
+		{"name.", "name"},
+		{"name. . ", "name"},
+		{"name ", "name"},
+		{"a\x00b\x1fc\td", "a_b_c_d"},
+		{"line\nbreak", "line_break"},
+		{`a<b>c:d"e/f\g|h?i*j`, "a_b_c_d_e_f_g_h_i_j"},
+		{"日本語.png", "日本語.png"},
+	}
+
+	for _, tt := range tests {
+		if got := SanitizeFilename(tt.input); got != tt.want {
+			t.Errorf("SanitizeFilename(%q) = %q, want %q", tt.input, got, tt.want)
+		}
+	}
+}
@@ -2,7 +2,11 @@ package backend
 
 import (
 	"context"
+	"fmt"
+	"net/url"
+	"os"
 	"os/exec"
+	"path/filepath"
 	"runtime"
 
 	wailsRuntime "github.com/wailsapp/wails/v2/pkg/runtime"
@@ -26,6 +30,48 @@ func OpenFolderInExplorer(path string) error {
 	return cmd.Start()
 }
 
+// RevealFile opens the folder containing path with the file selected. Linux
+// file managers are asked over D-Bus; when that isn't available, or the file
+// no longer exists, the containing folder is opened instead.
+func RevealFile(path string) error {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return err
+	}
+	folder := filepath.Dir(absPath)
+	if _, err := os.Stat(absPath); err != nil {
+		if _, err := os.Stat(folder); err != nil {
+			return fmt.Errorf("file not found: %s", absPath)
+		}
+		return OpenFolderInExplorer(folder)
+	}
+
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "windows":
+		cmd = exec.Command("explorer", "/select,"+absPath)
+	case "darwin": // macOS
+		cmd = exec.Command("open", "-R", absPath)
+	default:
+		fileURL := (&url.URL{Scheme: "file", Path: filepath.ToSlash(absPath)}).String()
+		cmd = exec.Command("dbus-send", "--session", "--print-reply", "--reply-timeout=2000",
+			"--dest=org.freedesktop.FileManager1", "--type=method_call",
+			"/org/freedesktop/FileManager1", "org.freedesktop.FileManager1.ShowItems",
+			"array:string:"+fileURL, "string:")
+		// No file manager implements the interface; just open the folder
+		if err := cmd.Run(); err != nil {
+			return OpenFolderInExplorer(folder)
+		}
+		return nil
+	}
+
+	hideWindow(cmd) // Hide console window on Windows
+	if err := cmd.Start(); err != nil {
+		return OpenFolderInExplorer(folder)
+	}
+	return nil
+}
+
 func SelectFolderDialog(ctx context.Context, defaultPath string) (string, error) {
 	// If defaultPath is empty, use default download path
 	if defaultPath == "" {
@@ -0,0 +1,287 @@
+package backend
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"strings"
+	"time"
+)
+
+// Default random pause between pages of a full-timeline extraction, so
+// pages aren't requested back to back
+const (
+	DefaultPageDelayMin = 500 * time.Millisecond
+	DefaultPageDelayMax = 1500 * time.Millisecond
+)
+
+// ExtractionProgress is the saved position of an interrupted full-timeline extraction
+type ExtractionProgress struct {
+	Page      int             `json:"page"`
+	Cursor    string          `json:"cursor"`
+	BatchSize int             `json:"batch_size,omitempty"` // page size in use, for adaptive runs
+	Timeline  []TimelineEntry `json:"timeline"`
+	Account   AccountInfo     `json:"account_info"`
+	UpdatedAt string          `json:"updated_at"` // RFC3339 in UTC
+}
+
+// PageProgressCallback is called after each extracted page with the page size used
+type PageProgressCallback func(page int, pageEntries int, totalEntries int, batchSize int)
+
+// PageWaitCallback is called before pausing ahead of the next page
+type PageWaitCallback func(nextPage int, delay time.Duration)
+
+// pageDelay picks a random pause within the request's delay range
+func pageDelay(req TimelineRequest) time.Duration {
+	if req.PageDelayMin < 0 {
+		return 0
+	}
+	shortest := time.Duration(req.PageDelayMin) * time.Millisecond
+	longest := time.Duration(req.PageDelayMax) * time.Millisecond
+	if shortest == 0 && longest == 0 {
+		shortest, longest = DefaultPageDelayMin, DefaultPageDelayMax
+	}
+	if longest <= shortest {
+		return shortest
+	}
+	return shortest + time.Duration(rand.Int63n(int64(longest-shortest)+1))
+}
+
+// initExtractionProgressTable creates the table holding resumable extraction state
+func initExtractionProgressTable() error {
+	// Progress saved before the batch size and retweets were part of the key
+	// can't be matched to a request anymore, so the old table is dropped
+	if schema := tableSchema("extraction_progress"); schema != "" && !strings.Contains(schema, "retweets") {
+		if _, err := db.Exec("DROP TABLE extraction_progress"); err != nil {
+			return err
+		}
+	}
+
+	_, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS extraction_progress (
+			username TEXT NOT NULL,
+			timeline_type TEXT NOT NULL,
+			media_type TEXT NOT NULL,
+			batch_size INTEGER NOT NULL DEFAULT 0,
+			retweets INTEGER NOT NULL DEFAULT 0,
+			page INTEGER DEFAULT 0,
+			cursor TEXT,
+			progress_json BLOB,
+			updated_at DATETIME,
+			PRIMARY KEY (username, timeline_type, media_type, batch_size, retweets)
+		)
+	`)
+	return err
+}
+
+// extractionKey holds the columns identifying an extraction. Pages of
+// different sizes, or with and without retweets, don't line up, so a run
+// only resumes progress saved with the same ones.
+type extractionKey struct {
+	username     string
+	timelineType string
+	mediaType    string
+	batchSize    int
+	retweets     bool
+}
+
+// progressKey returns the key identifying an extraction
+func progressKey(req TimelineRequest) extractionKey {
+	target, err := timelineTarget(req)
+	if err != nil {
+		target = normalizeUsername(req.Username)
+	}
+	return extractionKey{target, req.TimelineType, req.MediaType, req.BatchSize, req.Retweets}
+}
+
+// GetExtractionProgress returns the saved progress for a request, or nil if there is none
+func GetExtractionProgress(req TimelineRequest) (*ExtractionProgress, error) {
+	if db == nil {
+		if err := InitDB(); err != nil {
+			return nil, err
+		}
+	}
+
+	key := progressKey(req)
+	var data []byte
+	err := db.QueryRow(`
+		SELECT progress_json FROM extraction_progress
+		WHERE username = ? AND timeline_type = ? AND media_type = ? AND batch_size = ? AND retweets = ?
+	`, key.username, key.timelineType, key.mediaType, key.batchSize, key.retweets).Scan(&data)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	jsonStr, err := decompressResponseJSON(data)
+	if err != nil {
+		return nil, err
+	}
+
+	var progress ExtractionProgress
+	if err := json.Unmarshal([]byte(jsonStr), &progress); err != nil {
+		return nil, err
+	}
+	return &progress, nil
+}
+
+// saveExtractionProgress stores the position and entries collected so far
+func saveExtractionProgress(req TimelineRequest, progress *ExtractionProgress) error {
+	if db == nil {
+		if err := InitDB(); err != nil {
+			return err
+		}
+	}
+
+	progress.UpdatedAt = time.Now().UTC().Format(time.RFC3339)
+	jsonData, err := json.Marshal(progress)
+	if err != nil {
+		return err
+	}
+	compressed, err := compressResponseJSON(string(jsonData))
+	if err != nil {
+		return err
+	}
+
+	key := progressKey(req)
+	_, err = db.Exec(`
+		INSERT OR REPLACE INTO extraction_progress (username, timeline_type, media_type, batch_size, retweets, page, cursor, progress_json, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, key.username, key.timelineType, key.mediaType, key.batchSize, key.retweets, progress.Page, progress.Cursor, compressed, time.Now().UTC())
+	return err
+}
+
+// ClearExtractionProgress discards the saved progress for a request
+func ClearExtractionProgress(req TimelineRequest) error {
+	if db == nil {
+		if err := InitDB(); err != nil {
+			return err
+		}
+	}
+
+	key := progressKey(req)
+	_, err := db.Exec(`
+		DELETE FROM extraction_progress
+		WHERE username = ? AND timeline_type = ? AND media_type = ? AND batch_size = ? AND retweets = ?
+	`, key.username, key.timelineType, key.mediaType, key.batchSize, key.retweets)
+	return err
+}
+
+// ExtractFullTimeline pages through an entire timeline until HasMore is false,
+// saving progress after every page. If a previous run was interrupted, it
+// resumes from the saved page/cursor with the entries collected so far. The
+// context is checked between pages; on cancellation or error the progress is
+// kept so the next call continues where this one stopped.
+//
+// With req.AdaptiveBatch the page size starts modest, grows while pages
+// succeed and halves on rate limits, within MinAdaptiveBatchSize and
+// MaxAdaptiveBatchSize; req.BatchSize 0 then still pages.
+//
+// Between pages it pauses for a random delay in req's PageDelayMin..Max range,
+// calling onWait first; cancelling ctx cuts the pause short.
+func ExtractFullTimeline(ctx context.Context, req TimelineRequest, onPage PageProgressCallback, onWait PageWaitCallback) (*TwitterResponse, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	progress, err := GetExtractionProgress(req)
+	if err != nil || progress == nil {
+		progress = &ExtractionProgress{
+			Page:     req.Page,
+			Cursor:   req.Cursor,
+			Timeline: []TimelineEntry{},
+		}
+	}
+
+	var sizer *adaptiveBatchSize
+	if req.AdaptiveBatch {
+		sizer = newAdaptiveBatchSize(req.BatchSize)
+		if progress.BatchSize > 0 {
+			sizer.size = progress.BatchSize
+		}
+		if progress.Page > 0 && progress.Cursor == "" {
+			sizer.lock()
+		}
+	}
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		pageReq := req
+		pageReq.Page = progress.Page
+		pageReq.Cursor = progress.Cursor
+		if sizer != nil {
+			pageReq.BatchSize = sizer.size
+		}
+
+		response, err := extractPageWithRetry(ctx, pageReq, DefaultPageRetries)
+		if err != nil && sizer != nil && isRateLimitError(err) && sizer.rateLimited() {
+			progress.BatchSize = sizer.size
+			saveExtractionProgress(req, progress)
+			if err := sleepContext(ctx, rateLimitWait(err)); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("page %d: %w", progress.Page, err)
+		}
+		if sizer != nil {
+			if response.Metadata.Cursor == "" {
+				sizer.lock()
+			}
+			sizer.success()
+		}
+
+		if progress.Account.Nick == "" {
+			progress.Account = response.AccountInfo
+		}
+		progress.Timeline = append(progress.Timeline, response.Timeline...)
+
+		if onPage != nil {
+			onPage(progress.Page, len(response.Timeline), len(progress.Timeline), pageReq.BatchSize)
+		}
+
+		// BatchSize 0 fetches everything in a single call
+		if !response.Metadata.HasMore || (req.BatchSize <= 0 && sizer == nil) {
+			progress.BatchSize = pageReq.BatchSize
+			break
+		}
+
+		progress.Page++
+		progress.Cursor = response.Metadata.Cursor
+		progress.BatchSize = pageReq.BatchSize
+		if sizer != nil {
+			progress.BatchSize = sizer.size
+		}
+		saveExtractionProgress(req, progress)
+
+		if delay := pageDelay(req); delay > 0 {
+			if onWait != nil {
+				onWait(progress.Page, delay)
+			}
+			if err := sleepContext(ctx, delay); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	ClearExtractionProgress(req)
+
+	return &TwitterResponse{
+		AccountInfo: progress.Account,
+		TotalURLs:   len(progress.Timeline),
+		Timeline:    progress.Timeline,
+		Metadata: ExtractMetadata{
+			NewEntries: len(progress.Timeline),
+			Page:       progress.Page,
+			BatchSize:  progress.BatchSize,
+		},
+	}, nil
+}
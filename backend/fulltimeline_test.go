@@ -0,0 +1,34 @@
+package backend
+
+import "testing"
+
+func TestExtractionProgressKey(t *testing.T) {
+	useTempDB(t)
+
+	req := TimelineRequest{Username: "someone", TimelineType: "media", BatchSize: 50}
+	if err := saveExtractionProgress(req, &ExtractionProgress{Page: 3, Cursor: "abc"}); err != nil {
+		t.Fatalf("saveExtractionProgress: %v", err)
+	}
+
+	progress, err := GetExtractionProgress(req)
+	if err != nil || progress == nil || progress.Page != 3 {
+		t.Fatalf("GetExtractionProgress = %+v, %v, want page 3", progress, err)
+	}
+
+	other := []TimelineRequest{
+		{Username: "someone", TimelineType: "media", BatchSize: 100},
+		{Username: "someone", TimelineType: "media", BatchSize: 50, Retweets: true},
+	}
+	for _, otherReq := range other {
+		if progress, err := GetExtractionProgress(otherReq); err != nil || progress != nil {
+			t.Errorf("GetExtractionProgress(%+v) = %+v, %v, want no progress", otherReq, progress, err)
+		}
+	}
+
+	if err := ClearExtractionProgress(req); err != nil {
+		t.Fatalf("ClearExtractionProgress: %v", err)
+	}
+	if progress, _ := GetExtractionProgress(req); progress != nil {
+		t.Errorf("progress kept after ClearExtractionProgress: %+v", progress)
+	}
+}
@@ -0,0 +1,120 @@
+package backend
+
+import (
+	"fmt"
+	"html/template"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// galleryFilename is the name of the generated gallery index
+const galleryFilename = "index.html"
+
+// gallerySection represents one media type section of the gallery
+type gallerySection struct {
+	Title string
+	Items []galleryItem
+}
+
+// galleryItem represents one downloaded file in the gallery
+type galleryItem struct {
+	Path    string
+	Name    string
+	IsVideo bool
+}
+
+var galleryTemplate = template.Must(template.New("gallery").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<style>
+body { margin: 0; padding: 16px; font-family: sans-serif; background: #111; color: #eee; }
+h1 { font-size: 20px; }
+h2 { font-size: 16px; margin-top: 24px; }
+.grid { display: grid; grid-template-columns: repeat(auto-fill, minmax(180px, 1fr)); gap: 8px; }
+.grid a { display: block; aspect-ratio: 1; overflow: hidden; background: #222; border-radius: 4px; }
+.grid img, .grid video { width: 100%; height: 100%; object-fit: cover; }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+{{range .Sections}}<h2>{{.Title}} ({{len .Items}})</h2>
+<div class="grid">
+{{range .Items}}<a href="{{.Path}}" title="{{.Name}}">{{if .IsVideo}}<video src="{{.Path}}" muted preload="metadata"></video>{{else}}<img src="{{.Path}}" alt="{{.Name}}" loading="lazy">{{end}}</a>
+{{end}}</div>
+{{end}}</body>
+</html>
+`))
+
+// GenerateGallery writes a self-contained index.html into an account download
+// folder that links to the downloaded media grouped by type
+func GenerateGallery(folderPath string) (string, error) {
+	cleanPath := filepath.Clean(folderPath)
+	if info, err := os.Stat(cleanPath); err != nil || !info.IsDir() {
+		return "", fmt.Errorf("folder not found: %s", cleanPath)
+	}
+
+	subfolders := []struct {
+		name  string
+		title string
+	}{
+		{"images", "Images"},
+		{"videos", "Videos"},
+		{"gifs", "GIFs"},
+		{"other", "Other"},
+	}
+
+	var sections []gallerySection
+	for _, sub := range subfolders {
+		files, err := os.ReadDir(filepath.Join(cleanPath, sub.name))
+		if err != nil {
+			continue
+		}
+
+		var items []galleryItem
+		for _, file := range files {
+			if file.IsDir() {
+				continue
+			}
+			name := file.Name()
+			ext := strings.ToLower(filepath.Ext(name))
+			items = append(items, galleryItem{
+				Path:    sub.name + "/" + name,
+				Name:    name,
+				IsVideo: ext == ".mp4" || ext == ".webm" || ext == ".mov",
+			})
+		}
+		if len(items) == 0 {
+			continue
+		}
+
+		// Filenames start with the timestamp after the username, so newest first
+		sort.Slice(items, func(i, j int) bool {
+			return items[i].Name > items[j].Name
+		})
+		sections = append(sections, gallerySection{Title: sub.title, Items: items})
+	}
+
+	outputPath := filepath.Join(cleanPath, galleryFilename)
+	out, err := os.Create(outputPath)
+	if err != nil {
+		return "", err
+	}
+	defer out.Close()
+
+	data := struct {
+		Title    string
+		Sections []gallerySection
+	}{
+		Title:    filepath.Base(cleanPath),
+		Sections: sections,
+	}
+	if err := galleryTemplate.Execute(out, data); err != nil {
+		return "", err
+	}
+
+	return outputPath, nil
+}
@@ -0,0 +1,18 @@
+package backend
+
+import "testing"
+
+// useTempDB points the database at a fresh file under a temporary home
+// directory for the duration of the test
+func useTempDB(t *testing.T) {
+	t.Helper()
+	CloseDB()
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("USERPROFILE", t.TempDir())
+	if err := InitDB(); err != nil {
+		t.Fatalf("InitDB: %v", err)
+	}
+	t.Cleanup(func() {
+		CloseDB()
+	})
+}
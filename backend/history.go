@@ -0,0 +1,142 @@
+package backend
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// DownloadHistoryRecord represents one finished download run
+type DownloadHistoryRecord struct {
+	ID          int64       `json:"id"`
+	Username    string      `json:"username"`
+	OutputDir   string      `json:"output_dir"`
+	CreatedAt   string      `json:"created_at"` // RFC3339 in UTC
+	Total       int         `json:"total"`
+	Downloaded  int         `json:"downloaded"`
+	Failed      int         `json:"failed"`
+	FailedItems []MediaItem `json:"failed_items"`
+}
+
+// initHistoryTable creates the download history table
+func initHistoryTable() error {
+	_, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS download_history (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			username TEXT,
+			output_dir TEXT,
+			created_at DATETIME,
+			total INTEGER DEFAULT 0,
+			downloaded INTEGER DEFAULT 0,
+			failed INTEGER DEFAULT 0,
+			failed_items TEXT
+		)
+	`)
+	return err
+}
+
+// SaveDownloadHistory records a finished download run and returns its ID
+func SaveDownloadHistory(record DownloadHistoryRecord) (int64, error) {
+	if db == nil {
+		if err := InitDB(); err != nil {
+			return 0, err
+		}
+	}
+
+	failedItems := record.FailedItems
+	if failedItems == nil {
+		failedItems = []MediaItem{}
+	}
+	failedJSON, err := json.Marshal(failedItems)
+	if err != nil {
+		return 0, err
+	}
+
+	result, err := db.Exec(`
+		INSERT INTO download_history (username, output_dir, created_at, total, downloaded, failed, failed_items)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+	`, record.Username, record.OutputDir, time.Now().UTC(), record.Total, record.Downloaded, record.Failed, string(failedJSON))
+	if err != nil {
+		return 0, err
+	}
+
+	return result.LastInsertId()
+}
+
+// GetDownloadHistory returns the most recent download runs (0 = all)
+func GetDownloadHistory(limit int) ([]DownloadHistoryRecord, error) {
+	if db == nil {
+		if err := InitDB(); err != nil {
+			return nil, err
+		}
+	}
+
+	if limit <= 0 {
+		limit = -1
+	}
+
+	rows, err := db.Query(`
+		SELECT id, username, output_dir, created_at, total, downloaded, failed, failed_items
+		FROM download_history
+		ORDER BY id DESC
+		LIMIT ?
+	`, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var records []DownloadHistoryRecord
+	for rows.Next() {
+		record, err := scanDownloadHistory(rows)
+		if err != nil {
+			continue
+		}
+		records = append(records, *record)
+	}
+
+	return records, nil
+}
+
+// GetDownloadHistoryByID returns a single download run
+func GetDownloadHistoryByID(id int64) (*DownloadHistoryRecord, error) {
+	if db == nil {
+		if err := InitDB(); err != nil {
+			return nil, err
+		}
+	}
+
+	row := db.QueryRow(`
+		SELECT id, username, output_dir, created_at, total, downloaded, failed, failed_items
+		FROM download_history WHERE id = ?
+	`, id)
+	return scanDownloadHistory(row)
+}
+
+// scanDownloadHistory scans a download history row
+func scanDownloadHistory(row interface{ Scan(...interface{}) error }) (*DownloadHistoryRecord, error) {
+	var record DownloadHistoryRecord
+	var createdAt time.Time
+	var failedJSON string
+	if err := row.Scan(&record.ID, &record.Username, &record.OutputDir, &createdAt, &record.Total, &record.Downloaded, &record.Failed, &failedJSON); err != nil {
+		return nil, err
+	}
+	record.CreatedAt = createdAt.UTC().Format(time.RFC3339)
+
+	if err := json.Unmarshal([]byte(failedJSON), &record.FailedItems); err != nil {
+		record.FailedItems = []MediaItem{}
+	}
+
+	return &record, nil
+}
+
+// updateHistoryOutputDir rewrites the output directory of an account's download history
+func updateHistoryOutputDir(username, oldDir, newDir string) error {
+	if db == nil {
+		if err := InitDB(); err != nil {
+			return err
+		}
+	}
+
+	_, err := db.Exec("UPDATE download_history SET output_dir = ? WHERE username = ? AND output_dir = ?", newDir, username, oldDir)
+	return err
+}
@@ -0,0 +1,68 @@
+package backend
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strconv"
+)
+
+// GetPostDownloadCommand returns the configured post-download command as
+// program followed by its fixed arguments (empty when disabled)
+func GetPostDownloadCommand() ([]string, error) {
+	value, err := GetSetting(SettingPostDownloadCommand)
+	if err != nil || value == "" {
+		return nil, err
+	}
+
+	var command []string
+	if err := json.Unmarshal([]byte(value), &command); err != nil {
+		return nil, fmt.Errorf("invalid post-download command setting: %v", err)
+	}
+	return command, nil
+}
+
+// SetPostDownloadCommand stores the post-download command; an empty slice disables it.
+// The command runs arbitrary local programs, so it is only ever set explicitly by the user.
+func SetPostDownloadCommand(command []string) error {
+	if len(command) == 0 || command[0] == "" {
+		return SetSetting(SettingPostDownloadCommand, "")
+	}
+
+	value, err := json.Marshal(command)
+	if err != nil {
+		return err
+	}
+	return SetSetting(SettingPostDownloadCommand, string(value))
+}
+
+// RunPostDownloadCommand runs the configured command after a successful download,
+// appending the output directory, username, downloaded and failed counts as
+// arguments. No shell is involved. Returns the combined output, or "" when disabled.
+func RunPostDownloadCommand(outputDir, username string, downloaded, failed int) (string, error) {
+	command, err := GetPostDownloadCommand()
+	if errors.Is(err, ErrDatabaseUnavailable) {
+		// No settings without a database, so no hook is configured
+		return "", nil
+	}
+	if err != nil || len(command) == 0 {
+		return "", err
+	}
+
+	args := append(append([]string{}, command[1:]...),
+		outputDir,
+		username,
+		strconv.Itoa(downloaded),
+		strconv.Itoa(failed),
+	)
+
+	cmd := exec.Command(command[0], args...)
+	hideWindow(cmd) // Hide console window on Windows
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return string(output), fmt.Errorf("post-download command failed: %v", err)
+	}
+
+	return string(output), nil
+}
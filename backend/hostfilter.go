@@ -0,0 +1,47 @@
+package backend
+
+import (
+	"net/url"
+	"strings"
+)
+
+// hostMatches reports whether host equals pattern or is a subdomain of it
+func hostMatches(host, pattern string) bool {
+	pattern = strings.ToLower(strings.TrimSpace(pattern))
+	if pattern == "" {
+		return false
+	}
+	return host == pattern || strings.HasSuffix(host, "."+pattern)
+}
+
+// checkMediaHost returns a skip reason if the item's URL host is not allowed.
+// Blocked hosts always win; an empty allowlist means only the Twitter media CDN.
+func checkMediaHost(rawURL string, opts DownloadOptions) string {
+	if !opts.FilterHosts {
+		return ""
+	}
+
+	parsed, err := url.Parse(rawURL)
+	if err != nil || parsed.Hostname() == "" {
+		return "invalid media URL"
+	}
+	host := strings.ToLower(parsed.Hostname())
+
+	for _, blocked := range opts.BlockedHosts {
+		if hostMatches(host, blocked) {
+			return "host is blocked: " + host
+		}
+	}
+
+	allowed := opts.AllowedHosts
+	if len(allowed) == 0 {
+		allowed = mediaHosts
+	}
+	for _, pattern := range allowed {
+		if hostMatches(host, pattern) {
+			return ""
+		}
+	}
+
+	return "host is not allowed: " + host
+}
@@ -0,0 +1,77 @@
+package backend
+
+import (
+	"context"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// DefaultHostConcurrency limits concurrent downloads per CDN host; the video CDN
+// rate-limits much sooner than the image CDN
+var DefaultHostConcurrency = map[string]int{
+	"pbs.twimg.com":   MaxConcurrentDownloads,
+	"video.twimg.com": 3,
+}
+
+// hostLimiter holds one semaphore per URL host
+type hostLimiter struct {
+	mu     sync.Mutex
+	limits map[string]int
+	sems   map[string]chan struct{}
+}
+
+// newHostLimiter creates a limiter from the defaults merged with overrides;
+// hosts without a limit are only bounded by the worker pool
+func newHostLimiter(overrides map[string]int) *hostLimiter {
+	limits := make(map[string]int, len(DefaultHostConcurrency)+len(overrides))
+	for host, limit := range DefaultHostConcurrency {
+		limits[host] = limit
+	}
+	for host, limit := range overrides {
+		limits[strings.ToLower(host)] = limit
+	}
+
+	return &hostLimiter{
+		limits: limits,
+		sems:   make(map[string]chan struct{}),
+	}
+}
+
+// semaphore returns the semaphore for a URL's host, or nil if it is unlimited
+func (l *hostLimiter) semaphore(rawURL string) chan struct{} {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return nil
+	}
+	host := strings.ToLower(parsed.Hostname())
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	limit := l.limits[host]
+	if limit <= 0 {
+		return nil
+	}
+	sem, ok := l.sems[host]
+	if !ok {
+		sem = make(chan struct{}, limit)
+		l.sems[host] = sem
+	}
+	return sem
+}
+
+// acquire waits for a slot on the URL's host and returns its release function
+func (l *hostLimiter) acquire(ctx context.Context, rawURL string) (func(), error) {
+	sem := l.semaphore(rawURL)
+	if sem == nil {
+		return func() {}, nil
+	}
+
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case sem <- struct{}{}:
+		return func() { <-sem }, nil
+	}
+}
@@ -0,0 +1,229 @@
+package backend
+
+import (
+	"compress/flate"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// TransportConfig holds the settings of the shared HTTP transport
+type TransportConfig struct {
+	ProxyURL            string `json:"proxy_url"` // empty = use environment proxy
+	MaxIdleConnsPerHost int    `json:"max_idle_conns_per_host"`
+	IdleConnTimeoutSecs int    `json:"idle_conn_timeout_secs"`
+}
+
+// DefaultTransportConfig returns the default transport settings
+func DefaultTransportConfig() TransportConfig {
+	return TransportConfig{
+		MaxIdleConnsPerHost: MaxConcurrentDownloads,
+		IdleConnTimeoutSecs: 90,
+	}
+}
+
+var (
+	transportMu     sync.RWMutex
+	sharedTransport *http.Transport
+	transportConfig = DefaultTransportConfig()
+)
+
+// newTransport builds an HTTP transport from the config
+func newTransport(cfg TransportConfig) (*http.Transport, error) {
+	proxy := http.ProxyFromEnvironment
+	if cfg.ProxyURL != "" {
+		proxyURL, err := url.Parse(cfg.ProxyURL)
+		if err != nil {
+			return nil, fmt.Errorf("invalid proxy URL: %v", err)
+		}
+		proxy = http.ProxyURL(proxyURL)
+	}
+
+	if cfg.MaxIdleConnsPerHost <= 0 {
+		cfg.MaxIdleConnsPerHost = MaxConcurrentDownloads
+	}
+	if cfg.IdleConnTimeoutSecs <= 0 {
+		cfg.IdleConnTimeoutSecs = 90
+	}
+
+	return &http.Transport{
+		Proxy: proxy,
+		DialContext: (&net.Dialer{
+			Timeout:   30 * time.Second,
+			KeepAlive: 30 * time.Second,
+		}).DialContext,
+		ForceAttemptHTTP2:     true,
+		MaxIdleConns:          cfg.MaxIdleConnsPerHost * 4,
+		MaxIdleConnsPerHost:   cfg.MaxIdleConnsPerHost,
+		IdleConnTimeout:       time.Duration(cfg.IdleConnTimeoutSecs) * time.Second,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ExpectContinueTimeout: 1 * time.Second,
+	}, nil
+}
+
+// getSharedTransport returns the shared transport, building it on first use
+func getSharedTransport() *http.Transport {
+	transportMu.RLock()
+	transport := sharedTransport
+	transportMu.RUnlock()
+	if transport != nil {
+		return transport
+	}
+
+	transportMu.Lock()
+	defer transportMu.Unlock()
+	if sharedTransport == nil {
+		transport, err := newTransport(transportConfig)
+		if err != nil {
+			transport, _ = newTransport(DefaultTransportConfig())
+		}
+		sharedTransport = transport
+	}
+	return sharedTransport
+}
+
+// newHTTPClient returns a client with the given timeout backed by the shared transport
+func newHTTPClient(timeout time.Duration) *http.Client {
+	return &http.Client{
+		Transport: getSharedTransport(),
+		Timeout:   timeout,
+	}
+}
+
+// ConfigureTransport rebuilds the shared transport with new settings and persists them
+func ConfigureTransport(cfg TransportConfig) error {
+	transport, err := newTransport(cfg)
+	if err != nil {
+		return err
+	}
+
+	swapTransport(transport, cfg)
+
+	value, err := json.Marshal(cfg)
+	if err != nil {
+		return err
+	}
+	return SetSetting(SettingTransportConfig, string(value))
+}
+
+// GetTransportConfig returns the current transport settings
+func GetTransportConfig() TransportConfig {
+	transportMu.RLock()
+	defer transportMu.RUnlock()
+	return transportConfig
+}
+
+// LoadTransportConfig applies the transport settings saved in the database
+func LoadTransportConfig() error {
+	value, err := GetSetting(SettingTransportConfig)
+	if err != nil || value == "" {
+		return err
+	}
+
+	var cfg TransportConfig
+	if err := json.Unmarshal([]byte(value), &cfg); err != nil {
+		return err
+	}
+
+	transport, err := newTransport(cfg)
+	if err != nil {
+		return err
+	}
+
+	swapTransport(transport, cfg)
+	return nil
+}
+
+// ResetHTTPClient closes every idle connection and rebuilds the shared
+// transport with the current settings. Call it after a network change (sleep,
+// Wi-Fi switch) so stale pooled connections aren't reused. Transfers in
+// progress finish on their existing connections.
+func ResetHTTPClient() error {
+	cfg := GetTransportConfig()
+	transport, err := newTransport(cfg)
+	if err != nil {
+		return err
+	}
+	swapTransport(transport, cfg)
+	return nil
+}
+
+// swapTransport installs a new shared transport and closes the idle
+// connections of the old one
+func swapTransport(transport *http.Transport, cfg TransportConfig) {
+	transportMu.Lock()
+	old := sharedTransport
+	sharedTransport = transport
+	transportConfig = cfg
+	transportMu.Unlock()
+
+	if old != nil {
+		old.CloseIdleConnections()
+	}
+}
+
+// mediaHosts are CDN hosts that serve already-compressed media
+var mediaHosts = []string{"pbs.twimg.com", "video.twimg.com"}
+
+// isMediaHost reports whether a host serves already-compressed media
+func isMediaHost(host string) bool {
+	for _, mediaHost := range mediaHosts {
+		if host == mediaHost {
+			return true
+		}
+	}
+	return false
+}
+
+// setAcceptEncoding requests media as-is (it's already compressed and its
+// Content-Length should match the file) and accepts gzip/deflate for anything else
+func setAcceptEncoding(req *http.Request) {
+	if isMediaHost(req.URL.Hostname()) {
+		req.Header.Set("Accept-Encoding", "identity")
+		return
+	}
+	req.Header.Set("Accept-Encoding", "gzip, deflate")
+}
+
+// decodeResponseBody transparently decodes a gzip or deflate response body.
+// ContentLength is reset to -1 since it refers to the encoded size.
+func decodeResponseBody(resp *http.Response) error {
+	var decoded io.ReadCloser
+	switch strings.ToLower(strings.TrimSpace(resp.Header.Get("Content-Encoding"))) {
+	case "gzip":
+		reader, err := gzip.NewReader(resp.Body)
+		if err != nil {
+			return fmt.Errorf("failed to decode gzip response: %v", err)
+		}
+		decoded = reader
+	case "deflate":
+		decoded = flate.NewReader(resp.Body)
+	default:
+		return nil
+	}
+
+	resp.Body = &decodedBody{ReadCloser: decoded, raw: resp.Body}
+	resp.Header.Del("Content-Encoding")
+	resp.Header.Del("Content-Length")
+	resp.ContentLength = -1
+	resp.Uncompressed = true
+	return nil
+}
+
+// decodedBody closes both the decoder and the underlying response body
+type decodedBody struct {
+	io.ReadCloser
+	raw io.ReadCloser
+}
+
+func (b *decodedBody) Close() error {
+	b.ReadCloser.Close()
+	return b.raw.Close()
+}
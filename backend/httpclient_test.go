@@ -0,0 +1,93 @@
+package backend
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDecodeResponseBody(t *testing.T) {
+	body := []byte(strings.Repeat("decoded body ", 100))
+
+	var gzipped, deflated bytes.Buffer
+	gz := gzip.NewWriter(&gzipped)
+	gz.Write(body)
+	gz.Close()
+	fl, _ := flate.NewWriter(&deflated, flate.DefaultCompression)
+	fl.Write(body)
+	fl.Close()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		payload := body
+		switch r.URL.Path {
+		case "/gzip":
+			w.Header().Set("Content-Encoding", "gzip")
+			payload = gzipped.Bytes()
+		case "/deflate":
+			w.Header().Set("Content-Encoding", "deflate")
+			payload = deflated.Bytes()
+		}
+		w.Header().Set("Content-Length", strconv.Itoa(len(payload)))
+		w.Write(payload)
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		path          string
+		contentLength int64
+	}{
+		{"/gzip", -1},
+		{"/deflate", -1},
+		{"/identity", int64(len(body))},
+	}
+
+	client := newHTTPClient(10 * time.Second)
+	for _, tt := range tests {
+		t.Run(strings.TrimPrefix(tt.path, "/"), func(t *testing.T) {
+			req, err := http.NewRequest("GET", server.URL+tt.path, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			setAcceptEncoding(req)
+			resp, err := client.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+
+			if err := decodeResponseBody(resp); err != nil {
+				t.Fatalf("decodeResponseBody: %v", err)
+			}
+			got, err := io.ReadAll(resp.Body)
+			if err != nil {
+				t.Fatalf("reading body: %v", err)
+			}
+			if !bytes.Equal(got, body) {
+				t.Errorf("body is %d bytes, want the %d decoded bytes", len(got), len(body))
+			}
+			if resp.ContentLength != tt.contentLength {
+				t.Errorf("ContentLength = %d, want %d", resp.ContentLength, tt.contentLength)
+			}
+			if resp.Header.Get("Content-Encoding") != "" {
+				t.Errorf("Content-Encoding %q left on the decoded response", resp.Header.Get("Content-Encoding"))
+			}
+		})
+	}
+
+	t.Run("corrupt gzip", func(t *testing.T) {
+		resp := &http.Response{
+			Header: http.Header{"Content-Encoding": []string{"gzip"}},
+			Body:   io.NopCloser(strings.NewReader("not gzip")),
+		}
+		if err := decodeResponseBody(resp); err == nil {
+			t.Error("want an error for a body that isn't gzip")
+		}
+	})
+}
@@ -0,0 +1,220 @@
+package backend
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// downloadedNamePattern matches the date, tweet ID and index at the end of a
+// filename built from DefaultFilenameTemplate
+var downloadedNamePattern = regexp.MustCompile(`_(\d{8}_\d{6})_(\d+)_(\d+)$`)
+
+// DownloadedMedia represents one file in a download folder
+type DownloadedMedia struct {
+	Path     string `json:"path"`
+	RelPath  string `json:"rel_path"`
+	Name     string `json:"name"`
+	Size     int64  `json:"size"`
+	Type     string `json:"type"` // photo, video, gif or other
+	TweetID  int64  `json:"tweet_id,omitempty"`
+	Index    int    `json:"index,omitempty"`
+	Date     string `json:"date,omitempty"` // RFC3339 UTC when known from the filename
+	Modified string `json:"modified"`
+}
+
+// mediaTypeForFile guesses the media type from the parent folder and extension
+func mediaTypeForFile(path string) string {
+	switch strings.ToLower(filepath.Base(filepath.Dir(path))) {
+	case "images":
+		return "photo"
+	case "videos":
+		return "video"
+	case "gifs":
+		return "gif"
+	}
+
+	return fileTypeForExt(filepath.Ext(path))
+}
+
+// fileTypeForExt returns the file type for an extension: photo, video, gif,
+// audio or other
+func fileTypeForExt(ext string) string {
+	switch strings.ToLower(ext) {
+	case ".jpg", ".jpeg", ".png", ".webp":
+		return "photo"
+	case ".mp4", ".mov", ".webm", ".m4v":
+		return "video"
+	case ".gif":
+		return "gif"
+	case ".mp3", ".aac", ".m4a":
+		return "audio"
+	}
+	return "other"
+}
+
+// FileInfo represents one file on disk in an account folder
+type FileInfo struct {
+	Path     string `json:"path"`
+	RelPath  string `json:"rel_path"`
+	Name     string `json:"name"`
+	Size     int64  `json:"size"`
+	Type     string `json:"type"` // photo, video, gif, audio or other, by extension
+	Modified string `json:"modified"`
+}
+
+// ValidateFileType checks a file type filter; "" matches every file
+func ValidateFileType(fileType string) error {
+	switch fileType {
+	case "", "photo", "video", "gif", "audio", "other":
+		return nil
+	}
+	return fmt.Errorf("unknown file type: %s", fileType)
+}
+
+// ListDownloadedFiles lists every file in an account's folder under baseDir
+// (empty = default download path), including files added by hand, optionally
+// only those of fileType. Hidden files and partial downloads are left out. A
+// missing folder has no files.
+func ListDownloadedFiles(username, baseDir, fileType string) ([]FileInfo, error) {
+	if err := ValidateFileType(fileType); err != nil {
+		return nil, err
+	}
+	if baseDir == "" {
+		baseDir = GetDefaultDownloadPath()
+	}
+
+	root := filepath.Join(baseDir, SanitizeFilename(username))
+	files := []FileInfo{}
+	if info, err := os.Stat(root); err != nil || !info.IsDir() {
+		return files, nil
+	}
+
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if d != nil && d.IsDir() {
+				return fs.SkipDir
+			}
+			return nil
+		}
+
+		name := d.Name()
+		if strings.HasPrefix(name, ".") {
+			if d.IsDir() && path != root {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() || strings.HasSuffix(name, partialDownloadSuffix) {
+			return nil
+		}
+
+		kind := fileTypeForExt(filepath.Ext(name))
+		if fileType != "" && kind != fileType {
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		relPath, _ := filepath.Rel(root, path)
+		files = append(files, FileInfo{
+			Path:     path,
+			RelPath:  filepath.ToSlash(relPath),
+			Name:     name,
+			Size:     info.Size(),
+			Type:     kind,
+			Modified: info.ModTime().UTC().Format(time.RFC3339),
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list account folder: %v", err)
+	}
+
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].RelPath < files[j].RelPath
+	})
+	return files, nil
+}
+
+// ListDownloadedMedia walks a download folder, including nested subfolders,
+// and returns every media file with the tweet ID and date parsed from its name
+func ListDownloadedMedia(folderPath string) ([]DownloadedMedia, error) {
+	cleanPath := filepath.Clean(folderPath)
+	if info, err := os.Stat(cleanPath); err != nil || !info.IsDir() {
+		return nil, fmt.Errorf("folder not found: %s", cleanPath)
+	}
+
+	entries := []DownloadedMedia{}
+	err := filepath.WalkDir(cleanPath, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			// Skip unreadable entries instead of aborting the whole listing
+			if d != nil && d.IsDir() {
+				return fs.SkipDir
+			}
+			return nil
+		}
+
+		name := d.Name()
+		if strings.HasPrefix(name, ".") {
+			if d.IsDir() && path != cleanPath {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() || name == galleryFilename || name == archiveIndexFilename || strings.HasSuffix(name, partialDownloadSuffix) {
+			return nil
+		}
+
+		// Skip metadata sidecars written next to media files
+		if strings.HasSuffix(name, ".json") {
+			if _, err := os.Stat(strings.TrimSuffix(path, ".json")); err == nil {
+				return nil
+			}
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+
+		relPath, _ := filepath.Rel(cleanPath, path)
+		entry := DownloadedMedia{
+			Path:     path,
+			RelPath:  filepath.ToSlash(relPath),
+			Name:     name,
+			Size:     info.Size(),
+			Type:     mediaTypeForFile(path),
+			Modified: info.ModTime().UTC().Format(time.RFC3339),
+		}
+
+		stem := strings.TrimSuffix(name, filepath.Ext(name))
+		if match := downloadedNamePattern.FindStringSubmatch(stem); match != nil {
+			if t, err := time.Parse(DefaultDateOutputFormat, match[1]); err == nil {
+				entry.Date = t.UTC().Format(time.RFC3339)
+			}
+			entry.TweetID, _ = strconv.ParseInt(match[2], 10, 64)
+			entry.Index, _ = strconv.Atoi(match[3])
+		}
+
+		entries = append(entries, entry)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list folder: %v", err)
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].RelPath < entries[j].RelPath
+	})
+
+	return entries, nil
+}
@@ -0,0 +1,178 @@
+package backend
+
+import (
+	"fmt"
+	"strings"
+)
+
+// mediaTableSchema creates the per-entry media table. A media item shared by
+// several saved accounts (e.g. a retweet) gets a row for each of them.
+const mediaTableSchema = `
+	CREATE TABLE IF NOT EXISTS media (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		account_id INTEGER NOT NULL,
+		tweet_id INTEGER NOT NULL,
+		url TEXT NOT NULL,
+		type TEXT,
+		date TEXT,
+		is_retweet INTEGER DEFAULT 0,
+		likes INTEGER,
+		retweets INTEGER,
+		replies INTEGER,
+		UNIQUE (account_id, tweet_id, url)
+	)
+`
+
+// initMediaTable creates the per-entry media table
+func initMediaTable() error {
+	if _, err := db.Exec(mediaTableSchema); err != nil {
+		return err
+	}
+
+	// Engagement counts, NULL when the extractor didn't report them
+	db.Exec("ALTER TABLE media ADD COLUMN likes INTEGER")
+	db.Exec("ALTER TABLE media ADD COLUMN retweets INTEGER")
+	db.Exec("ALTER TABLE media ADD COLUMN replies INTEGER")
+
+	if !strings.Contains(tableSchema("media"), "UNIQUE (account_id, tweet_id, url)") {
+		if err := migrateMediaUniqueKey(); err != nil {
+			return fmt.Errorf("failed to migrate media table: %v", err)
+		}
+	}
+
+	_, err := db.Exec("CREATE INDEX IF NOT EXISTS idx_media_account ON media (account_id)")
+	return err
+}
+
+// migrateMediaUniqueKey rebuilds a media table keyed by tweet ID + URL alone,
+// where a second account syncing the same media took over the first one's
+// row, so rows are unique per account
+func migrateMediaUniqueKey() error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	steps := []string{
+		"ALTER TABLE media RENAME TO media_old",
+		mediaTableSchema,
+		`INSERT INTO media (id, account_id, tweet_id, url, type, date, is_retweet, likes, retweets, replies)
+			SELECT id, account_id, tweet_id, url, type, date, is_retweet, likes, retweets, replies FROM media_old`,
+		"DROP TABLE media_old",
+	}
+	for _, step := range steps {
+		if _, err := tx.Exec(step); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// syncAccountMedia upserts every timeline entry of a stored response into the
+// media table, keyed by account + tweet ID + URL
+func syncAccountMedia(accountID int64, responseJSON string) (int, error) {
+	response, err := parseStoredResponse(responseJSON)
+	if err != nil {
+		return 0, err
+	}
+	return syncMediaEntries(accountID, response)
+}
+
+// syncMediaEntries upserts the timeline entries of a parsed response
+func syncMediaEntries(accountID int64, response *TwitterResponse) (int, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.Prepare(`
+		INSERT INTO media (account_id, tweet_id, url, type, date, is_retweet, likes, retweets, replies)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(account_id, tweet_id, url) DO UPDATE SET
+			type = excluded.type,
+			date = excluded.date,
+			is_retweet = excluded.is_retweet,
+			likes = COALESCE(excluded.likes, likes),
+			retweets = COALESCE(excluded.retweets, retweets),
+			replies = COALESCE(excluded.replies, replies)
+	`)
+	if err != nil {
+		return 0, err
+	}
+	defer stmt.Close()
+
+	for _, entry := range response.Timeline {
+		if _, err := stmt.Exec(accountID, int64(entry.TweetID), entry.URL, entry.Type, entry.Date, entry.IsRetweet, entry.Likes, entry.Retweets, entry.Replies); err != nil {
+			return 0, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return len(response.Timeline), nil
+}
+
+// IsMediaBackfillDone reports whether the media table was backfilled from stored responses
+func IsMediaBackfillDone() bool {
+	value, err := GetSetting(SettingMediaBackfilled)
+	return err == nil && value != ""
+}
+
+// BackfillMediaTable populates the media table from every account's stored
+// response JSON. It is idempotent; accounts that fail to parse are skipped.
+// Returns the number of media entries processed.
+func BackfillMediaTable(progress ProgressCallback) (int, error) {
+	if db == nil {
+		if err := InitDB(); err != nil {
+			return 0, err
+		}
+	}
+
+	rows, err := db.Query("SELECT id FROM accounts ORDER BY id")
+	if err != nil {
+		return 0, err
+	}
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err == nil {
+			ids = append(ids, id)
+		}
+	}
+	rows.Close()
+
+	processed := 0
+	for i, id := range ids {
+		acc, err := GetAccountByID(id)
+		if err == nil {
+			if count, err := syncAccountMedia(id, acc.ResponseJSON); err == nil {
+				processed += count
+			}
+		}
+
+		if progress != nil {
+			progress(i+1, len(ids))
+		}
+	}
+
+	if err := SetSetting(SettingMediaBackfilled, "1"); err != nil {
+		return processed, err
+	}
+	return processed, nil
+}
+
+// deleteAccountMedia removes the media rows of an account
+func deleteAccountMedia(accountID int64) error {
+	_, err := db.Exec("DELETE FROM media WHERE account_id = ?", accountID)
+	return err
+}
+
+// accountIDByUsername returns the ID of a saved account
+func accountIDByUsername(username string) (int64, error) {
+	var id int64
+	err := db.QueryRow("SELECT id FROM accounts WHERE username = ?", username).Scan(&id)
+	return id, err
+}
@@ -0,0 +1,48 @@
+package backend
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMediaTableMigratesUniqueKey(t *testing.T) {
+	useTempDB(t)
+
+	// Recreate the table as older versions did, keyed by tweet ID + URL alone
+	for _, stmt := range []string{
+		"DROP TABLE media",
+		`CREATE TABLE media (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			account_id INTEGER NOT NULL,
+			tweet_id INTEGER NOT NULL,
+			url TEXT NOT NULL,
+			type TEXT,
+			date TEXT,
+			is_retweet INTEGER DEFAULT 0,
+			UNIQUE (tweet_id, url)
+		)`,
+		"INSERT INTO media (account_id, tweet_id, url, type) VALUES (1, 10, 'https://pbs.twimg.com/media/a.jpg', 'photo')",
+	} {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	CloseDB()
+	if err := InitDB(); err != nil {
+		t.Fatalf("InitDB: %v", err)
+	}
+	if schema := tableSchema("media"); !strings.Contains(schema, "UNIQUE (account_id, tweet_id, url)") {
+		t.Fatalf("media table not migrated: %s", schema)
+	}
+
+	// The same item synced by a second account keeps the first account's row
+	response := &TwitterResponse{Timeline: []TimelineEntry{{URL: "https://pbs.twimg.com/media/a.jpg", TweetID: 10, Type: "photo"}}}
+	if _, err := syncMediaEntries(2, response); err != nil {
+		t.Fatalf("syncMediaEntries: %v", err)
+	}
+	var rows int
+	if err := db.QueryRow("SELECT COUNT(*) FROM media WHERE tweet_id = 10 AND account_id IN (1, 2)").Scan(&rows); err != nil || rows != 2 {
+		t.Errorf("%d rows for the shared item, %v, want one per account", rows, err)
+	}
+}
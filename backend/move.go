@@ -0,0 +1,332 @@
+package backend
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// moveFile moves a file, renaming when possible and falling back to
+// copy+delete when source and destination are on different volumes
+func moveFile(src, dst string) error {
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return err
+	}
+
+	if err := os.Rename(src, dst); err == nil {
+		return nil
+	}
+
+	if err := copyFile(src, dst); err != nil {
+		os.Remove(dst)
+		return err
+	}
+	return os.Remove(src)
+}
+
+// copyFile copies a file's contents and permissions
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	info, err := in.Stat()
+	if err != nil {
+		return err
+	}
+
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, info.Mode().Perm())
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// removeEmptyDirs removes empty directories under root, including root
+func removeEmptyDirs(root string) {
+	var dirs []string
+	filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err == nil && d.IsDir() {
+			dirs = append(dirs, path)
+		}
+		return nil
+	})
+
+	// Deepest first
+	for i := len(dirs) - 1; i >= 0; i-- {
+		os.Remove(dirs[i])
+	}
+}
+
+// MoveDownloadedMedia moves an account's download folder from oldBase to newBase
+// and returns how many files were moved. Files already present at the
+// destination are left in place and reported as errors.
+func MoveDownloadedMedia(username, oldBase, newBase string) (int, error) {
+	folder := SanitizeFilename(username)
+	srcDir := filepath.Join(filepath.Clean(oldBase), folder)
+	dstDir := filepath.Join(filepath.Clean(newBase), folder)
+
+	if info, err := os.Stat(srcDir); err != nil || !info.IsDir() {
+		return 0, fmt.Errorf("download folder not found: %s", srcDir)
+	}
+	if srcDir == dstDir {
+		return 0, nil
+	}
+
+	moved := 0
+	var failures []string
+	err := filepath.WalkDir(srcDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			failures = append(failures, err.Error())
+			return nil
+		}
+		if d.IsDir() || d.Type()&fs.ModeSymlink != 0 {
+			return nil
+		}
+
+		rel, err := filepath.Rel(srcDir, path)
+		if err != nil {
+			failures = append(failures, err.Error())
+			return nil
+		}
+		target := filepath.Join(dstDir, rel)
+
+		if _, err := os.Stat(target); err == nil {
+			failures = append(failures, fmt.Sprintf("%s: already exists at destination", rel))
+			return nil
+		}
+
+		if err := moveFile(path, target); err != nil {
+			failures = append(failures, fmt.Sprintf("%s: %v", rel, err))
+			return nil
+		}
+		moved++
+		return nil
+	})
+	if err != nil {
+		return moved, err
+	}
+
+	removeEmptyDirs(srcDir)
+
+	// Point stored download locations at the new base
+	if moved > 0 {
+		updateHistoryOutputDir(username, oldBase, newBase)
+	}
+
+	if len(failures) > 0 {
+		return moved, fmt.Errorf("moved %d files, %d failed: %s", moved, len(failures), strings.Join(failures, "; "))
+	}
+
+	return moved, nil
+}
+
+// MoveDownloadsResult reports what MoveDownloads did
+type MoveDownloadsResult struct {
+	Transferred      int      `json:"transferred"` // files moved or copied
+	SkippedExisting  int      `json:"skipped_existing"`
+	Overwritten      int      `json:"overwritten"`
+	Renamed          int      `json:"renamed"`
+	ManifestsUpdated int      `json:"manifests_updated"` // archive indexes rewritten
+	Errors           []string `json:"errors,omitempty"`
+}
+
+// MoveDownloads moves, or with copyOnly copies, every file under srcDir to the
+// same relative path under destDir. Moves across volumes copy then delete.
+// Files already at the destination follow policy (OverwriteSkip, OverwriteReplace or
+// OverwriteRename); a media file's .json sidecar always follows the media file's name.
+//
+// With updateManifest the archive indexes are rewritten instead of copied as
+// plain files: each transferred file's entry moves to the destination's index
+// with its new path, and a move drops it from the source index. An index in a
+// folder above srcDir (moving part of an account folder) is handled the same
+// way, its entries landing in an index at destDir. Without it, indexes are
+// transferred like any other file.
+func MoveDownloads(srcDir, destDir string, copyOnly bool, policy string, updateManifest bool) (*MoveDownloadsResult, error) {
+	if err := ValidateOverwritePolicy(policy); err != nil {
+		return nil, err
+	}
+	if policy == "" {
+		policy = OverwriteSkip
+	}
+
+	srcDir, err := filepath.Abs(srcDir)
+	if err != nil {
+		return nil, err
+	}
+	destDir, err = filepath.Abs(destDir)
+	if err != nil {
+		return nil, err
+	}
+	if info, err := os.Stat(srcDir); err != nil || !info.IsDir() {
+		return nil, fmt.Errorf("source folder not found: %s", srcDir)
+	}
+	if srcDir == destDir {
+		return &MoveDownloadsResult{}, nil
+	}
+	if rel, err := filepath.Rel(srcDir, destDir); err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return nil, fmt.Errorf("destination is inside the source folder")
+	}
+
+	// Collect files first so the walk never sees files it created
+	var files []string
+	indexes := []string{}
+	filepath.WalkDir(srcDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || d.Type()&fs.ModeSymlink != 0 {
+			return nil
+		}
+		if updateManifest && d.Name() == archiveIndexFilename {
+			indexes = append(indexes, path)
+			return nil
+		}
+		files = append(files, path)
+		return nil
+	})
+
+	isFile := make(map[string]bool, len(files))
+	for _, path := range files {
+		isFile[path] = true
+	}
+
+	result := &MoveDownloadsResult{}
+	transferred := make(map[string]string) // old absolute path -> new absolute path
+
+	transfer := func(src, target string) error {
+		if copyOnly {
+			if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+				return err
+			}
+			return copyFile(src, target)
+		}
+		return moveFile(src, target)
+	}
+
+	for _, path := range files {
+		// Sidecars are handled with their media file
+		if strings.HasSuffix(path, ".json") && isFile[strings.TrimSuffix(path, ".json")] {
+			continue
+		}
+
+		rel, _ := filepath.Rel(srcDir, path)
+		target := filepath.Join(destDir, rel)
+		if _, err := os.Stat(target); err == nil {
+			switch policy {
+			case OverwriteSkip:
+				result.SkippedExisting++
+				continue
+			case OverwriteRename:
+				target = uniqueOutputPath(target, 0)
+				result.Renamed++
+			case OverwriteReplace:
+				result.Overwritten++
+			}
+		}
+
+		if err := transfer(path, target); err != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("%s: %v", rel, err))
+			continue
+		}
+		transferred[path] = target
+		result.Transferred++
+
+		if sidecar := path + ".json"; isFile[sidecar] {
+			if err := transfer(sidecar, target+".json"); err != nil {
+				result.Errors = append(result.Errors, fmt.Sprintf("%s.json: %v", rel, err))
+			} else {
+				transferred[sidecar] = target + ".json"
+				result.Transferred++
+			}
+		}
+	}
+
+	if updateManifest {
+		// The account folder's index sits above srcDir when only part of it moves
+		for dir := filepath.Dir(srcDir); ; dir = filepath.Dir(dir) {
+			if _, err := os.Stat(filepath.Join(dir, archiveIndexFilename)); err == nil {
+				indexes = append(indexes, filepath.Join(dir, archiveIndexFilename))
+				break
+			}
+			if dir == filepath.Dir(dir) {
+				break
+			}
+		}
+
+		for _, indexPath := range indexes {
+			updated, err := relocateArchiveIndex(indexPath, srcDir, destDir, transferred, copyOnly)
+			if err != nil {
+				result.Errors = append(result.Errors, err.Error())
+			} else if updated {
+				result.ManifestsUpdated++
+			}
+		}
+	}
+
+	if !copyOnly {
+		removeEmptyDirs(srcDir)
+	}
+
+	if len(result.Errors) > 0 {
+		return result, fmt.Errorf("transferred %d files, %d failed", result.Transferred, len(result.Errors))
+	}
+	return result, nil
+}
+
+// relocateArchiveIndex moves the entries of transferred files from the index
+// at indexPath into the matching index under destDir, with paths relative to
+// it. A move keeps only the remaining entries in the source index.
+func relocateArchiveIndex(indexPath, srcDir, destDir string, transferred map[string]string, copyOnly bool) (bool, error) {
+	entries, err := readArchiveIndex(indexPath)
+	if err != nil {
+		return false, fmt.Errorf("failed to read archive index %s: %v", indexPath, err)
+	}
+
+	indexDir := filepath.Dir(indexPath)
+	destIndexDir := destDir
+	if rel, err := filepath.Rel(srcDir, indexDir); err == nil && !strings.HasPrefix(rel, "..") {
+		destIndexDir = filepath.Join(destDir, rel)
+	}
+
+	var moved, kept []ArchiveIndexEntry
+	for _, entry := range entries {
+		newPath, ok := transferred[filepath.Join(indexDir, filepath.FromSlash(entry.File))]
+		if !ok {
+			kept = append(kept, entry)
+			continue
+		}
+		rel, err := filepath.Rel(destIndexDir, newPath)
+		if err != nil {
+			kept = append(kept, entry)
+			continue
+		}
+		entry.File = filepath.ToSlash(rel)
+		moved = append(moved, entry)
+	}
+	if len(moved) == 0 {
+		return false, nil
+	}
+
+	if err := os.MkdirAll(destIndexDir, 0755); err != nil {
+		return false, err
+	}
+	if err := appendArchiveIndex(destIndexDir, moved); err != nil {
+		return false, err
+	}
+	if copyOnly {
+		return true, nil
+	}
+
+	if len(kept) == 0 {
+		return true, os.Remove(indexPath)
+	}
+	return true, writeArchiveIndex(indexPath, kept)
+}
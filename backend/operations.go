@@ -0,0 +1,97 @@
+package backend
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Operation types reported by ActiveOperations
+const (
+	OperationExtract        = "extract"
+	OperationDownload       = "download"
+	OperationGIFConvert     = "gif-convert"
+	OperationAudioExtract   = "audio-extract"
+	OperationTranscode      = "transcode"
+	OperationFFmpegDownload = "ffmpeg-download"
+	OperationThumbnails     = "thumbnail-prefetch"
+	OperationMove           = "move"
+	OperationRepair         = "archive-repair"
+)
+
+// Operation is a long-running task in progress
+type Operation struct {
+	ID        string `json:"id"`
+	Type      string `json:"type"`
+	Target    string `json:"target"` // username or folder the operation works on
+	Current   int64  `json:"current"`
+	Total     int64  `json:"total"`   // 0 when not known up front
+	Percent   int    `json:"percent"` // 0 when the total is unknown
+	StartedAt string `json:"started_at"`
+
+	seq int64
+}
+
+// operations is the registry of running operations
+var (
+	operationsMu  sync.Mutex
+	operations    = make(map[string]*Operation)
+	nextOperation int64
+)
+
+// StartOperation registers a running operation and returns its ID
+func StartOperation(opType, target string) string {
+	operationsMu.Lock()
+	defer operationsMu.Unlock()
+
+	nextOperation++
+	op := &Operation{
+		ID:        fmt.Sprintf("op-%d", nextOperation),
+		Type:      opType,
+		Target:    target,
+		StartedAt: time.Now().UTC().Format(time.RFC3339),
+		seq:       nextOperation,
+	}
+	operations[op.ID] = op
+	return op.ID
+}
+
+// UpdateOperation records the progress of a running operation
+func UpdateOperation(id string, current, total int64) {
+	operationsMu.Lock()
+	defer operationsMu.Unlock()
+
+	op, ok := operations[id]
+	if !ok {
+		return
+	}
+	op.Current = current
+	op.Total = total
+	op.Percent = 0
+	if total > 0 {
+		op.Percent = int(current * 100 / total)
+	}
+}
+
+// FinishOperation removes an operation from the registry
+func FinishOperation(id string) {
+	operationsMu.Lock()
+	delete(operations, id)
+	operationsMu.Unlock()
+}
+
+// ActiveOperations returns a snapshot of the running operations, oldest first
+func ActiveOperations() []Operation {
+	operationsMu.Lock()
+	defer operationsMu.Unlock()
+
+	list := make([]Operation, 0, len(operations))
+	for _, op := range operations {
+		list = append(list, *op)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].seq < list[j].seq
+	})
+	return list
+}
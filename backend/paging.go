@@ -0,0 +1,123 @@
+package backend
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+const (
+	// DefaultPageRetries is how many times a failed page is retried
+	DefaultPageRetries = 2
+	// maxConsecutiveFailedPages stops skipping after this many pages fail in a row
+	maxConsecutiveFailedPages = 3
+)
+
+// TimelineAllOptions holds options for extracting every page of a timeline
+type TimelineAllOptions struct {
+	ContinueOnError bool // skip pages that still fail after retries
+	PageRetries     int  // 0 = DefaultPageRetries
+}
+
+// TimelineAllResponse represents an aggregated multi-page extraction
+type TimelineAllResponse struct {
+	TwitterResponse
+	FailedPages []int `json:"failed_pages"`
+}
+
+// extractPageWithRetry extracts one page, retrying transient failures.
+// Cancelling ctx cuts the pause before a retry short.
+func extractPageWithRetry(ctx context.Context, req TimelineRequest, retries int) (*TwitterResponse, error) {
+	var lastErr error
+	for attempt := 0; attempt <= retries; attempt++ {
+		if attempt > 0 {
+			if err := sleepContext(ctx, time.Duration(attempt)*2*time.Second); err != nil {
+				return nil, err
+			}
+		}
+		response, err := ExtractTimeline(req)
+		if err == nil {
+			return response, nil
+		}
+		// Retrying won't make a protected account visible
+		if errors.Is(err, ErrAccountProtected) {
+			return nil, err
+		}
+		lastErr = err
+	}
+	return nil, lastErr
+}
+
+// ExtractTimelineAll pages through a timeline starting at req.Page (or
+// req.Cursor) and aggregates every page into one response. Pages are chained
+// by the extractor's cursor when it returns one, so entries added mid-run do
+// not shift pages; otherwise it falls back to page numbers. With
+// ContinueOnError, pages that keep failing are skipped and reported in FailedPages.
+func ExtractTimelineAll(req TimelineRequest, opts TimelineAllOptions) (*TimelineAllResponse, error) {
+	retries := opts.PageRetries
+	if retries <= 0 {
+		retries = DefaultPageRetries
+	}
+
+	result := &TimelineAllResponse{
+		TwitterResponse: TwitterResponse{
+			Timeline: []TimelineEntry{},
+		},
+		FailedPages: []int{},
+	}
+
+	page := req.Page
+	cursor := req.Cursor
+	consecutiveFailures := 0
+	for {
+		pageReq := req
+		pageReq.Page = page
+		pageReq.Cursor = cursor
+
+		response, err := extractPageWithRetry(context.Background(), pageReq, retries)
+		if err != nil {
+			if !opts.ContinueOnError {
+				return nil, fmt.Errorf("page %d: %v", page, err)
+			}
+
+			result.FailedPages = append(result.FailedPages, page)
+			consecutiveFailures++
+			if consecutiveFailures >= maxConsecutiveFailedPages || req.BatchSize <= 0 {
+				break
+			}
+			// A failed page has no next cursor, so skip ahead by page number
+			cursor = ""
+			page++
+			continue
+		}
+		consecutiveFailures = 0
+
+		if result.AccountInfo.Nick == "" {
+			result.AccountInfo = response.AccountInfo
+		}
+		result.Timeline = append(result.Timeline, response.Timeline...)
+
+		// BatchSize 0 fetches everything in a single call
+		if !response.Metadata.HasMore || req.BatchSize <= 0 {
+			break
+		}
+		cursor = response.Metadata.Cursor
+		page++
+	}
+
+	if len(result.Timeline) == 0 && len(result.FailedPages) > 0 {
+		return nil, fmt.Errorf("all pages failed: %v", result.FailedPages)
+	}
+
+	result.TotalURLs = len(result.Timeline)
+	result.Metadata = ExtractMetadata{
+		NewEntries: len(result.Timeline),
+		Page:       page,
+		BatchSize:  req.BatchSize,
+		HasMore:    len(result.FailedPages) > 0 && consecutiveFailures >= maxConsecutiveFailedPages,
+		Cursor:     cursor,
+	}
+
+	return result, nil
+}
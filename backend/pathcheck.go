@@ -0,0 +1,152 @@
+package backend
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strings"
+)
+
+// windowsDrivePath matches a path starting with a drive letter, e.g. C:\ or D:/
+var windowsDrivePath = regexp.MustCompile(`^[A-Za-z]:[\\/]`)
+
+// ValidateOutputPath checks that path can be used as a download folder on
+// this system: it must be absolute and valid for the OS, sit on a volume that
+// is present, and be writable (or creatable, when it doesn't exist yet). It
+// returns false with the reason when it can't. Nothing is left behind on disk.
+func ValidateOutputPath(path string) (bool, string) {
+	if reason := outputPathSyntaxProblem(path, runtime.GOOS); reason != "" {
+		return false, reason
+	}
+
+	path = filepath.Clean(path)
+
+	// Find the deepest part of the path that already exists
+	existing := path
+	for {
+		info, err := os.Stat(existing)
+		if err == nil {
+			if !info.IsDir() {
+				return false, fmt.Sprintf("%s is a file, not a folder", existing)
+			}
+			break
+		}
+		if !os.IsNotExist(err) {
+			return false, fmt.Sprintf("cannot access %s: %v", existing, err)
+		}
+
+		parent := filepath.Dir(existing)
+		if parent == existing {
+			return false, fmt.Sprintf("drive or volume %s is not available", existing)
+		}
+		if reason := unmountedVolume(existing, runtime.GOOS); reason != "" {
+			return false, reason
+		}
+		existing = parent
+	}
+
+	// Try a real write; permission bits don't tell the whole story on
+	// network shares, read-only mounts and Windows ACLs
+	probe, err := os.CreateTemp(existing, ".xmd-write-test-*")
+	if err != nil {
+		if existing == path {
+			return false, fmt.Sprintf("folder is not writable: %v", err)
+		}
+		return false, fmt.Sprintf("folder cannot be created, %s is not writable: %v", existing, err)
+	}
+	probe.Close()
+	os.Remove(probe.Name())
+
+	return true, ""
+}
+
+// outputPathSyntaxProblem returns why path is not a usable absolute folder
+// path on goos, judging by its text alone, or "" when it looks fine
+func outputPathSyntaxProblem(path, goos string) string {
+	if path == "" {
+		return "path is empty"
+	}
+	if strings.ContainsRune(path, 0) {
+		return "path contains a NUL character"
+	}
+	if len(path) >= 2 && (path[0] == '"' && path[len(path)-1] == '"' || path[0] == '\'' && path[len(path)-1] == '\'') {
+		return "remove the quotes around the path"
+	}
+	if strings.TrimSpace(path) != path {
+		return "path starts or ends with a space"
+	}
+
+	if goos == "windows" {
+		return windowsPathProblem(path)
+	}
+
+	if windowsDrivePath.MatchString(path) || strings.HasPrefix(path, `\\`) {
+		return fmt.Sprintf("%s is a Windows path, which is not valid on this system", path)
+	}
+	if strings.HasPrefix(path, "~") {
+		return "~ is not expanded, use the full path to your home folder"
+	}
+	if !strings.HasPrefix(path, "/") {
+		return "path must be absolute, starting with /"
+	}
+	return ""
+}
+
+// windowsPathProblem checks a path against the Windows naming rules
+func windowsPathProblem(path string) string {
+	if strings.HasPrefix(path, `\\?\`) {
+		// Long path syntax is passed to the file system as is
+		return ""
+	}
+
+	var rest string
+	switch {
+	case strings.HasPrefix(path, `\\`) || strings.HasPrefix(path, `//`):
+		// UNC path: \\server\share\...
+		parts := strings.FieldsFunc(path[2:], func(r rune) bool { return r == '\\' || r == '/' })
+		if len(parts) < 2 {
+			return `network path must include a server and share, e.g. \\server\share`
+		}
+		rest = strings.Join(parts[2:], `\`)
+	case windowsDrivePath.MatchString(path):
+		rest = path[3:]
+	case strings.HasPrefix(path, "/") || strings.HasPrefix(path, "~"):
+		return fmt.Sprintf("%s is a macOS or Linux path, which is not valid on Windows", path)
+	case len(path) == 2 && path[1] == ':':
+		return fmt.Sprintf(`add a backslash after the drive letter, e.g. %s\`, path)
+	default:
+		return `path must be absolute, starting with a drive letter (C:\) or \\server\share`
+	}
+
+	for _, part := range strings.FieldsFunc(rest, func(r rune) bool { return r == '\\' || r == '/' }) {
+		if i := strings.IndexAny(part, `<>:"|?*`); i >= 0 {
+			return fmt.Sprintf("folder name %q contains %q, which Windows does not allow", part, part[i])
+		}
+		if strings.HasSuffix(part, " ") || strings.HasSuffix(part, ".") && part != "." && part != ".." {
+			return fmt.Sprintf("folder name %q ends with a space or dot, which Windows does not allow", part)
+		}
+		name := strings.ToUpper(part)
+		if dot := strings.IndexByte(name, '.'); dot >= 0 {
+			name = name[:dot]
+		}
+		if windowsReservedNames[name] {
+			return fmt.Sprintf("%s is a reserved name on Windows", part)
+		}
+	}
+	return ""
+}
+
+// unmountedVolume returns a reason when missing is the mount point of an
+// external volume that isn't attached, e.g. /Volumes/Backup on macOS
+func unmountedVolume(missing, goos string) string {
+	parent := filepath.Dir(missing)
+	switch {
+	case goos == "darwin" && parent == "/Volumes":
+	case goos == "linux" && (parent == "/mnt" || parent == "/media" || filepath.Dir(parent) == "/media" || filepath.Dir(parent) == "/run/media"):
+	default:
+		return ""
+	}
+	return fmt.Sprintf("volume %s is not mounted", filepath.Base(missing))
+}
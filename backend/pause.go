@@ -0,0 +1,73 @@
+package backend
+
+import (
+	"context"
+	"sync"
+)
+
+// PauseController lets a running download stop starting new files until resumed
+type PauseController struct {
+	mu     sync.Mutex
+	paused bool
+	resume chan struct{}
+}
+
+// NewPauseController creates a controller in the running state
+func NewPauseController() *PauseController {
+	return &PauseController{}
+}
+
+// Pause stops new work from starting; returns false if already paused
+func (p *PauseController) Pause() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.paused {
+		return false
+	}
+	p.paused = true
+	p.resume = make(chan struct{})
+	return true
+}
+
+// Resume lets blocked workers continue; returns false if not paused
+func (p *PauseController) Resume() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if !p.paused {
+		return false
+	}
+	p.paused = false
+	close(p.resume)
+	return true
+}
+
+// IsPaused reports whether the controller is paused
+func (p *PauseController) IsPaused() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.paused
+}
+
+// Wait blocks while paused until resumed or the context is canceled
+func (p *PauseController) Wait(ctx context.Context) error {
+	if p == nil {
+		return nil
+	}
+
+	p.mu.Lock()
+	if !p.paused {
+		p.mu.Unlock()
+		return nil
+	}
+	resume := p.resume
+	p.mu.Unlock()
+
+	select {
+	case <-resume:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
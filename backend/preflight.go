@@ -0,0 +1,149 @@
+package backend
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Defaults for the HEAD requests of the preflight checks
+const (
+	DefaultHeadCheckConcurrency = 8
+	DefaultHeadCheckTimeout     = 10 * time.Second
+)
+
+// HeadCheckOptions tunes the HEAD requests made by CheckMediaAvailability and
+// EstimateDownloadSize. They go through the shared transport, so the
+// configured proxy applies.
+type HeadCheckOptions struct {
+	Concurrency int           // requests in flight at once, <= 0 = DefaultHeadCheckConcurrency
+	Timeout     time.Duration // per request, <= 0 = DefaultHeadCheckTimeout
+}
+
+// MediaAvailability is the result of a HEAD request for one media item
+type MediaAvailability struct {
+	URL       string `json:"url"`
+	TweetID   int64  `json:"tweet_id"`
+	Available bool   `json:"available"`
+	Status    int    `json:"status,omitempty"` // 0 when the request failed
+	Size      int64  `json:"size"`             // -1 when the server didn't say
+	Error     string `json:"error,omitempty"`
+}
+
+// DownloadSizeEstimate sums the sizes reported for a batch of media items
+type DownloadSizeEstimate struct {
+	TotalBytes  int64 `json:"total_bytes"` // of the items with a known size
+	Known       int   `json:"known"`
+	Unknown     int   `json:"unknown"`     // available, but no Content-Length
+	Unavailable int   `json:"unavailable"` // failed or not found
+}
+
+// CheckMediaAvailability sends a HEAD request for every item and reports
+// whether it can still be downloaded, in the order of items. Cancelling ctx
+// stops sending requests; the items not checked carry the context error.
+func CheckMediaAvailability(ctx context.Context, items []MediaItem, opts HeadCheckOptions) ([]MediaAvailability, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	concurrency := opts.Concurrency
+	if concurrency <= 0 {
+		concurrency = DefaultHeadCheckConcurrency
+	}
+	if concurrency > len(items) {
+		concurrency = len(items)
+	}
+	timeout := opts.Timeout
+	if timeout <= 0 {
+		timeout = DefaultHeadCheckTimeout
+	}
+
+	results := make([]MediaAvailability, len(items))
+	for i, item := range items {
+		results[i] = MediaAvailability{URL: item.URL, TweetID: item.TweetID, Size: -1}
+	}
+
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	client := newHTTPClient(timeout)
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for index := range indexes {
+				headMedia(ctx, client, &results[index])
+			}
+		}()
+	}
+
+	sent := 0
+dispatch:
+	for sent < len(items) {
+		select {
+		case <-ctx.Done():
+			break dispatch
+		case indexes <- sent:
+			sent++
+		}
+	}
+	close(indexes)
+	wg.Wait()
+
+	if err := ctx.Err(); err != nil {
+		for i := sent; i < len(results); i++ {
+			results[i].Error = err.Error()
+		}
+		return results, err
+	}
+	return results, nil
+}
+
+// EstimateDownloadSize adds up the Content-Length of every item, fetched with
+// HEAD requests as in CheckMediaAvailability
+func EstimateDownloadSize(ctx context.Context, items []MediaItem, opts HeadCheckOptions) (DownloadSizeEstimate, error) {
+	var estimate DownloadSizeEstimate
+	results, err := CheckMediaAvailability(ctx, items, opts)
+	if err != nil {
+		return estimate, err
+	}
+
+	for _, result := range results {
+		switch {
+		case !result.Available:
+			estimate.Unavailable++
+		case result.Size < 0:
+			estimate.Unknown++
+		default:
+			estimate.Known++
+			estimate.TotalBytes += result.Size
+		}
+	}
+	return estimate, nil
+}
+
+// headMedia fills in result from a HEAD request for its URL
+func headMedia(ctx context.Context, client *http.Client, result *MediaAvailability) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, result.URL, nil)
+	if err != nil {
+		result.Error = err.Error()
+		return
+	}
+	setAcceptEncoding(req)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		result.Error = err.Error()
+		return
+	}
+	resp.Body.Close()
+
+	result.Status = resp.StatusCode
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		result.Error = fmt.Sprintf("HTTP %d", resp.StatusCode)
+		return
+	}
+	result.Available = true
+	result.Size = resp.ContentLength
+}
@@ -0,0 +1,55 @@
+package backend
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"time"
+)
+
+// avatarSizeSuffix matches the size variant suffix of a profile image URL
+var avatarSizeSuffix = regexp.MustCompile(`_(normal|bigger|mini|\d+x\d+)(\.[A-Za-z0-9]+)$`)
+
+// GetOriginalAvatarURL converts a profile image URL to its original size
+func GetOriginalAvatarURL(url string) string {
+	return avatarSizeSuffix.ReplaceAllString(url, "$2")
+}
+
+// DownloadProfileAssets downloads the full-resolution avatar and banner into
+// the account folder as avatar.jpg and banner.jpg, returning how many were saved
+func DownloadProfileAssets(profileImage, profileBanner, outputDir, username string) (int, error) {
+	accountDir := filepath.Join(outputDir, SanitizeFilename(username))
+	if err := os.MkdirAll(accountDir, 0755); err != nil {
+		return 0, fmt.Errorf("failed to create output directory: %v", err)
+	}
+
+	client := newHTTPClient(60 * time.Second)
+
+	assets := []struct {
+		url  string
+		name string
+	}{
+		{GetOriginalAvatarURL(profileImage), "avatar.jpg"},
+		{profileBanner, "banner.jpg"},
+	}
+
+	saved := 0
+	var lastErr error
+	for _, asset := range assets {
+		if asset.url == "" {
+			continue
+		}
+		if err := downloadFile(client, asset.url, filepath.Join(accountDir, asset.name)); err != nil {
+			lastErr = fmt.Errorf("failed to download %s: %v", asset.name, err)
+			continue
+		}
+		saved++
+	}
+
+	if saved == 0 && lastErr != nil {
+		return 0, lastErr
+	}
+
+	return saved, nil
+}
@@ -0,0 +1,62 @@
+package backend
+
+import (
+	"context"
+	"fmt"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
+	"net/http"
+	"os"
+)
+
+// imageHeaderProbeSize is how many bytes are fetched to read image dimensions
+const imageHeaderProbeSize = 64 * 1024
+
+// probeImageSize reads the dimensions of a remote image from its first bytes
+// using a range request; ok is false when the size could not be determined
+func probeImageSize(ctx context.Context, client *http.Client, url string) (width, height int, ok bool) {
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return 0, 0, false
+	}
+	req.Header.Set("Range", fmt.Sprintf("bytes=0-%d", imageHeaderProbeSize-1))
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, 0, false
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusPartialContent {
+		return 0, 0, false
+	}
+
+	config, _, err := image.DecodeConfig(io.LimitReader(resp.Body, imageHeaderProbeSize))
+	if err != nil {
+		return 0, 0, false
+	}
+	return config.Width, config.Height, true
+}
+
+// readImageFileSize reads the dimensions of a local image file
+func readImageFileSize(path string) (width, height int, ok bool) {
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, 0, false
+	}
+	defer file.Close()
+
+	config, _, err := image.DecodeConfig(file)
+	if err != nil {
+		return 0, 0, false
+	}
+	return config.Width, config.Height, true
+}
+
+// meetsMinResolution reports whether the dimensions satisfy the minimums (0 = no minimum)
+func meetsMinResolution(width, height, minWidth, minHeight int) bool {
+	return (minWidth <= 0 || width >= minWidth) && (minHeight <= 0 || height >= minHeight)
+}
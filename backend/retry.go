@@ -0,0 +1,236 @@
+package backend
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultDownloadRetries is how many extra attempts a failed file gets
+const DefaultDownloadRetries = 2
+
+// DefaultMaxRetryAfter caps the wait requested by a Retry-After header
+const DefaultMaxRetryAfter = 5 * time.Minute
+
+// DefaultCircuitBreakerThreshold is how many consecutive failed attempts across
+// a batch stop it, so an outage fails fast instead of retrying every item
+const DefaultCircuitBreakerThreshold = 10
+
+// ErrCDNUnreachable is returned when a batch stopped after too many consecutive
+// failures; the unattempted items are reported as failed and can be retried later
+var ErrCDNUnreachable = errors.New("media CDN appears unreachable: stopped after too many consecutive failures, retry later")
+
+// circuitBreaker trips after a run of consecutive failed attempts shared by
+// every worker of a batch. A nil breaker never trips.
+type circuitBreaker struct {
+	mu          sync.Mutex
+	threshold   int
+	consecutive int
+	tripped     bool
+}
+
+// newCircuitBreaker returns a breaker for threshold (0 = default, < 0 = disabled)
+func newCircuitBreaker(threshold int) *circuitBreaker {
+	if threshold < 0 {
+		return nil
+	}
+	if threshold == 0 {
+		threshold = DefaultCircuitBreakerThreshold
+	}
+	return &circuitBreaker{threshold: threshold}
+}
+
+// record counts an attempt's outcome. Only failures that suggest the server is
+// unreachable count; any other response, including a 429 rate limit, shows it
+// is up and resets the run.
+func (b *circuitBreaker) record(err error) {
+	if b == nil {
+		return
+	}
+	var skip *skipError
+	if errors.As(err, &skip) || errors.Is(err, context.Canceled) {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	var status *statusError
+	if err == nil || !isRetryableDownloadError(err) || (errors.As(err, &status) && status.code == http.StatusTooManyRequests) {
+		b.consecutive = 0
+		return
+	}
+	b.consecutive++
+	if b.consecutive >= b.threshold {
+		b.tripped = true
+	}
+}
+
+// isTripped reports whether the batch should stop trying
+func (b *circuitBreaker) isTripped() bool {
+	if b == nil {
+		return false
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.tripped
+}
+
+// FailedItem describes a media item that could not be downloaded
+type FailedItem struct {
+	URL      string `json:"url"`
+	TweetID  int64  `json:"tweet_id"`
+	Error    string `json:"error"`
+	Attempts int    `json:"attempts"`
+}
+
+// DownloadError wraps the last error of a failed item with the attempts made
+type DownloadError struct {
+	Err      error
+	Attempts int
+}
+
+func (e *DownloadError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *DownloadError) Unwrap() error {
+	return e.Err
+}
+
+// NewFailedItem builds the failure report for an item from its download error
+func NewFailedItem(item MediaItem, err error) FailedItem {
+	attempts := 1
+	var downloadErr *DownloadError
+	if errors.As(err, &downloadErr) {
+		attempts = downloadErr.Attempts
+	}
+	return FailedItem{
+		URL:      item.URL,
+		TweetID:  item.TweetID,
+		Error:    err.Error(),
+		Attempts: attempts,
+	}
+}
+
+// statusError is returned for a non-200 HTTP response
+type statusError struct {
+	code          int
+	status        string
+	retryAfter    time.Duration
+	hasRetryAfter bool
+}
+
+// newStatusError builds the error for resp, keeping its Retry-After if valid
+func newStatusError(resp *http.Response) *statusError {
+	err := &statusError{code: resp.StatusCode, status: resp.Status}
+	err.retryAfter, err.hasRetryAfter = parseRetryAfter(resp.Header.Get("Retry-After"), time.Now())
+	return err
+}
+
+// parseRetryAfter parses a Retry-After value, either delay seconds or an
+// HTTP date. A date in the past means no wait.
+func parseRetryAfter(value string, now time.Time) (time.Duration, bool) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+	date, err := http.ParseTime(value)
+	if err != nil {
+		return 0, false
+	}
+	if wait := date.Sub(now); wait > 0 {
+		return wait, true
+	}
+	return 0, true
+}
+
+// capRetryAfter limits a server-requested wait (maxWait 0 = DefaultMaxRetryAfter)
+func capRetryAfter(wait, maxWait time.Duration) time.Duration {
+	if maxWait <= 0 {
+		maxWait = DefaultMaxRetryAfter
+	}
+	if wait > maxWait {
+		return maxWait
+	}
+	return wait
+}
+
+// retryDelay is the wait before the next attempt: the server's Retry-After
+// when it sent one, otherwise a backoff growing with each attempt
+func retryDelay(err error, attempts int, maxRetryAfter time.Duration) time.Duration {
+	var status *statusError
+	if errors.As(err, &status) && status.hasRetryAfter {
+		return capRetryAfter(status.retryAfter, maxRetryAfter)
+	}
+	return time.Duration(attempts) * time.Second
+}
+
+func (e *statusError) Error() string {
+	return fmt.Sprintf("bad status: %s", e.status)
+}
+
+// isRetryableDownloadError reports whether another attempt might succeed;
+// skips, cancellation and 4xx responses other than 429 are final
+func isRetryableDownloadError(err error) bool {
+	var skip *skipError
+	if errors.As(err, &skip) || errors.Is(err, context.Canceled) {
+		return false
+	}
+
+	var status *statusError
+	if errors.As(err, &status) {
+		return status.code == 429 || status.code >= 500
+	}
+	return true
+}
+
+// withDownloadRetries runs fn until it succeeds, fails permanently or runs out
+// of attempts, backing off between attempts or waiting as long as a
+// Retry-After header asks, up to maxRetryAfter. retries is the number of extra
+// attempts (0 = DefaultDownloadRetries, < 0 = none). Once the breaker trips no
+// new attempts are made. Returns how many times fn ran.
+func withDownloadRetries(ctx context.Context, retries int, maxRetryAfter time.Duration, breaker *circuitBreaker, fn func() error) (int, error) {
+	if retries == 0 {
+		retries = DefaultDownloadRetries
+	} else if retries < 0 {
+		retries = 0
+	}
+
+	attempts := 0
+	for {
+		if breaker.isTripped() {
+			return attempts, &DownloadError{Err: ErrCDNUnreachable, Attempts: attempts}
+		}
+
+		attempts++
+		err := fn()
+		breaker.record(err)
+		if err == nil {
+			return attempts, nil
+		}
+		if attempts > retries || !isRetryableDownloadError(err) || ctx.Err() != nil {
+			var skip *skipError
+			if errors.As(err, &skip) {
+				return attempts, err
+			}
+			return attempts, &DownloadError{Err: err, Attempts: attempts}
+		}
+
+		select {
+		case <-ctx.Done():
+			return attempts, &DownloadError{Err: ctx.Err(), Attempts: attempts}
+		case <-time.After(retryDelay(err, attempts, maxRetryAfter)):
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package backend
+
+import (
+	"context"
+	"testing"
+)
+
+func TestWithDownloadRetriesAttempts(t *testing.T) {
+	tests := []struct {
+		name    string
+		retries int
+		want    int
+	}{
+		{"default", 0, DefaultDownloadRetries + 1},
+		{"none", -1, 1},
+		{"one", 1, 2},
+		{"five", 5, 6},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			attempts, err := withDownloadRetries(context.Background(), tt.retries, 0, nil, func() error {
+				calls++
+				// Retryable, with a zero Retry-After so the test doesn't wait
+				return &statusError{code: 503, status: "503 Service Unavailable", hasRetryAfter: true}
+			})
+			if err == nil {
+				t.Fatal("want the last error")
+			}
+			if attempts != tt.want || calls != tt.want {
+				t.Errorf("retries %d: %d attempts, %d calls, want %d", tt.retries, attempts, calls, tt.want)
+			}
+		})
+	}
+}
+
+func TestCircuitBreakerIgnoresRateLimits(t *testing.T) {
+	unavailable := &statusError{code: 503, status: "503 Service Unavailable"}
+	rateLimited := &statusError{code: 429, status: "429 Too Many Requests"}
+
+	breaker := newCircuitBreaker(3)
+	breaker.record(unavailable)
+	breaker.record(unavailable)
+	breaker.record(rateLimited)
+	breaker.record(unavailable)
+	breaker.record(unavailable)
+	if breaker.isTripped() {
+		t.Error("tripped with a 429 between the failures")
+	}
+
+	for i := 0; i < 5; i++ {
+		breaker.record(rateLimited)
+	}
+	if breaker.isTripped() {
+		t.Error("tripped on rate limits alone")
+	}
+
+	breaker.record(unavailable)
+	breaker.record(unavailable)
+	breaker.record(unavailable)
+	if !breaker.isTripped() {
+		t.Error("not tripped after 3 consecutive failures")
+	}
+}
@@ -0,0 +1,83 @@
+package backend
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"os"
+	"sort"
+	"sync"
+)
+
+// sessionCookies holds the cookies each extractor session ended with, keyed
+// by auth token, so downloads can fetch media that needs a logged-in session
+var (
+	sessionCookiesMu sync.Mutex
+	sessionCookies   = make(map[string]map[string]string)
+)
+
+// readSessionCookies loads the cookies the extractor wrote with --cookies-out
+// and remembers them under their auth_token
+func readSessionCookies(path string) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return
+	}
+	var cookies map[string]string
+	if err := json.Unmarshal(data, &cookies); err != nil || cookies["auth_token"] == "" {
+		return
+	}
+
+	sessionCookiesMu.Lock()
+	sessionCookies[cookies["auth_token"]] = cookies
+	sessionCookiesMu.Unlock()
+}
+
+// hasSessionCookies reports whether an extraction with authToken has left
+// its session cookies in this run of the app
+func hasSessionCookies(authToken string) bool {
+	sessionCookiesMu.Lock()
+	defer sessionCookiesMu.Unlock()
+	return sessionCookies[authToken] != nil
+}
+
+// SessionCookies returns the cookies of the last extraction made with
+// authToken, or just the auth_token cookie when there was none
+func SessionCookies(authToken string) []*http.Cookie {
+	if authToken == "" {
+		return nil
+	}
+
+	sessionCookiesMu.Lock()
+	values := sessionCookies[authToken]
+	sessionCookiesMu.Unlock()
+	if values == nil {
+		values = map[string]string{"auth_token": authToken}
+	}
+
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	cookies := make([]*http.Cookie, 0, len(names))
+	for _, name := range names {
+		cookies = append(cookies, &http.Cookie{Name: name, Value: values[name]})
+	}
+	return cookies
+}
+
+// mediaCookieJar returns a jar holding cookies for the media CDN hosts only,
+// so the session never leaks to other hosts
+func mediaCookieJar(cookies []*http.Cookie) http.CookieJar {
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		return nil
+	}
+	for _, host := range mediaHosts {
+		jar.SetCookies(&url.URL{Scheme: "https", Host: host, Path: "/"}, cookies)
+	}
+	return jar
+}
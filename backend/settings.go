@@ -0,0 +1,63 @@
+package backend
+
+import (
+	"database/sql"
+)
+
+// Setting keys stored in the settings table
+const (
+	SettingPostDownloadCommand = "post_download_command"
+	SettingTransportConfig     = "transport_config"
+	SettingAuthTokenFile       = "auth_token_file"
+	SettingMediaBackfilled     = "media_backfilled"
+	SettingSearchIndexed       = "search_indexed"
+	SettingExtractionDefaults  = "extraction_defaults"
+	SettingExtractCacheTTL     = "extract_cache_ttl" // seconds, "0" = off
+)
+
+// initSettingsTable creates the key/value settings table
+func initSettingsTable() error {
+	_, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS settings (
+			key TEXT PRIMARY KEY,
+			value TEXT
+		)
+	`)
+	return err
+}
+
+// GetSetting returns a stored setting, or an empty string if unset
+func GetSetting(key string) (string, error) {
+	if db == nil {
+		if err := InitDB(); err != nil {
+			return "", err
+		}
+	}
+
+	var value string
+	err := db.QueryRow("SELECT value FROM settings WHERE key = ?", key).Scan(&value)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	return value, err
+}
+
+// SetSetting stores a setting, removing it when value is empty
+func SetSetting(key, value string) error {
+	if db == nil {
+		if err := InitDB(); err != nil {
+			return err
+		}
+	}
+
+	if value == "" {
+		_, err := db.Exec("DELETE FROM settings WHERE key = ?", key)
+		return err
+	}
+
+	_, err := db.Exec(`
+		INSERT INTO settings (key, value) VALUES (?, ?)
+		ON CONFLICT(key) DO UPDATE SET value = excluded.value
+	`, key, value)
+	return err
+}
@@ -0,0 +1,102 @@
+package backend
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// DefaultFilenameTemplate is the filename layout used when no template is set
+const DefaultFilenameTemplate = "{username}_{date}_{tweet_id}_{index}"
+
+// filenameTokenPattern matches a {token} placeholder
+var filenameTokenPattern = regexp.MustCompile(`\{([^{}]*)\}`)
+
+// filenameTokens are the placeholders supported in filename templates
+var filenameTokens = map[string]bool{
+	"username": true,
+	"date":     true,
+	"tweet_id": true,
+	"index":    true,
+	"type":     true,
+}
+
+// filenameFields holds the values available to filename templates
+type filenameFields struct {
+	Username string
+	Date     string
+	TweetID  int64
+	Index    int
+	Type     string
+}
+
+// renderFilenameTemplate replaces the template placeholders with field values
+func renderFilenameTemplate(template string, fields filenameFields) string {
+	return strings.NewReplacer(
+		"{username}", fields.Username,
+		"{date}", fields.Date,
+		"{tweet_id}", strconv.FormatInt(fields.TweetID, 10),
+		"{index}", fmt.Sprintf("%02d", fields.Index),
+		"{type}", fields.Type,
+	).Replace(template)
+}
+
+// ValidateFilenameTemplate checks a template for unknown tokens, unbalanced
+// braces and path separators, and that it keeps filenames unique
+func ValidateFilenameTemplate(template string) error {
+	if strings.TrimSpace(template) == "" {
+		return fmt.Errorf("template is empty")
+	}
+
+	var problems []string
+
+	var unknown []string
+	used := make(map[string]bool)
+	for _, match := range filenameTokenPattern.FindAllStringSubmatch(template, -1) {
+		if !filenameTokens[match[1]] {
+			unknown = append(unknown, "{"+match[1]+"}")
+			continue
+		}
+		used[match[1]] = true
+	}
+	if len(unknown) > 0 {
+		problems = append(problems, "unknown tokens: "+strings.Join(unknown, ", "))
+	}
+
+	if rest := filenameTokenPattern.ReplaceAllString(template, ""); strings.ContainsAny(rest, "{}") {
+		problems = append(problems, "unbalanced braces")
+	} else if strings.ContainsAny(rest, `/\`) {
+		problems = append(problems, "path separators are not allowed")
+	} else if strings.ContainsAny(rest, `<>:"|?*`) {
+		problems = append(problems, `characters <>:"|?* are not allowed`)
+	}
+
+	if !used["tweet_id"] || !used["index"] {
+		problems = append(problems, "{tweet_id} and {index} are required to keep filenames unique")
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("invalid template: %s", strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// PreviewFilenameTemplate renders a template against a sample item, returning
+// the filename that would be used or a descriptive validation error
+func PreviewFilenameTemplate(template string, sample MediaItem, opts DownloadOptions) (string, error) {
+	if err := ValidateFilenameTemplate(template); err != nil {
+		return "", err
+	}
+
+	fields := filenameFields{
+		Username: SanitizeFilename(sample.Username),
+		Date:     formatTimestamp(sample.Date, opts.DateInputFormat, opts.DateOutputFormat),
+		TweetID:  sample.TweetID,
+		Index:    1,
+		Type:     sample.Type,
+	}
+	ext := getExtension(sample.URL, sample.Type)
+
+	return SanitizeFilename(buildMediaFilename(template, fields, ext, opts.MaxFilenameLength)), nil
+}
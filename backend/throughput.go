@@ -0,0 +1,148 @@
+package backend
+
+import (
+	"io"
+	"sync"
+	"time"
+)
+
+const (
+	// throughputSampleInterval is the minimum time between rate samples
+	throughputSampleInterval = 500 * time.Millisecond
+	// throughputSmoothing is the weight of the newest sample in the moving average
+	throughputSmoothing = 0.3
+	// throughputMinSizedFiles is how many files must finish before the ETA
+	// trusts their average size instead of the time per completed item
+	throughputMinSizedFiles = 3
+)
+
+// ThroughputMeter aggregates bytes written by all download workers and
+// reports a smoothed transfer rate
+type ThroughputMeter struct {
+	mu         sync.Mutex
+	started    time.Time
+	bytes      int64 // bytes of finished files and files still in flight
+	doneBytes  int64 // bytes of fully downloaded files
+	doneFiles  int64
+	lastSample time.Time
+	lastBytes  int64
+	rate       float64
+}
+
+// NewThroughputMeter creates a meter starting from now
+func NewThroughputMeter() *ThroughputMeter {
+	now := time.Now()
+	return &ThroughputMeter{started: now, lastSample: now}
+}
+
+// add records n bytes written; safe to call on a nil meter
+func (m *ThroughputMeter) add(n int) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	m.bytes += int64(n)
+	m.mu.Unlock()
+}
+
+// fileDone records a finished file of size bytes for the total size estimate
+func (m *ThroughputMeter) fileDone(size int64) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	m.doneBytes += size
+	m.doneFiles++
+	m.mu.Unlock()
+}
+
+// attemptFailed takes back the n bytes written by a failed attempt, which was
+// discarded, so they count neither toward the rate nor as in flight
+func (m *ThroughputMeter) attemptFailed(n int64) {
+	if m == nil || n <= 0 {
+		return
+	}
+	m.mu.Lock()
+	m.bytes -= n
+	if m.lastBytes > m.bytes {
+		m.lastBytes = m.bytes
+	}
+	m.mu.Unlock()
+}
+
+// BytesPerSec returns the exponentially smoothed transfer rate
+func (m *ThroughputMeter) BytesPerSec() float64 {
+	if m == nil {
+		return 0
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	elapsed := now.Sub(m.lastSample)
+	if elapsed < throughputSampleInterval {
+		return m.rate
+	}
+
+	sample := float64(m.bytes-m.lastBytes) / elapsed.Seconds()
+	if sample < 0 {
+		sample = 0
+	}
+	if m.lastBytes == 0 && m.rate == 0 {
+		m.rate = sample
+	} else {
+		m.rate = throughputSmoothing*sample + (1-throughputSmoothing)*m.rate
+	}
+	m.lastSample = now
+	m.lastBytes = m.bytes
+
+	return m.rate
+}
+
+// SecondsRemaining estimates the time left from the average size of finished
+// files and the current rate. Until a few files have finished, their average
+// size means little, so it extrapolates the time taken per completed item
+// instead. Returns -1 when there is not enough data yet.
+func (m *ThroughputMeter) SecondsRemaining(completed, total int) int {
+	if m == nil {
+		return -1
+	}
+	rate := m.BytesPerSec()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.doneFiles < throughputMinSizedFiles {
+		if completed <= 0 {
+			return -1
+		}
+		perItem := time.Since(m.started).Seconds() / float64(completed)
+		return int(perItem * float64(total-completed))
+	}
+	if rate <= 0 {
+		return -1
+	}
+
+	averageSize := float64(m.doneBytes) / float64(m.doneFiles)
+	inFlight := float64(m.bytes - m.doneBytes)
+	remaining := averageSize*float64(total-completed) - inFlight
+	if remaining < 0 {
+		remaining = 0
+	}
+
+	return int(remaining / rate)
+}
+
+// countingWriter reports every write to a ThroughputMeter
+type countingWriter struct {
+	w     io.Writer
+	meter *ThroughputMeter
+	n     int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	c.meter.add(n)
+	return n, err
+}
@@ -0,0 +1,58 @@
+package backend
+
+import (
+	"testing"
+	"time"
+)
+
+func TestThroughputFailedAttemptNotCounted(t *testing.T) {
+	m := NewThroughputMeter()
+	m.add(4000)
+	m.attemptFailed(4000)
+	m.lastSample = time.Now().Add(-time.Second)
+
+	if rate := m.BytesPerSec(); rate != 0 {
+		t.Errorf("rate = %v after the only transfer failed, want 0", rate)
+	}
+
+	// A completed file after the failure counts normally
+	m.add(1000)
+	m.fileDone(1000)
+	m.lastSample = time.Now().Add(-time.Second)
+	if rate := m.BytesPerSec(); rate < 900 || rate > 1100 {
+		t.Errorf("rate = %v, want about 1000", rate)
+	}
+}
+
+func TestThroughputSecondsRemaining(t *testing.T) {
+	t.Run("no data", func(t *testing.T) {
+		m := NewThroughputMeter()
+		if got := m.SecondsRemaining(0, 10); got != -1 {
+			t.Errorf("got %d, want -1", got)
+		}
+	})
+
+	t.Run("count based until a few files finish", func(t *testing.T) {
+		m := NewThroughputMeter()
+		m.started = time.Now().Add(-10 * time.Second)
+		m.fileDone(1) // one tiny file would skew a size-based estimate
+		if got := m.SecondsRemaining(2, 6); got < 19 || got > 20 {
+			t.Errorf("got %d, want about 20 (5s per item, 4 left)", got)
+		}
+	})
+
+	t.Run("size based", func(t *testing.T) {
+		m := NewThroughputMeter()
+		m.rate = 1000
+		m.lastSample = time.Now()
+		for i := 0; i < throughputMinSizedFiles; i++ {
+			m.add(2000)
+			m.fileDone(2000)
+		}
+		m.add(500) // in flight
+		// 2 files of about 2000 bytes left, less the 500 already written
+		if got := m.SecondsRemaining(3, 5); got != 3 {
+			t.Errorf("got %d, want 3", got)
+		}
+	})
+}
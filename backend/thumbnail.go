@@ -0,0 +1,109 @@
+package backend
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// MaxConcurrentThumbnailFetches is the number of parallel thumbnail fetches
+	MaxConcurrentThumbnailFetches = 6
+)
+
+// GetThumbnailCacheDir returns the directory where thumbnails are cached
+func GetThumbnailCacheDir() string {
+	return filepath.Join(GetDataDir(), "thumbnails")
+}
+
+// GetCachedThumbnailPath returns the cache path for a media URL's thumbnail
+func GetCachedThumbnailPath(mediaURL string) string {
+	hash := sha1.Sum([]byte(GetThumbnailURL(mediaURL)))
+	return filepath.Join(GetThumbnailCacheDir(), hex.EncodeToString(hash[:])+".jpg")
+}
+
+// PrefetchThumbnails downloads thumbnails for image URLs into the disk cache
+// using a bounded worker pool; already cached thumbnails are skipped
+func PrefetchThumbnails(ctx context.Context, urls []string, progress ProgressCallback) (fetched int, failed int, err error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	if err := os.MkdirAll(GetThumbnailCacheDir(), 0755); err != nil {
+		return 0, 0, err
+	}
+
+	// Only images have thumbnail variants
+	var imageURLs []string
+	seen := make(map[string]bool)
+	for _, u := range urls {
+		if !strings.Contains(u, "pbs.twimg.com/media/") || seen[u] {
+			continue
+		}
+		seen[u] = true
+		imageURLs = append(imageURLs, u)
+	}
+
+	total := len(imageURLs)
+	if total == 0 {
+		return 0, 0, nil
+	}
+
+	var fetchedCount int64
+	var failedCount int64
+	var completedCount int64
+
+	urlChan := make(chan string)
+	var wg sync.WaitGroup
+
+	numWorkers := MaxConcurrentThumbnailFetches
+	if numWorkers > total {
+		numWorkers = total
+	}
+
+	for i := 0; i < numWorkers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			client := newHTTPClient(30 * time.Second)
+
+			for mediaURL := range urlChan {
+				cachePath := GetCachedThumbnailPath(mediaURL)
+				if _, err := os.Stat(cachePath); err == nil {
+					atomic.AddInt64(&fetchedCount, 1)
+				} else if err := downloadFileWithContext(ctx, client, GetThumbnailURL(mediaURL), cachePath); err != nil {
+					os.Remove(cachePath)
+					atomic.AddInt64(&failedCount, 1)
+				} else {
+					atomic.AddInt64(&fetchedCount, 1)
+				}
+
+				completed := atomic.AddInt64(&completedCount, 1)
+				if progress != nil {
+					progress(int(completed), total)
+				}
+			}
+		}()
+	}
+
+	for _, u := range imageURLs {
+		select {
+		case <-ctx.Done():
+			close(urlChan)
+			wg.Wait()
+			return int(fetchedCount), int(failedCount), ctx.Err()
+		case urlChan <- u:
+		}
+	}
+	close(urlChan)
+
+	wg.Wait()
+
+	return int(fetchedCount), int(failedCount), nil
+}
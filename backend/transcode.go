@@ -0,0 +1,165 @@
+package backend
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// videoCodec describes an ffmpeg video encoder the transcoder supports
+type videoCodec struct {
+	ext        string   // output container extension
+	defaultCRF int      // used when no CRF is given
+	maxCRF     int      // highest quality value the encoder accepts
+	videoArgs  []string // encoder arguments before -crf
+	audioArgs  []string
+}
+
+// videoCodecs are the supported transcode targets; webm is an alias of vp9
+var videoCodecs = map[string]videoCodec{
+	"h264": {
+		ext:        ".mp4",
+		defaultCRF: 23,
+		maxCRF:     51,
+		videoArgs:  []string{"-c:v", "libx264", "-preset", "medium", "-pix_fmt", "yuv420p"},
+		audioArgs:  []string{"-c:a", "aac", "-b:a", "128k", "-movflags", "+faststart"},
+	},
+	"vp9": {
+		ext:        ".webm",
+		defaultCRF: 32,
+		maxCRF:     63,
+		videoArgs:  []string{"-c:v", "libvpx-vp9", "-b:v", "0", "-row-mt", "1"},
+		audioArgs:  []string{"-c:a", "libopus", "-b:a", "96k"},
+	},
+}
+
+// normalizeVideoCodec lowercases a codec name and resolves aliases
+func normalizeVideoCodec(codec string) string {
+	codec = strings.ToLower(strings.TrimSpace(codec))
+	if codec == "webm" {
+		return "vp9"
+	}
+	return codec
+}
+
+// ValidateTranscodeOptions checks that codec is h264, vp9 or webm and that crf
+// is within the encoder's range (0 = the codec's default)
+func ValidateTranscodeOptions(codec string, crf int) error {
+	target, ok := videoCodecs[normalizeVideoCodec(codec)]
+	if !ok {
+		return fmt.Errorf("unsupported video codec: %s (use h264, vp9 or webm)", codec)
+	}
+	if crf < 0 || crf > target.maxCRF {
+		return fmt.Errorf("crf must be between 0 and %d for %s", target.maxCRF, codec)
+	}
+	return nil
+}
+
+// transcodeVideoFile re-encodes inputPath into outputPath with codec at crf
+func transcodeVideoFile(ctx context.Context, inputPath, outputPath, codec string, crf int) error {
+	target := videoCodecs[codec]
+	args := append([]string{"-i", inputPath}, target.videoArgs...)
+	args = append(args, "-crf", strconv.Itoa(crf))
+	args = append(args, target.audioArgs...)
+	args = append(args, "-y", outputPath)
+
+	cmd := exec.CommandContext(ctx, GetFFmpegPath(), args...)
+	hideWindow(cmd) // Hide console window on Windows
+	output, err := cmd.CombinedOutput()
+	if ctx.Err() != nil {
+		os.Remove(outputPath)
+		return ctx.Err()
+	}
+	if err != nil {
+		os.Remove(outputPath)
+		return fmt.Errorf("ffmpeg error: %v, output: %s", err, string(output))
+	}
+	return nil
+}
+
+// TranscodeVideosInFolder re-encodes the videos in a folder with codec
+func TranscodeVideosInFolder(folderPath string, codec string, crf int, deleteOriginal bool) (converted int, failed int, err error) {
+	return TranscodeVideosProgress(context.Background(), folderPath, codec, crf, deleteOriginal, nil)
+}
+
+// TranscodeVideosProgress re-encodes every video in the folder's videos
+// subfolder (or the folder itself when it has none) as h264 MP4 or VP9 WebM.
+// Output goes next to the input; when it would have the input's name it gets
+// a _<codec> suffix, dropped again if the original is deleted. Non-video
+// files and earlier outputs are skipped. Cancelling ctx kills the running
+// ffmpeg and stops before the next file.
+func TranscodeVideosProgress(ctx context.Context, folderPath string, codec string, crf int, deleteOriginal bool, progress ProgressCallback) (converted int, failed int, err error) {
+	if !IsFFmpegInstalled() {
+		return 0, 0, fmt.Errorf("ffmpeg not installed")
+	}
+	if err := ValidateTranscodeOptions(codec, crf); err != nil {
+		return 0, 0, err
+	}
+	codec = normalizeVideoCodec(codec)
+	target := videoCodecs[codec]
+	if crf == 0 {
+		crf = target.defaultCRF
+	}
+
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	folder := filepath.Clean(folderPath)
+	if info, err := os.Stat(filepath.Join(folder, "videos")); err == nil && info.IsDir() {
+		folder = filepath.Join(folder, "videos")
+	}
+	files, err := os.ReadDir(folder)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to read folder: %v", err)
+	}
+
+	suffix := "_" + codec
+	inputs := []string{}
+	for _, file := range files {
+		name := file.Name()
+		if file.IsDir() || strings.HasPrefix(name, ".") || fileTypeForExt(filepath.Ext(name)) != "video" {
+			continue
+		}
+		if strings.HasSuffix(strings.TrimSuffix(name, filepath.Ext(name)), suffix) {
+			continue
+		}
+		inputs = append(inputs, filepath.Join(folder, name))
+	}
+
+	for i, inputPath := range inputs {
+		if ctx.Err() != nil {
+			break
+		}
+
+		stem := strings.TrimSuffix(inputPath, filepath.Ext(inputPath))
+		outputPath := stem + target.ext
+		sameName := strings.EqualFold(outputPath, inputPath)
+		if sameName {
+			outputPath = stem + suffix + target.ext
+		}
+
+		if err := transcodeVideoFile(ctx, inputPath, outputPath, codec, crf); err != nil {
+			if ctx.Err() == nil {
+				failed++
+			}
+		} else {
+			converted++
+			if deleteOriginal {
+				if err := os.Remove(inputPath); err == nil && sameName {
+					os.Rename(outputPath, inputPath)
+				}
+			}
+		}
+
+		if progress != nil {
+			progress(i+1, len(inputs))
+		}
+	}
+
+	return converted, failed, ctx.Err()
+}
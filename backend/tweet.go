@@ -0,0 +1,130 @@
+package backend
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// tweetStatusPattern matches the numeric ID in a status URL
+var tweetStatusPattern = regexp.MustCompile(`/status(?:es)?/(\d+)`)
+
+// tweetIDPattern matches a bare numeric tweet ID
+var tweetIDPattern = regexp.MustCompile(`^\d+$`)
+
+// ErrTweetUnavailable is returned when a tweet is deleted, protected or has no media
+var ErrTweetUnavailable = errors.New("tweet unavailable: it may have been deleted, be from a protected account, or contain no media")
+
+// tweetUnavailableMarker is the extractor's message for unavailable tweets
+const tweetUnavailableMarker = "Tweet unavailable"
+
+// TweetBatchError represents a URL that could not be extracted in a batch
+type TweetBatchError struct {
+	URL   string `json:"url"`
+	Error string `json:"error"`
+}
+
+// TweetBatchResponse represents the aggregated result of a tweet batch extraction
+type TweetBatchResponse struct {
+	TwitterResponse
+	Errors []TweetBatchError `json:"errors"`
+}
+
+// parseTweetID extracts the tweet ID from a status URL (twitter.com, x.com,
+// mobile., with or without query strings) or a bare ID
+func parseTweetID(urlOrID string) (string, error) {
+	input := strings.TrimSpace(urlOrID)
+	if tweetIDPattern.MatchString(input) {
+		return input, nil
+	}
+
+	// Ignore query strings and fragments such as ?s=20 or #m
+	if idx := strings.IndexAny(input, "?#"); idx >= 0 {
+		input = input[:idx]
+	}
+
+	if match := tweetStatusPattern.FindStringSubmatch(input); match != nil {
+		return match[1], nil
+	}
+
+	return "", fmt.Errorf("invalid tweet URL or ID: %s", urlOrID)
+}
+
+// ExtractTweet extracts media from a single tweet given its URL or ID
+func ExtractTweet(urlOrID string, authToken string) (*TwitterResponse, error) {
+	tweetID, err := parseTweetID(urlOrID)
+	if err != nil {
+		return nil, err
+	}
+
+	args := []string{"--token", authToken, "--json", "tweet", tweetID}
+	response, err := currentExtractor().Extract(args, 0)
+	if err != nil {
+		if strings.Contains(err.Error(), tweetUnavailableMarker) {
+			return nil, ErrTweetUnavailable
+		}
+		return nil, err
+	}
+
+	if len(response.Timeline) == 0 {
+		return nil, ErrTweetUnavailable
+	}
+
+	return response, nil
+}
+
+// ExtractTweetsBatch extracts media from a list of tweet URLs and aggregates
+// the results into a single response
+func ExtractTweetsBatch(urls []string, authToken string) (*TweetBatchResponse, error) {
+	result := &TweetBatchResponse{
+		TwitterResponse: TwitterResponse{
+			Timeline: []TimelineEntry{},
+		},
+		Errors: []TweetBatchError{},
+	}
+
+	seen := make(map[string]bool)
+	for _, rawURL := range urls {
+		rawURL = strings.TrimSpace(rawURL)
+		if rawURL == "" {
+			continue
+		}
+
+		tweetID, err := parseTweetID(rawURL)
+		if err != nil {
+			result.Errors = append(result.Errors, TweetBatchError{URL: rawURL, Error: err.Error()})
+			continue
+		}
+
+		// Skip repeated tweets
+		if seen[tweetID] {
+			continue
+		}
+		seen[tweetID] = true
+
+		response, err := ExtractTweet(tweetID, authToken)
+		if err != nil {
+			result.Errors = append(result.Errors, TweetBatchError{URL: rawURL, Error: err.Error()})
+			continue
+		}
+
+		// Keep the first account info found
+		if result.AccountInfo.Nick == "" {
+			result.AccountInfo = response.AccountInfo
+		}
+
+		result.Timeline = append(result.Timeline, response.Timeline...)
+	}
+
+	if len(seen) == 0 && len(result.Errors) == 0 {
+		return nil, fmt.Errorf("no URLs provided")
+	}
+
+	result.TotalURLs = len(result.Timeline)
+	result.Metadata = ExtractMetadata{
+		NewEntries: len(result.Timeline),
+	}
+
+	return result, nil
+}
@@ -2,14 +2,109 @@ package backend
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"net/url"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"regexp"
 	"runtime"
 	"strings"
+	"time"
 )
 
+// ErrAccountProtected is returned when the account is protected and the auth
+// token's account doesn't follow it
+var ErrAccountProtected = errors.New("this account is protected and your auth token's account doesn't follow it")
+
+// accountProtectedMarker is the extractor's message for protected accounts
+const accountProtectedMarker = "Account protected"
+
+// classifyExtractorError replaces extractor failures that have a known cause
+// with their typed error. Exit codes are mapped by the extractor itself; the
+// marker covers older extractors that exit with 1 for everything.
+func classifyExtractorError(err error) error {
+	if err != nil && !errors.Is(err, ErrAccountProtected) && strings.Contains(err.Error(), accountProtectedMarker) {
+		return ErrAccountProtected
+	}
+	return err
+}
+
+// profileURLPattern matches a profile URL on x.com or twitter.com, including
+// the www. and mobile. subdomains, and captures the handle
+var profileURLPattern = regexp.MustCompile(`(?i)^(?:https?://)?(?:(?:www|mobile)\.)?(?:x|twitter)\.com/@?([^/?#]+)`)
+
+// inferMediaType guesses photo, video or gif from a media URL for entries the
+// extractor left untyped; returns an empty string when it can't tell
+func inferMediaType(mediaURL string) string {
+	lower := strings.ToLower(mediaURL)
+	switch {
+	case strings.Contains(lower, "/tweet_video/") || strings.Contains(lower, "/tweet_video_thumb/"):
+		return "gif"
+	case strings.Contains(lower, "video.twimg.com") || strings.Contains(lower, ".mp4"):
+		return "video"
+	case strings.Contains(lower, "/media/") || strings.Contains(lower, "format=jpg") || strings.Contains(lower, "format=png"):
+		return "photo"
+	}
+	return ""
+}
+
+// normalizeUsername extracts the bare handle from a pasted profile URL,
+// @handle or plain handle; id:123 inputs are passed through unchanged
+func normalizeUsername(input string) string {
+	username := strings.TrimSpace(input)
+	if strings.HasPrefix(username, "id:") {
+		return username
+	}
+
+	if match := profileURLPattern.FindStringSubmatch(username); match != nil {
+		username = match[1]
+	}
+
+	// Drop query strings, fragments and trailing slashes
+	if idx := strings.IndexAny(username, "?#"); idx >= 0 {
+		username = username[:idx]
+	}
+	username = strings.TrimRight(username, "/")
+
+	return strings.TrimPrefix(username, "@")
+}
+
+// maxHandleLength is the longest handle X allows; a longer number is a user ID
+const maxHandleLength = 15
+
+// timelineTarget returns the account argument for the extractor: id:<UserID>
+// when UserID is set, otherwise the normalized Username, turning a number
+// too long to be a handle into a user ID
+func timelineTarget(req TimelineRequest) (string, error) {
+	if userID := strings.TrimPrefix(strings.TrimSpace(req.UserID), "id:"); userID != "" {
+		if !isNumeric(userID) {
+			return "", fmt.Errorf("invalid user ID: %s", req.UserID)
+		}
+		return "id:" + userID, nil
+	}
+
+	username := normalizeUsername(req.Username)
+	if len(username) > maxHandleLength && isNumeric(username) {
+		return "id:" + username, nil
+	}
+	return username, nil
+}
+
+// isNumeric reports whether s is a non-empty string of ASCII digits
+func isNumeric(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
 // getExecutableName returns the appropriate executable name for the current OS
 func getExecutableName() string {
 	if runtime.GOOS == "windows" {
@@ -26,6 +121,7 @@ type AccountInfo struct {
 	FollowersCount int    `json:"followers_count"`
 	FriendsCount   int    `json:"friends_count"`
 	ProfileImage   string `json:"profile_image"`
+	ProfileBanner  string `json:"profile_banner,omitempty"`
 	StatusesCount  int    `json:"statuses_count"`
 }
 
@@ -65,14 +161,20 @@ type TimelineEntry struct {
 	TweetID   TweetIDString `json:"tweet_id"`
 	Type      string        `json:"type"`
 	IsRetweet bool          `json:"is_retweet"`
+	Text      string        `json:"text,omitempty"`
+	AltText   string        `json:"alt_text,omitempty"` // accessibility description of the media
+	Likes     *int          `json:"likes,omitempty"`    // nil when the extractor didn't report it
+	Retweets  *int          `json:"retweets,omitempty"` // nil when the extractor didn't report it
+	Replies   *int          `json:"replies,omitempty"`  // nil when the extractor didn't report it
 }
 
 // Metadata represents extraction metadata
 type ExtractMetadata struct {
-	NewEntries int  `json:"new_entries"`
-	Page       int  `json:"page"`
-	BatchSize  int  `json:"batch_size"`
-	HasMore    bool `json:"has_more"`
+	NewEntries int    `json:"new_entries"`
+	Page       int    `json:"page"`
+	BatchSize  int    `json:"batch_size"`
+	HasMore    bool   `json:"has_more"`
+	Cursor     string `json:"cursor,omitempty"` // opaque position to resume from, if supported
 }
 
 // TwitterResponse represents the full response from metadata-extractor
@@ -85,13 +187,30 @@ type TwitterResponse struct {
 
 // TimelineRequest represents request parameters for timeline extraction
 type TimelineRequest struct {
+	// Username is a handle, profile URL or id:<user ID>; a number longer than
+	// a handle can be (15 characters) is taken as a user ID. UserID, when set,
+	// takes precedence, so archives keep working after a rename.
 	Username     string `json:"username"`
+	UserID       string `json:"user_id"`
 	AuthToken    string `json:"auth_token"`
 	TimelineType string `json:"timeline_type"` // media, timeline, tweets, with_replies
 	BatchSize    int    `json:"batch_size"`    // 0 = all
 	Page         int    `json:"page"`
 	MediaType    string `json:"media_type"` // all, image, video, gif
 	Retweets     bool   `json:"retweets"`
+	MaxEntries   int    `json:"max_entries"` // 0 = no limit
+	Cursor       string `json:"cursor"`      // resume position from a previous response, overrides Page
+	// AdaptiveBatch lets ExtractFullTimeline tune BatchSize to rate limiting
+	AdaptiveBatch bool `json:"adaptive_batch"`
+	// Random pause between pages in ExtractFullTimeline, in milliseconds.
+	// Both 0 = DefaultPageDelayMin..DefaultPageDelayMax, PageDelayMin < 0 = none.
+	PageDelayMin int `json:"page_delay_min_ms"`
+	PageDelayMax int `json:"page_delay_max_ms"`
+	// SinceID only extracts tweets newer than this ID, stopping once the
+	// timeline reaches known tweets (0 = no bound)
+	SinceID int64 `json:"since_id"`
+	// NoCache always runs the extractor instead of reusing a cached result
+	NoCache bool `json:"no_cache"`
 }
 
 // DateRangeRequest represents request parameters for date range extraction
@@ -103,21 +222,17 @@ type DateRangeRequest struct {
 	MediaFilter string `json:"media_filter"`
 }
 
-// ExtractTimeline extracts media from user timeline
+// ExtractTimeline extracts media from user timeline. A result cached within
+// GetExtractCacheTTL for the same parameters is returned without running the
+// extractor, unless req.NoCache is set or the token's session cookies haven't
+// been captured yet in this run (a cache hit would leave SessionCookies empty).
 func ExtractTimeline(req TimelineRequest) (*TwitterResponse, error) {
-	// Create temporary file for metadata-extractor
-	tempDir := os.TempDir()
-	exePath := filepath.Join(tempDir, getExecutableName())
-
-	// Write embedded binary to temporary file
-	err := os.WriteFile(exePath, metadataExtractorBin, 0755)
+	// Build command arguments - global args first, then subcommand
+	target, err := timelineTarget(req)
 	if err != nil {
-		return nil, fmt.Errorf("failed to write metadata-extractor: %v", err)
+		return nil, err
 	}
-	defer os.Remove(exePath)
-
-	// Build command arguments - global args first, then subcommand
-	args := []string{"--token", req.AuthToken, "--json", "timeline", req.Username}
+	args := []string{"--token", req.AuthToken, "--json", "timeline", target}
 
 	// Add optional parameters for timeline subcommand
 	if req.TimelineType != "" && req.TimelineType != "media" {
@@ -141,48 +256,44 @@ func ExtractTimeline(req TimelineRequest) (*TwitterResponse, error) {
 		args = append(args, "--no-retweets")
 	}
 
-	// Execute command with UTF-8 encoding
-	cmd := exec.Command(exePath, args...)
-	cmd.Env = append(os.Environ(), "PYTHONIOENCODING=utf-8", "PYTHONUTF8=1")
-	hideWindow(cmd) // Hide console window on Windows
-	output, err := cmd.CombinedOutput()
-	if err != nil {
-		return nil, fmt.Errorf("failed to execute metadata-extractor: %v, output: %s", err, string(output))
+	if req.MaxEntries > 0 {
+		args = append(args, "--max-entries", fmt.Sprintf("%d", req.MaxEntries))
 	}
 
-	// Find JSON in output (skip any info messages)
-	jsonStr := extractJSON(string(output))
-	if jsonStr == "" {
-		return nil, fmt.Errorf("no JSON found in output: %s", string(output))
+	if req.Cursor != "" {
+		args = append(args, "--cursor", req.Cursor)
 	}
 
-	// Parse JSON response
-	var response TwitterResponse
-	if err := json.Unmarshal([]byte(jsonStr), &response); err != nil {
-		return nil, fmt.Errorf("failed to parse response: %v, output: %s", err, jsonStr)
+	if req.SinceID > 0 {
+		args = append(args, "--since-id", fmt.Sprintf("%d", req.SinceID))
 	}
 
-	return &response, nil
-}
-
-// ExtractDateRange extracts media based on date range
-func ExtractDateRange(req DateRangeRequest) (*TwitterResponse, error) {
-	// Create temporary file for metadata-extractor
-	tempDir := os.TempDir()
-	exePath := filepath.Join(tempDir, getExecutableName())
+	ttl := time.Duration(0)
+	if !req.NoCache {
+		ttl = GetExtractCacheTTL()
+	}
+	key := extractCacheKey(args)
+	if req.AuthToken == "" || hasSessionCookies(req.AuthToken) {
+		if response, ok := cachedExtraction(key, ttl); ok {
+			return response, nil
+		}
+	}
 
-	// Write embedded binary to temporary file
-	err := os.WriteFile(exePath, metadataExtractorBin, 0755)
+	response, err := currentExtractor().Extract(args, req.MaxEntries)
 	if err != nil {
-		return nil, fmt.Errorf("failed to write metadata-extractor: %v", err)
+		return nil, classifyExtractorError(err)
 	}
-	defer os.Remove(exePath)
+	cacheExtraction(key, response, ttl)
+	return response, nil
+}
 
+// ExtractDateRange extracts media based on date range
+func ExtractDateRange(req DateRangeRequest) (*TwitterResponse, error) {
 	// Build command arguments - global args first, then subcommand
 	args := []string{
 		"--token", req.AuthToken,
 		"--json",
-		"daterange", req.Username,
+		"daterange", normalizeUsername(req.Username),
 		"--start-date", req.StartDate,
 		"--end-date", req.EndDate,
 	}
@@ -192,25 +303,93 @@ func ExtractDateRange(req DateRangeRequest) (*TwitterResponse, error) {
 		args = append(args, "--filter", req.MediaFilter)
 	}
 
+	response, err := currentExtractor().Extract(args, 0)
+	return response, classifyExtractorError(err)
+}
+
+// execMetadataExtractor writes the embedded metadata-extractor to a temporary
+// file and runs it, returning its combined output
+func execMetadataExtractor(args []string) ([]byte, error) {
+	// Create temporary file for metadata-extractor
+	tempDir := os.TempDir()
+	exePath := filepath.Join(tempDir, getExecutableName())
+
+	// Write embedded binary to temporary file
+	err := os.WriteFile(exePath, metadataExtractorBin, 0755)
+	if err != nil {
+		return nil, fmt.Errorf("failed to write metadata-extractor: %v", err)
+	}
+	defer os.Remove(exePath)
+
 	// Execute command with UTF-8 encoding
 	cmd := exec.Command(exePath, args...)
 	cmd.Env = append(os.Environ(), "PYTHONIOENCODING=utf-8", "PYTHONUTF8=1")
 	hideWindow(cmd) // Hide console window on Windows
-	output, err := cmd.CombinedOutput()
-	if err != nil {
-		return nil, fmt.Errorf("failed to execute metadata-extractor: %v, output: %s", err, string(output))
-	}
+	return cmd.CombinedOutput()
+}
+
+// parseTwitterResponse decodes the extractor JSON, streaming the timeline so
+// that entries past maxEntries are discarded while parsing (0 = no limit)
+func parseTwitterResponse(jsonStr string, maxEntries int) (*TwitterResponse, error) {
+	decoder := json.NewDecoder(strings.NewReader(jsonStr))
 
-	// Find JSON in output (skip any info messages)
-	jsonStr := extractJSON(string(output))
-	if jsonStr == "" {
-		return nil, fmt.Errorf("no JSON found in output: %s", string(output))
+	if _, err := decoder.Token(); err != nil {
+		return nil, err
 	}
 
-	// Parse JSON response
 	var response TwitterResponse
-	if err := json.Unmarshal([]byte(jsonStr), &response); err != nil {
-		return nil, fmt.Errorf("failed to parse response: %v, output: %s", err, jsonStr)
+	capped := false
+	for decoder.More() {
+		keyToken, err := decoder.Token()
+		if err != nil {
+			return nil, err
+		}
+		key, _ := keyToken.(string)
+
+		switch key {
+		case "account_info":
+			err = decoder.Decode(&response.AccountInfo)
+		case "total_urls":
+			err = decoder.Decode(&response.TotalURLs)
+		case "metadata":
+			err = decoder.Decode(&response.Metadata)
+		case "timeline":
+			if _, err := decoder.Token(); err != nil {
+				return nil, err
+			}
+			for decoder.More() {
+				if maxEntries > 0 && len(response.Timeline) >= maxEntries {
+					// Skip remaining entries without keeping them
+					var skipped json.RawMessage
+					if err := decoder.Decode(&skipped); err != nil {
+						return nil, err
+					}
+					capped = true
+					continue
+				}
+				var entry TimelineEntry
+				if err := decoder.Decode(&entry); err != nil {
+					return nil, err
+				}
+				if entry.Type == "" {
+					entry.Type = inferMediaType(entry.URL)
+				}
+				response.Timeline = append(response.Timeline, entry)
+			}
+			_, err = decoder.Token()
+		default:
+			var skipped json.RawMessage
+			err = decoder.Decode(&skipped)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if capped {
+		response.TotalURLs = len(response.Timeline)
+		response.Metadata.NewEntries = len(response.Timeline)
+		response.Metadata.HasMore = true
 	}
 
 	return &response, nil
@@ -224,20 +403,69 @@ func extractJSON(output string) string {
 		return ""
 	}
 
-	// Find the matching closing brace
-	depth := 0
-	for i := start; i < len(output); i++ {
-		if output[i] == '{' {
-			depth++
-		} else if output[i] == '}' {
-			depth--
-			if depth == 0 {
-				return output[start : i+1]
-			}
+	// Decode a single value so braces inside strings don't end it early
+	var raw json.RawMessage
+	if err := json.NewDecoder(strings.NewReader(output[start:])).Decode(&raw); err != nil {
+		return ""
+	}
+	return string(raw)
+}
+
+// imageFormats are the formats pbs.twimg.com serves through the format parameter
+var imageFormats = map[string]bool{"jpg": true, "png": true, "webp": true}
+
+// ValidateImageFormat checks a forced image format; "" keeps the served one
+func ValidateImageFormat(format string) error {
+	if format != "" && !imageFormats[format] {
+		return fmt.Errorf("unsupported image format: %s (use jpg, png or webp)", format)
+	}
+	return nil
+}
+
+// GetOriginalMediaURL converts a Twitter image URL to its original-quality
+// variant (name=orig), the inverse of GetThumbnailURL. Other URLs are
+// returned unchanged.
+func GetOriginalMediaURL(mediaURL string) string {
+	return mediaURLVariant(mediaURL, "", "orig")
+}
+
+// mediaURLVariant rewrites a pbs.twimg.com image URL to the query form
+// ?format=<format>&name=<name>, converting legacy XXX.jpg and XXX.jpg:large
+// URLs. format "" keeps the URL's own format, defaulting to jpg; name ""
+// keeps the URL's own size.
+func mediaURLVariant(mediaURL, format, name string) string {
+	if !strings.Contains(mediaURL, "pbs.twimg.com/media/") {
+		return mediaURL
+	}
+	parsed, err := url.Parse(mediaURL)
+	if err != nil {
+		return mediaURL
+	}
+
+	path := parsed.Path
+	if i := strings.LastIndex(path, ":"); i > strings.LastIndex(path, "/") {
+		path = path[:i]
+	}
+	query := parsed.Query()
+	if ext := filepath.Ext(path); ext != "" {
+		path = strings.TrimSuffix(path, ext)
+		if query.Get("format") == "" {
+			query.Set("format", strings.TrimPrefix(ext, "."))
 		}
 	}
+	if format != "" {
+		query.Set("format", format)
+	}
+	if query.Get("format") == "" {
+		query.Set("format", "jpg")
+	}
+	if name != "" {
+		query.Set("name", name)
+	}
 
-	return ""
+	parsed.Path = path
+	parsed.RawQuery = query.Encode()
+	return parsed.String()
 }
 
 // GetThumbnailURL converts a Twitter media URL to thumbnail size
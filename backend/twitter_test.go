@@ -0,0 +1,104 @@
+package backend
+
+import "testing"
+
+func TestNormalizeUsername(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"someone", "someone"},
+		{"  someone  ", "someone"},
+		{"@someone", "someone"},
+		{"https://x.com/someone", "someone"},
+		{"https://twitter.com/someone/", "someone"},
+		{"http://www.x.com/someone", "someone"},
+		{"https://mobile.twitter.com/someone", "someone"},
+		{"x.com/someone", "someone"},
+		{"https://X.COM/someone", "someone"},
+		{"https://x.com/@someone", "someone"},
+		{"https://x.com/someone/media", "someone"},
+		{"https://x.com/someone/status/1234567890", "someone"},
+		{"https://x.com/someone?lang=en", "someone"},
+		{"https://x.com/someone#top", "someone"},
+		{"someone?ref=share", "someone"},
+		{"someone/", "someone"},
+		{"id:12345", "id:12345"},
+		{"1234567890123456789", "1234567890123456789"},
+	}
+
+	for _, tt := range tests {
+		if got := normalizeUsername(tt.input); got != tt.want {
+			t.Errorf("normalizeUsername(%q) = %q, want %q", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestTimelineTarget(t *testing.T) {
+	tests := []struct {
+		name    string
+		req     TimelineRequest
+		want    string
+		wantErr bool
+	}{
+		{"handle", TimelineRequest{Username: "@someone"}, "someone", false},
+		{"id prefix", TimelineRequest{Username: "id:12345"}, "id:12345", false},
+		{"number up to 15 characters is a handle", TimelineRequest{Username: "123456789012345"}, "123456789012345", false},
+		{"longer number is a user ID", TimelineRequest{Username: "1234567890123456"}, "id:1234567890123456", false},
+		{"user ID from a profile URL", TimelineRequest{Username: "https://x.com/1234567890123456789/media"}, "id:1234567890123456789", false},
+		{"UserID takes precedence", TimelineRequest{Username: "someone", UserID: "987"}, "id:987", false},
+		{"UserID with prefix", TimelineRequest{UserID: " id:987 "}, "id:987", false},
+		{"invalid UserID", TimelineRequest{UserID: "abc"}, "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := timelineTarget(tt.req)
+			if (err != nil) != tt.wantErr || got != tt.want {
+				t.Errorf("timelineTarget(%+v) = %q, %v, want %q (error %v)", tt.req, got, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestInferMediaType(t *testing.T) {
+	tests := []struct {
+		url  string
+		want string
+	}{
+		{"https://pbs.twimg.com/media/GAbc123.jpg", "photo"},
+		{"https://pbs.twimg.com/media/GAbc123?format=jpg&name=orig", "photo"},
+		{"https://pbs.twimg.com/media/GAbc123?format=png&name=large", "photo"},
+		{"https://video.twimg.com/ext_tw_video/1/pu/vid/avc1/1280x720/abc.mp4?tag=12", "video"},
+		{"https://video.twimg.com/amplify_video/1/vid/avc1/720x1280/abc.mp4", "video"},
+		{"https://VIDEO.TWIMG.COM/ext_tw_video/1/abc.MP4", "video"},
+		{"https://video.twimg.com/tweet_video/GAbc123.mp4", "gif"},
+		{"https://pbs.twimg.com/tweet_video_thumb/GAbc123.jpg", "gif"},
+		{"https://example.com/clip.mp4", "video"},
+		{"https://example.com/image.jpg", ""},
+		{"https://pbs.twimg.com/profile_images/1/avatar.jpg", ""},
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+		if got := inferMediaType(tt.url); got != tt.want {
+			t.Errorf("inferMediaType(%q) = %q, want %q", tt.url, got, tt.want)
+		}
+	}
+}
+
+func TestParseExtractorOutputBracesInText(t *testing.T) {
+	output := `Fetching timeline...
+{"account_info": {"name": "someone"}, "total_urls": 1, "timeline": [
+	{"url": "https://pbs.twimg.com/media/a.jpg", "date": "2024-01-01T00:00:00", "tweet_id": 1, "type": "photo", "text": "set {a, b} and a stray } or {"}
+]}
+done`
+
+	response, err := parseExtractorOutput([]byte(output), 0)
+	if err != nil {
+		t.Fatalf("parseExtractorOutput: %v", err)
+	}
+	if len(response.Timeline) != 1 || response.Timeline[0].Text != "set {a, b} and a stray } or {" {
+		t.Errorf("got %+v", response.Timeline)
+	}
+}
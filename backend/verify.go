@@ -0,0 +1,196 @@
+package backend
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Problems reported by VerifyArchive
+const (
+	VerifyMissing      = "missing"       // the file is gone
+	VerifySizeMismatch = "size_mismatch" // the size differs from the recorded one
+	VerifyHashMismatch = "hash_mismatch" // the SHA-256 differs from the recorded one
+)
+
+// VerifyItem is an archive index entry checked by VerifyArchive
+type VerifyItem struct {
+	File    string `json:"file"` // relative to the account folder
+	URL     string `json:"url"`
+	TweetID int64  `json:"tweet_id"`
+	Problem string `json:"problem,omitempty"`
+}
+
+// VerifyResult sorts the entries of an archive index by the state of their file
+type VerifyResult struct {
+	OK         []VerifyItem `json:"ok"`
+	Missing    []VerifyItem `json:"missing"`
+	Corrupt    []VerifyItem `json:"corrupt"`
+	Unverified []VerifyItem `json:"unverified"` // present, but indexed before hashes were recorded
+}
+
+// RepairResult reports what RepairArchive re-downloaded
+type RepairResult struct {
+	Verify   VerifyResult `json:"verify"` // the state before repairing
+	Repaired []VerifyItem `json:"repaired"`
+	Failed   []VerifyItem `json:"failed"` // Problem holds the download error
+}
+
+// fileSHA256 returns the hex SHA-256 and size of a file
+func fileSHA256(path string) (string, int64, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", 0, err
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	size, err := io.Copy(hash, file)
+	if err != nil {
+		return "", 0, err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), size, nil
+}
+
+// VerifyArchive checks every entry of the archive index in folderPath (an
+// account folder) against the file on disk: it must exist and match the size
+// and SHA-256 recorded at download time. Entries indexed before hashes were
+// recorded are only checked for existence and size, if known.
+func VerifyArchive(folderPath string) (VerifyResult, error) {
+	result := VerifyResult{
+		OK:         []VerifyItem{},
+		Missing:    []VerifyItem{},
+		Corrupt:    []VerifyItem{},
+		Unverified: []VerifyItem{},
+	}
+
+	indexPath := filepath.Join(folderPath, archiveIndexFilename)
+	if _, err := os.Stat(indexPath); err != nil {
+		return result, fmt.Errorf("no archive index in %s", folderPath)
+	}
+	entries, err := readArchiveIndex(indexPath)
+	if err != nil {
+		return result, fmt.Errorf("failed to read archive index: %v", err)
+	}
+
+	for _, entry := range entries {
+		item := VerifyItem{File: entry.File, URL: entry.URL, TweetID: entry.TweetID}
+		path := filepath.Join(folderPath, filepath.FromSlash(entry.File))
+
+		info, err := os.Stat(path)
+		if err != nil || !info.Mode().IsRegular() {
+			item.Problem = VerifyMissing
+			result.Missing = append(result.Missing, item)
+			continue
+		}
+		if entry.Size > 0 && info.Size() != entry.Size {
+			item.Problem = VerifySizeMismatch
+			result.Corrupt = append(result.Corrupt, item)
+			continue
+		}
+		if entry.SHA256 == "" {
+			result.Unverified = append(result.Unverified, item)
+			continue
+		}
+
+		sum, _, err := fileSHA256(path)
+		if err != nil {
+			item.Problem = VerifyMissing
+			result.Missing = append(result.Missing, item)
+			continue
+		}
+		if sum != entry.SHA256 {
+			item.Problem = VerifyHashMismatch
+			result.Corrupt = append(result.Corrupt, item)
+			continue
+		}
+		result.OK = append(result.OK, item)
+	}
+
+	return result, nil
+}
+
+// RepairArchive verifies the archive in folderPath and re-downloads every
+// missing or corrupt file from its indexed source URL, recording the new size
+// and hash in the index. Files whose source is gone are listed as failed.
+// Cancelling ctx stops before the next file.
+func RepairArchive(ctx context.Context, folderPath string, progress ProgressCallback) (*RepairResult, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	verify, err := VerifyArchive(folderPath)
+	if err != nil {
+		return nil, err
+	}
+	result := &RepairResult{Verify: verify, Repaired: []VerifyItem{}, Failed: []VerifyItem{}}
+
+	bad := append(append([]VerifyItem{}, verify.Missing...), verify.Corrupt...)
+	if len(bad) == 0 {
+		return result, nil
+	}
+
+	client := newHTTPClient(60 * time.Second)
+	repaired := make(map[string]ArchiveIndexEntry) // by file
+	for i, item := range bad {
+		if ctx.Err() != nil {
+			break
+		}
+
+		path := filepath.Join(folderPath, filepath.FromSlash(item.File))
+		err := os.MkdirAll(filepath.Dir(path), 0755)
+		if err == nil && item.URL == "" {
+			err = fmt.Errorf("no source URL recorded")
+		}
+		if err == nil {
+			_, err = withDownloadRetries(ctx, 0, 0, nil, func() error {
+				return downloadFileCounted(ctx, client, item.URL, path, nil)
+			})
+		}
+
+		var sum string
+		var size int64
+		if err == nil {
+			sum, size, err = fileSHA256(path)
+		}
+		if err != nil {
+			if ctx.Err() == nil {
+				item.Problem = err.Error()
+				result.Failed = append(result.Failed, item)
+			}
+		} else {
+			repaired[item.File] = ArchiveIndexEntry{SHA256: sum, Size: size}
+			result.Repaired = append(result.Repaired, item)
+		}
+
+		if progress != nil {
+			progress(i+1, len(bad))
+		}
+	}
+
+	// Record the hashes of the repaired files, which may differ from the old
+	// ones if the CDN re-encoded the media
+	if len(repaired) > 0 {
+		indexPath := filepath.Join(folderPath, archiveIndexFilename)
+		entries, err := readArchiveIndex(indexPath)
+		if err != nil {
+			return result, fmt.Errorf("failed to read archive index: %v", err)
+		}
+		for i, entry := range entries {
+			if fixed, ok := repaired[entry.File]; ok {
+				entries[i].SHA256 = fixed.SHA256
+				entries[i].Size = fixed.Size
+			}
+		}
+		if err := writeArchiveIndex(indexPath, entries); err != nil {
+			return result, err
+		}
+	}
+
+	return result, ctx.Err()
+}
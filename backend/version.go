@@ -0,0 +1,44 @@
+package backend
+
+import (
+	"os/exec"
+	"strings"
+	"sync"
+)
+
+var (
+	extractorVersion     string
+	extractorVersionOnce sync.Once
+)
+
+// GetExtractorVersion returns the embedded metadata-extractor version; the
+// extractor is only run once and the result is cached
+func GetExtractorVersion() string {
+	extractorVersionOnce.Do(func() {
+		output, err := execMetadataExtractor([]string{"--version"})
+		if err != nil {
+			extractorVersion = "unknown"
+			return
+		}
+		extractorVersion = strings.TrimSpace(string(output))
+	})
+	return extractorVersion
+}
+
+// GetFFmpegVersion returns the first line of `ffmpeg -version`, or an empty
+// string if ffmpeg is not installed
+func GetFFmpegVersion() string {
+	if !IsFFmpegInstalled() {
+		return ""
+	}
+
+	cmd := exec.Command(GetFFmpegPath(), "-version")
+	hideWindow(cmd) // Hide console window on Windows
+	output, err := cmd.Output()
+	if err != nil {
+		return ""
+	}
+
+	firstLine, _, _ := strings.Cut(string(output), "\n")
+	return strings.TrimSpace(firstLine)
+}
@@ -0,0 +1,138 @@
+package backend
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// xmpNamespace is the JPEG APP1 identifier for an XMP packet
+const xmpNamespace = "http://ns.adobe.com/xap/1.0/\x00"
+
+// maxJPEGSegmentPayload is the largest payload a JPEG marker segment can hold
+const maxJPEGSegmentPayload = 65533
+
+// mediaSidecar is written next to files that cannot hold embedded metadata
+type mediaSidecar struct {
+	TweetID int64  `json:"tweet_id"`
+	URL     string `json:"url"`
+	Date    string `json:"date"`
+	Text    string `json:"text"`
+	AltText string `json:"alt_text,omitempty"`
+}
+
+// xmlEscape escapes text for use inside an XML element
+func xmlEscape(text string) string {
+	var buf bytes.Buffer
+	xml.EscapeText(&buf, []byte(text))
+	return buf.String()
+}
+
+// buildXMPPacket builds an XMP packet with the tweet text as dc:description
+// and the tweet URL as dc:source
+func buildXMPPacket(text, sourceURL string) []byte {
+	return []byte(`<?xpacket begin="" id="W5M0MpCehiHzreSzNTczkc9d"?>` +
+		`<x:xmpmeta xmlns:x="adobe:ns:meta/">` +
+		`<rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#">` +
+		`<rdf:Description rdf:about="" xmlns:dc="http://purl.org/dc/elements/1.1/">` +
+		`<dc:description><rdf:Alt><rdf:li xml:lang="x-default">` + xmlEscape(text) + `</rdf:li></rdf:Alt></dc:description>` +
+		`<dc:source>` + xmlEscape(sourceURL) + `</dc:source>` +
+		`</rdf:Description></rdf:RDF></x:xmpmeta>` +
+		`<?xpacket end="w"?>`)
+}
+
+// insertJPEGXMP returns the JPEG data with an XMP APP1 segment inserted after
+// SOI and any JFIF APP0 segment
+func insertJPEGXMP(data []byte, packet []byte) ([]byte, error) {
+	if len(data) < 4 || data[0] != 0xFF || data[1] != 0xD8 {
+		return nil, fmt.Errorf("not a JPEG file")
+	}
+
+	payload := append([]byte(xmpNamespace), packet...)
+	if len(payload) > maxJPEGSegmentPayload {
+		return nil, fmt.Errorf("metadata too large for a JPEG segment")
+	}
+
+	insertAt := 2
+	if data[2] == 0xFF && data[3] == 0xE0 && len(data) >= 6 {
+		insertAt = 4 + int(data[4])<<8 + int(data[5])
+		if insertAt > len(data) {
+			return nil, fmt.Errorf("corrupt JPEG header")
+		}
+	}
+
+	length := len(payload) + 2
+	segment := append([]byte{0xFF, 0xE1, byte(length >> 8), byte(length)}, payload...)
+
+	result := make([]byte, 0, len(data)+len(segment))
+	result = append(result, data[:insertAt]...)
+	result = append(result, segment...)
+	result = append(result, data[insertAt:]...)
+	return result, nil
+}
+
+// writeMediaSidecar writes the tweet metadata to <path>.json
+func writeMediaSidecar(path string, item MediaItem, sourceURL string) error {
+	data, err := json.MarshalIndent(mediaSidecar{
+		TweetID: item.TweetID,
+		URL:     sourceURL,
+		Date:    item.Date,
+		Text:    item.Text,
+		AltText: item.AltText,
+	}, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path+".json", data, 0644)
+}
+
+// tweetSourceURL returns the status URL of an item's tweet, or the media URL
+// when the tweet ID is unknown
+func tweetSourceURL(item MediaItem) string {
+	if item.TweetID == 0 {
+		return item.URL
+	}
+	username := item.Username
+	if username == "" {
+		username = "i"
+	}
+	return fmt.Sprintf("https://x.com/%s/status/%d", username, item.TweetID)
+}
+
+// embedTweetMetadata writes the tweet text and source URL into a downloaded
+// JPEG as XMP; other image formats get a JSON sidecar and videos are skipped
+func embedTweetMetadata(path string, item MediaItem) error {
+	if item.Type != "photo" {
+		return nil
+	}
+
+	sourceURL := tweetSourceURL(item)
+
+	lowerPath := strings.ToLower(path)
+	if !strings.HasSuffix(lowerPath, ".jpg") && !strings.HasSuffix(lowerPath, ".jpeg") {
+		return writeMediaSidecar(path, item, sourceURL)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	updated, err := insertJPEGXMP(data, buildXMPPacket(item.Text, sourceURL))
+	if err != nil {
+		return writeMediaSidecar(path, item, sourceURL)
+	}
+
+	tempPath := path + partialDownloadSuffix
+	if err := os.WriteFile(tempPath, updated, 0644); err != nil {
+		return err
+	}
+	if err := os.Rename(tempPath, path); err != nil {
+		os.Remove(tempPath)
+		return err
+	}
+	return nil
+}
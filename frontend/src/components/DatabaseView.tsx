@@ -83,6 +83,20 @@ function getRelativeTime(dateStr: string): string {
   }
 }
 
+function formatLastFetched(dateStr: string): string {
+  const date = new Date(dateStr);
+  if (isNaN(date.getTime())) {
+    return dateStr;
+  }
+  return date.toLocaleString(undefined, {
+    year: "numeric",
+    month: "2-digit",
+    day: "2-digit",
+    hour: "2-digit",
+    minute: "2-digit",
+  });
+}
+
 interface AccountListItem {
   id: number;
   username: string;
@@ -649,7 +663,7 @@ export function DatabaseView({ onBack, onLoadAccount }: DatabaseViewProps) {
                   </div>
                   <div className="text-sm text-muted-foreground">@{account.username}</div>
                   <div className="text-sm text-muted-foreground">
-                    {account.last_fetched} {getRelativeTime(account.last_fetched)}
+                    {formatLastFetched(account.last_fetched)} {getRelativeTime(account.last_fetched)}
                   </div>
                 </div>
                 <div className="flex items-center gap-2">
@@ -9,7 +9,25 @@ import json
 import sys
 from pathlib import Path
 from typing import Optional
-from metadata import get_metadata, get_metadata_by_date
+from metadata import (
+    get_metadata,
+    get_metadata_by_date,
+    get_metadata_by_tweet,
+    ERROR_MSG_AUTH_FAILED,
+    ERROR_MSG_ACCOUNT_NOT_FOUND,
+    ERROR_MSG_PROTECTED,
+)
+
+__version__ = "1.0.0"
+
+# Exit codes, keep in sync with backend/extractor.go
+EXIT_OK = 0
+EXIT_ERROR = 1
+EXIT_AUTH_FAILED = 2
+EXIT_NETWORK_ERROR = 3
+EXIT_NOT_FOUND = 4
+EXIT_RATE_LIMITED = 5
+EXIT_PROTECTED = 6
 
 
 def print_success(message: str):
@@ -24,6 +42,25 @@ def print_info(message: str):
     print(f"Info: {message}")
 
 
+def exit_code_for(data: dict) -> int:
+    error = data.get("error")
+    if not error:
+        return EXIT_OK
+
+    message = str(error).lower()
+    if error == ERROR_MSG_AUTH_FAILED or "401" in message or "authenticat" in message or "login" in message:
+        return EXIT_AUTH_FAILED
+    if "429" in message or "rate limit" in message or "too many requests" in message:
+        return EXIT_RATE_LIMITED
+    if error == ERROR_MSG_PROTECTED:
+        return EXIT_PROTECTED
+    if error == ERROR_MSG_ACCOUNT_NOT_FOUND or "404" in message or "not found" in message or "does not exist" in message:
+        return EXIT_NOT_FOUND
+    if any(marker in message for marker in ("connection", "timed out", "timeout", "name resolution", "max retries", "network")):
+        return EXIT_NETWORK_ERROR
+    return EXIT_ERROR
+
+
 def print_result_summary(data: dict):
     if "error" in data:
         print_error(data["error"])
@@ -85,7 +122,11 @@ def timeline_mode(args):
         batch_size=args.batch_size,
         page=args.page,
         media_type=args.media_type,
-        retweets=args.retweets
+        retweets=args.retweets,
+        max_entries=args.max_entries,
+        cursor=args.cursor,
+        since_id=args.since_id,
+        cookies_out=args.cookies_out
     )
 
     # Save to file if specified
@@ -104,7 +145,7 @@ def timeline_mode(args):
     else:
         print_result_summary(data)
 
-    return 0 if "error" not in data else 1
+    return exit_code_for(data)
 
 
 def date_range_mode(args):
@@ -116,7 +157,8 @@ def date_range_mode(args):
         date_start=args.start_date,
         date_end=args.end_date,
         media_filter=args.media_filter,
-        output_file=args.output
+        output_file=args.output,
+        cookies_out=args.cookies_out
     )
 
     # Display results
@@ -127,7 +169,35 @@ def date_range_mode(args):
             print_success(f"Results saved to: {args.output}")
         print_result_summary(data)
 
-    return 0 if "error" not in data else 1
+    return exit_code_for(data)
+
+
+def tweet_mode(args):
+    print_info(f"extracting media from tweet {args.tweet_id}...")
+
+    data = get_metadata_by_tweet(
+        tweet_id=args.tweet_id,
+        auth_token=args.token,
+        cookies_out=args.cookies_out
+    )
+
+    # Save to file if specified
+    if args.output:
+        try:
+            output_path = Path(args.output)
+            with open(output_path, 'w', encoding='utf-8') as f:
+                json.dump(data, f, ensure_ascii=False, indent=2)
+            print_success(f"Results saved to: {output_path}")
+        except Exception as e:
+            print_error(f"Failed to save output file: {e}")
+
+    # Display results
+    if args.json:
+        print(json.dumps(data, ensure_ascii=False, indent=2))
+    else:
+        print_result_summary(data)
+
+    return exit_code_for(data)
 
 
 def main():
@@ -148,6 +218,9 @@ Examples:
   # Extract by date range
   %(prog)s --token YOUR_TOKEN daterange masteraoko --start-date 2024-01-01 --end-date 2024-12-31
 
+  # Extract media from a single tweet
+  %(prog)s --token YOUR_TOKEN tweet 1234567890123456789
+
   # Save to file
   %(prog)s --token YOUR_TOKEN --output output.json timeline masteraoko
 
@@ -163,6 +236,9 @@ Username formats supported:
     )
 
     # Global arguments (use long flags only to avoid conflicts with Python/Nuitka)
+    parser.add_argument('--version',
+                       action='version',
+                       version=f'metadata-extractor {__version__}')
     parser.add_argument('--token',
                        required=True,
                        help='Twitter/X authentication token (required)')
@@ -171,6 +247,8 @@ Username formats supported:
     parser.add_argument('--json',
                        action='store_true',
                        help='Output raw JSON instead of formatted summary')
+    parser.add_argument('--cookies-out',
+                       help='Write the session cookies (auth_token, ct0) as JSON to this file (optional)')
 
     # Subcommands
     subparsers = parser.add_subparsers(dest='mode', help='Extraction mode')
@@ -203,6 +281,17 @@ Username formats supported:
                                 action='store_false',
                                 dest='retweets',
                                 help='Exclude retweets (default)')
+    timeline_parser.add_argument('--max-entries',
+                                type=int,
+                                default=0,
+                                help='Stop after collecting this many media entries (0 = no limit)')
+    timeline_parser.add_argument('--cursor',
+                                default='',
+                                help='Resume from the cursor returned by a previous run (overrides --page skipping)')
+    timeline_parser.add_argument('--since-id',
+                                type=int,
+                                default=0,
+                                help='Only collect media from tweets newer than this ID, stopping once known tweets are reached (0 = all)')
 
     # Date range mode
     daterange_parser = subparsers.add_parser('daterange',
@@ -220,6 +309,12 @@ Username formats supported:
                                  default='filter:media',
                                  help='Media filter (default: filter:media)')
 
+    # Single tweet mode
+    tweet_parser = subparsers.add_parser('tweet',
+                                         help='Extract media from a single tweet')
+    tweet_parser.add_argument('tweet_id',
+                              help='Numeric tweet ID')
+
     args = parser.parse_args()
 
     # Check if mode was specified
@@ -233,6 +328,8 @@ Username formats supported:
             return timeline_mode(args)
         elif args.mode == 'daterange':
             return date_range_mode(args)
+        elif args.mode == 'tweet':
+            return tweet_mode(args)
     except KeyboardInterrupt:
         print_error("Operation cancelled by user")
         return 130
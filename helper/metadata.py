@@ -8,13 +8,21 @@ from gallery_dl.extractor import twitter
 TWITTER_IMAGE_DOMAIN = "pbs.twimg.com"
 TWITTER_VIDEO_DOMAIN = "video.twimg.com"
 
+# Consecutive already-known media items after which a --since-id run stops.
+# A pinned tweet or an old retweet can appear among new tweets, so a single
+# known item isn't enough to tell the rest of the timeline is known.
+SINCE_ID_STOP_AFTER = 20
+
 # Error Codes
 WITHHELD_ERROR_CODE = "withheld"
+PROTECTED_ERROR_CODE = "protected"
 
 # Error Messages
 ERROR_MSG_WITHHELD = "Account withheld. Alternative version available at: https://www.patreon.com/exyezed"
 ERROR_MSG_AUTH_FAILED = "Authentication failed. Verify your auth token is valid."
 ERROR_MSG_ACCOUNT_NOT_FOUND = "Failed to fetch account information. Check the username and auth token."
+ERROR_MSG_PROTECTED = "Account protected. The account of your auth token does not follow it."
+ERROR_MSG_TWEET_UNAVAILABLE = "Tweet unavailable. It may have been deleted, be from a protected account, or contain no media."
 
 
 def _parse_username(username_input: str) -> str:
@@ -45,6 +53,39 @@ def _parse_username(username_input: str) -> str:
     return username.lower()
 
 
+def _retry_after(error: Exception) -> Optional[str]:
+    response = getattr(error, "response", None)
+    headers = getattr(response, "headers", None)
+    if headers:
+        return headers.get("Retry-After")
+    return None
+
+
+def _error_result(error_str: str, error: Exception) -> Dict[str, Any]:
+    result = {"error": error_str}
+    retry_after = _retry_after(error)
+    if retry_after:
+        result["retry_after"] = retry_after
+    return result
+
+
+def _save_session_cookies(extractor: Any, path: Optional[str]):
+    # Writes the x.com/twitter.com cookies the session ended with (auth_token,
+    # ct0, ...) so media downloads can reuse them; failures are ignored
+    if not path:
+        return
+    try:
+        cookies = {}
+        for cookie in extractor.cookies:
+            domain = (cookie.domain or "").lstrip(".")
+            if domain.endswith("x.com") or domain.endswith("twitter.com"):
+                cookies[cookie.name] = cookie.value
+        with open(path, 'w', encoding='utf-8') as f:
+            json.dump(cookies, f)
+    except Exception:
+        pass
+
+
 def _format_datetime(dt: Any) -> str:
     if isinstance(dt, datetime):
         return dt.strftime("%Y-%m-%d %H:%M:%S")
@@ -63,6 +104,17 @@ def _build_timeline_entry(media_url: str, tweet_data: Dict[str, Any]) -> Dict[st
     if 'type' in tweet_data:
         entry['type'] = tweet_data['type']
 
+    if tweet_data.get('content'):
+        entry['text'] = tweet_data['content']
+
+    if tweet_data.get('description'):
+        entry['alt_text'] = tweet_data['description']
+
+    # Engagement counts, only when the extractor reports them
+    for key, source in (('likes', 'favorite_count'), ('retweets', 'retweet_count'), ('replies', 'reply_count')):
+        if isinstance(tweet_data.get(source), int):
+            entry[key] = tweet_data[source]
+
     if 'retweet_id' in tweet_data and tweet_data['retweet_id']:
         entry['retweet_id'] = tweet_data['retweet_id']
         entry['is_retweet'] = True
@@ -101,6 +153,12 @@ def _is_withheld_error(error: Exception) -> bool:
     return is_withheld_value_error or has_withheld_in_message or has_withheld_in_response
 
 
+def _check_protected(user: Dict[str, Any]):
+    legacy = user.get("legacy", {})
+    if legacy.get("protected") and not legacy.get("following"):
+        raise ValueError(PROTECTED_ERROR_CODE)
+
+
 def _build_account_info(user_data: Dict[str, Any]) -> Dict[str, Any]:
     return {
         'name': user_data.get('name', ''),
@@ -109,6 +167,7 @@ def _build_account_info(user_data: Dict[str, Any]) -> Dict[str, Any]:
         'followers_count': user_data.get('followers_count', 0),
         'friends_count': user_data.get('friends_count', 0),
         'profile_image': user_data.get('profile_image', ''),
+        'profile_banner': user_data.get('profile_banner', ''),
         'statuses_count': user_data.get('statuses_count', 0)
     }
 
@@ -119,7 +178,8 @@ def get_metadata_by_date(
     date_start: str,
     date_end: str,
     media_filter: str = "filter:media",
-    output_file: Optional[str] = None
+    output_file: Optional[str] = None,
+    cookies_out: Optional[str] = None
 ) -> Dict[str, Any]:
     # Parse username from various input formats
     username = _parse_username(username)
@@ -158,6 +218,8 @@ def get_metadata_by_date(
             if "legacy" in user and user["legacy"].get("withheld_scope"):
                 raise ValueError(WITHHELD_ERROR_CODE)
 
+            _check_protected(user)
+
         except Exception as e:
             if _is_withheld_error(e):
                 raise ValueError(WITHHELD_ERROR_CODE)
@@ -216,17 +278,97 @@ def get_metadata_by_date(
             except Exception as e:
                 print(f"Warning: Failed to write output file '{output_file}': {e}")
 
+        _save_session_cookies(extractor, cookies_out)
         return structured_output
 
     except Exception as e:
         if _is_withheld_error(e):
             return {"error": ERROR_MSG_WITHHELD}
+        if str(e) == PROTECTED_ERROR_CODE:
+            return {"error": ERROR_MSG_PROTECTED}
 
         error_str = str(e)
         if error_str == "None":
             return {"error": ERROR_MSG_AUTH_FAILED}
 
-        return {"error": error_str}
+        return _error_result(error_str, e)
+
+
+def get_metadata_by_tweet(
+    tweet_id: str,
+    auth_token: str,
+    cookies_out: Optional[str] = None
+) -> Dict[str, Any]:
+    url = f"https://x.com/i/web/status/{tweet_id}"
+
+    extractor_class = twitter.TwitterTweetExtractor
+    match = re.match(extractor_class.pattern, url)
+
+    if not match:
+        raise ValueError(f"Invalid tweet URL: {url}")
+
+    extractor = extractor_class(match)
+
+    config_dict = {
+        "cookies": {
+            "auth_token": auth_token
+        },
+        "retweets": True,
+        "conversations": False
+    }
+
+    extractor.config = lambda key, default=None: config_dict.get(key, default)
+
+    try:
+        extractor.initialize()
+
+        structured_output = {
+            'account_info': {},
+            'total_urls': 0,
+            'timeline': []
+        }
+
+        new_timeline_entries = []
+
+        try:
+            for item in extractor:
+                if isinstance(item, tuple) and len(item) >= 3:
+                    media_url = item[1]
+                    tweet_data = item[2]
+
+                    if not structured_output['account_info'] and 'user' in tweet_data:
+                        structured_output['account_info'] = _build_account_info(tweet_data['user'])
+
+                    if _is_twitter_media(media_url):
+                        new_timeline_entries.append(_build_timeline_entry(media_url, tweet_data))
+                        structured_output['total_urls'] += 1
+        except StopIteration:
+            pass
+
+        if not new_timeline_entries:
+            return {"error": ERROR_MSG_TWEET_UNAVAILABLE}
+
+        structured_output['timeline'] = new_timeline_entries
+
+        structured_output['metadata'] = {
+            "new_entries": len(new_timeline_entries),
+            "page": 0,
+            "batch_size": 0,
+            "has_more": False
+        }
+
+        _save_session_cookies(extractor, cookies_out)
+        return structured_output
+
+    except Exception as e:
+        if _is_withheld_error(e):
+            return {"error": ERROR_MSG_WITHHELD}
+
+        error_str = str(e)
+        if error_str == "None":
+            return {"error": ERROR_MSG_AUTH_FAILED}
+
+        return _error_result(error_str, e)
 
 
 def get_metadata(
@@ -236,7 +378,11 @@ def get_metadata(
     batch_size: int = 0,
     page: int = 0,
     media_type: str = "all",
-    retweets: bool = False
+    retweets: bool = False,
+    max_entries: int = 0,
+    cursor: str = "",
+    since_id: int = 0,
+    cookies_out: Optional[str] = None
 ) -> Dict[str, Any]:
     # Parse username from various input formats
     username = _parse_username(username)
@@ -268,6 +414,10 @@ def get_metadata(
     if batch_size > 0:
         config_dict["count"] = batch_size
 
+    # Resume from an opaque cursor returned by a previous extraction
+    if cursor:
+        config_dict["cursor"] = cursor
+
     extractor.config = lambda key, default=None: config_dict.get(key, default)
 
     try:
@@ -283,6 +433,8 @@ def get_metadata(
             if "legacy" in user and user["legacy"].get("withheld_scope"):
                 raise ValueError(WITHHELD_ERROR_CODE)
 
+            _check_protected(user)
+
         except Exception as e:
             if _is_withheld_error(e):
                 raise ValueError(WITHHELD_ERROR_CODE)
@@ -296,7 +448,7 @@ def get_metadata(
 
         iterator = iter(extractor)
 
-        if batch_size > 0 and page > 0:
+        if batch_size > 0 and page > 0 and not cursor:
             items_to_skip = page * batch_size
 
             if hasattr(extractor, '_cursor') and extractor._cursor:
@@ -314,9 +466,16 @@ def get_metadata(
 
         items_to_fetch = batch_size if batch_size > 0 else float('inf')
         items_fetched = 0
+        capped = False
+        known_streak = 0
+        reached_known = False
 
         try:
             while items_fetched < items_to_fetch:
+                if max_entries > 0 and len(new_timeline_entries) >= max_entries:
+                    capped = True
+                    break
+
                 item = next(iterator)
                 items_fetched += 1
 
@@ -328,6 +487,17 @@ def get_metadata(
                         user = tweet_data['user']
                         structured_output['account_info'] = _build_account_info(user)
 
+                    # Incremental run: skip media at or below since_id and
+                    # stop once a run of them shows the rest is known
+                    if since_id > 0:
+                        if int(tweet_data.get('tweet_id', 0) or 0) <= since_id:
+                            known_streak += 1
+                            if known_streak >= SINCE_ID_STOP_AFTER:
+                                reached_known = True
+                                break
+                            continue
+                        known_streak = 0
+
                     if _is_twitter_media(media_url):
                         timeline_entry = _build_timeline_entry(media_url, tweet_data)
 
@@ -347,24 +517,27 @@ def get_metadata(
             "new_entries": len(new_timeline_entries),
             "page": page,
             "batch_size": batch_size,
-            "has_more": batch_size > 0 and items_fetched == batch_size,
+            "has_more": capped or (not reached_known and batch_size > 0 and items_fetched == batch_size),
             "cursor": cursor_info
         }
 
         if not structured_output['account_info']:
             raise ValueError(ERROR_MSG_ACCOUNT_NOT_FOUND)
 
+        _save_session_cookies(extractor, cookies_out)
         return structured_output
 
     except Exception as e:
         if _is_withheld_error(e):
             return {"error": ERROR_MSG_WITHHELD}
+        if str(e) == PROTECTED_ERROR_CODE:
+            return {"error": ERROR_MSG_PROTECTED}
 
         error_str = str(e)
         if error_str == "None":
             return {"error": ERROR_MSG_AUTH_FAILED}
 
-        return {"error": error_str}
+        return _error_result(error_str, e)
 
 
 def main():
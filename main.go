@@ -13,6 +13,9 @@ import (
 //go:embed all:frontend/dist
 var assets embed.FS
 
+// AppVersion is set at build time with -ldflags "-X main.AppVersion=..."
+var AppVersion = "dev"
+
 func main() {
 	// Create an instance of the app structure
 	app := NewApp()